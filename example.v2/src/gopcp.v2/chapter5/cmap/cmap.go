@@ -22,6 +22,10 @@ type ConcurrentMap interface {
 	Delete(key string) bool
 	// Len 会返回当前字典中键-元素对的数量。
 	Len() uint64
+	// Range 会依次把每个键-元素对传给参数fn。
+	// 若fn返回false，则会中止遍历。
+	// 注意！遍历的是调用时各散列段的快照，遍历期间的改动不一定可见。
+	Range(fn func(key string, element interface{}) bool)
 }
 
 // myConcurrentMap 代表ConcurrentMap接口的实现类型。
@@ -92,6 +96,19 @@ func (cmap *myConcurrentMap) Len() uint64 {
 	return atomic.LoadUint64(&cmap.total)
 }
 
+func (cmap *myConcurrentMap) Range(fn func(key string, element interface{}) bool) {
+	if fn == nil {
+		return
+	}
+	for _, s := range cmap.segments {
+		for _, p := range s.Pairs() {
+			if !fn(p.Key(), p.Element()) {
+				return
+			}
+		}
+	}
+}
+
 // findSegment 会根据给定参数寻找并返回对应散列段。
 func (cmap *myConcurrentMap) findSegment(keyHash uint64) Segment {
 	if cmap.concurrency == 1 {
@@ -203,6 +203,42 @@ func TestCmapDelete(t *testing.T) {
 	}
 }
 
+func TestCmapRange(t *testing.T) {
+	number := 30
+	testCases := genNoRepetitiveTestingPairs(number)
+	concurrency := number / 2
+	cm, _ := NewConcurrentMap(concurrency, nil)
+	expected := map[string]interface{}{}
+	for _, p := range testCases {
+		cm.Put(p.Key(), p.Element())
+		expected[p.Key()] = p.Element()
+	}
+	actual := map[string]interface{}{}
+	cm.Range(func(key string, element interface{}) bool {
+		actual[key] = element
+		return true
+	})
+	if len(actual) != len(expected) {
+		t.Fatalf("Inconsistent range count: expected: %d, actual: %d",
+			len(expected), len(actual))
+	}
+	for k, e := range expected {
+		if actual[k] != e {
+			t.Fatalf("Inconsistent element for key %q: expected: %#v, actual: %#v",
+				k, e, actual[k])
+		}
+	}
+	var count int
+	cm.Range(func(key string, element interface{}) bool {
+		count++
+		return count < 3
+	})
+	if count != 3 {
+		t.Fatalf("Inconsistent range count after break: expected: %d, actual: %d",
+			3, count)
+	}
+}
+
 func TestCmapDeleteInParallel(t *testing.T) {
 	number := 30
 	testCases := genNoRepetitiveTestingPairs(number)
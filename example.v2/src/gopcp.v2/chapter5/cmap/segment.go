@@ -22,6 +22,8 @@ type Segment interface {
 	Delete(key string) bool
 	// Size 用于获取当前段的尺寸（其中包含的散列桶的数量）。
 	Size() uint64
+	// Pairs 会返回当前段中所有键-元素对的快照。
+	Pairs() []Pair
 }
 
 // segment 代表并发安全的散列段的类型。
@@ -98,6 +100,18 @@ func (s *segment) Size() uint64 {
 	return atomic.LoadUint64(&s.pairTotal)
 }
 
+func (s *segment) Pairs() []Pair {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	pairs := make([]Pair, 0, atomic.LoadUint64(&s.pairTotal))
+	for _, b := range s.buckets {
+		for p := b.GetFirstPair(); p != nil; p = p.Next() {
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs
+}
+
 // redistribute 会检查给定参数并设置相应的阈值和计数，
 // 并在必要时重新分配所有散列桶中的所有键-元素对。
 // 注意！必须在互斥锁的保护下调用本方法！
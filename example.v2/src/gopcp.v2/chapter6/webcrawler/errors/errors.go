@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // ErrorType 代表错误类型。
@@ -37,6 +38,8 @@ type myCrawlerError struct {
 	errMsg string
 	// fullErrMsg 代表完整的错误提示信息。
 	fullErrMsg string
+	// err 代表被包装的错误值，可以为nil。
+	err error
 }
 
 // NewCrawlerError 用于创建一个新的爬虫错误值。
@@ -48,14 +51,24 @@ func NewCrawlerError(errType ErrorType, errMsg string) CrawlerError {
 }
 
 // NewCrawlerErrorBy 用于根据给定的错误值创建一个新的爬虫错误值。
+// 给定的错误值会被包装在新的爬虫错误值中。
 func NewCrawlerErrorBy(errType ErrorType, err error) CrawlerError {
-	return NewCrawlerError(errType, err.Error())
+	return &myCrawlerError{
+		errType: errType,
+		errMsg:  strings.TrimSpace(err.Error()),
+		err:     err,
+	}
 }
 
 func (ce *myCrawlerError) Type() ErrorType {
 	return ce.errType
 }
 
+// Unwrap 用于获取被包装的错误值。
+func (ce *myCrawlerError) Unwrap() error {
+	return ce.err
+}
+
 func (ce *myCrawlerError) Error() string {
 	if ce.fullErrMsg == "" {
 		ce.genFullErrMsg()
@@ -92,3 +105,86 @@ func NewIllegalParameterError(errMsg string) IllegalParameterError {
 func (ipe IllegalParameterError) Error() string {
 	return ipe.msg
 }
+
+// BodyTooLargeError 代表响应体过大的错误类型。
+type BodyTooLargeError struct {
+	msg string
+}
+
+// NewBodyTooLargeError 会创建一个BodyTooLargeError类型的实例。
+// 参数url代表请求的URL，参数limit代表响应体的最大字节数。
+func NewBodyTooLargeError(url string, limit int64) BodyTooLargeError {
+	return BodyTooLargeError{
+		msg: fmt.Sprintf("response body too large: exceeds %d bytes (URL: %s)",
+			limit, url),
+	}
+}
+
+func (btle BodyTooLargeError) Error() string {
+	return btle.msg
+}
+
+// ContentTypeError 代表响应的内容类型不被允许的错误类型。
+type ContentTypeError struct {
+	msg string
+}
+
+// NewContentTypeError 会创建一个ContentTypeError类型的实例。
+// 参数url代表请求的URL，参数contentType代表响应的内容类型。
+func NewContentTypeError(url string, contentType string) ContentTypeError {
+	return ContentTypeError{
+		msg: fmt.Sprintf("disallowed content type %q (URL: %s)",
+			contentType, url),
+	}
+}
+
+func (cte ContentTypeError) Error() string {
+	return cte.msg
+}
+
+// TooManyRedirectsError 代表重定向次数过多的错误类型。
+type TooManyRedirectsError struct {
+	msg string
+}
+
+// NewTooManyRedirectsError 会创建一个TooManyRedirectsError类型的实例。
+// 参数url代表请求的URL，参数max代表最大的重定向次数。
+func NewTooManyRedirectsError(url string, max int) TooManyRedirectsError {
+	return TooManyRedirectsError{
+		msg: fmt.Sprintf("too many redirects: exceeds %d (URL: %s)",
+			max, url),
+	}
+}
+
+func (tmre TooManyRedirectsError) Error() string {
+	return tmre.msg
+}
+
+// DownloadTimeoutError 代表下载超时的错误类型。
+// 它实现了net.Error接口，以便被识别为超时错误。
+type DownloadTimeoutError struct {
+	msg string
+}
+
+// NewDownloadTimeoutError 会创建一个DownloadTimeoutError类型的实例。
+// 参数url代表请求的URL，参数timeout代表超时时间。
+func NewDownloadTimeoutError(url string, timeout time.Duration) DownloadTimeoutError {
+	return DownloadTimeoutError{
+		msg: fmt.Sprintf("download timeout: exceeds %s (URL: %s)",
+			timeout, url),
+	}
+}
+
+func (dte DownloadTimeoutError) Error() string {
+	return dte.msg
+}
+
+// Timeout 用于判断错误是否为超时错误，总是返回true。
+func (dte DownloadTimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary 用于判断错误是否为暂时性的错误，总是返回true。
+func (dte DownloadTimeoutError) Temporary() bool {
+	return true
+}
@@ -12,16 +12,30 @@ type Counts struct {
 	CompletedCount uint64
 	// HandlingNumber 代表实时处理数。
 	HandlingNumber uint64
+	// Latency 代表处理耗时的分布。
+	Latency LatencyStruct
+	// BytesProcessed 代表已处理的字节数。
+	BytesProcessed uint64
+	// ErrorCounts 代表按错误类型划分的错误计数。
+	ErrorCounts ErrorCounts
 }
 
 // SummaryStruct 代表组件摘要结构的类型。
 type SummaryStruct struct {
-	ID        MID         `json:"id"`
-	Called    uint64      `json:"called"`
-	Accepted  uint64      `json:"accepted"`
-	Completed uint64      `json:"completed"`
-	Handling  uint64      `json:"handling"`
-	Extra     interface{} `json:"extra,omitempty"`
+	ID        MID    `json:"id"`
+	Called    uint64 `json:"called"`
+	Accepted  uint64 `json:"accepted"`
+	Completed uint64 `json:"completed"`
+	Handling  uint64 `json:"handling"`
+	// Latency 代表处理耗时的分布。
+	Latency LatencyStruct `json:"latency"`
+	// Bytes 代表已处理的字节数。
+	Bytes uint64 `json:"bytes"`
+	// Errors 代表按错误类型划分的错误计数。
+	Errors ErrorCounts `json:"errors"`
+	// Health 代表组件的健康状况，由注册器负责统计。
+	Health HealthStruct `json:"health"`
+	Extra  interface{}  `json:"extra,omitempty"`
 }
 
 // Module 代表组件的基础接口类型。
@@ -74,6 +88,11 @@ type Analyzer interface {
 // ParseResponse 代表用于解析HTTP响应的函数的类型。
 type ParseResponse func(httpResp *http.Response, respDepth uint32) ([]Data, []error)
 
+// CheckDuplicate 代表用于检查HTTP响应的内容是否重复的函数的类型。
+// 参数httpResp的响应体可以被读取，但不应被关闭。
+// 结果值代表该响应的内容是否与之前检查过的响应重复。
+type CheckDuplicate func(httpResp *http.Response) bool
+
 // Pipeline 代表条目处理管道的接口类型。
 // 该接口的实现类型必须是并发安全的！
 type Pipeline interface {
@@ -16,6 +16,14 @@ type Request struct {
 	httpReq *http.Request
 	// depth 代表请求的深度。
 	depth uint32
+	// attempt 代表请求的尝试序号。首次下载时为0，每次重试都会加1。
+	attempt uint32
+	// unlimited 代表请求是否不受下载器的内容类型和响应体大小的限制。
+	unlimited bool
+	// score 代表请求的分数。
+	score float64
+	// scored 代表请求是否带有分数。
+	scored bool
 }
 
 // NewRequest 用于创建一个新的请求实例。
@@ -23,6 +31,13 @@ func NewRequest(httpReq *http.Request, depth uint32) *Request {
 	return &Request{httpReq: httpReq, depth: depth}
 }
 
+// NewUnlimitedRequest 用于创建一个深度为0的、
+// 不受下载器的内容类型和响应体大小限制的请求实例。
+// 它适用于获取robots.txt和站点地图等由调度器自身发起的请求。
+func NewUnlimitedRequest(httpReq *http.Request) *Request {
+	return &Request{httpReq: httpReq, unlimited: true}
+}
+
 // HTTPReq 用于获取HTTP请求。
 func (req *Request) HTTPReq() *http.Request {
 	return req.httpReq
@@ -33,6 +48,41 @@ func (req *Request) Depth() uint32 {
 	return req.depth
 }
 
+// Attempt 用于获取请求的尝试序号。
+func (req *Request) Attempt() uint32 {
+	return req.attempt
+}
+
+// Unlimited 用于判断请求是否不受下载器的内容类型和响应体大小的限制。
+func (req *Request) Unlimited() bool {
+	return req.unlimited
+}
+
+// Score 用于获取请求的分数。
+// 第二个结果值代表请求是否带有分数。
+func (req *Request) Score() (float64, bool) {
+	return req.score, req.scored
+}
+
+// WithScore 用于生成带有给定分数的请求实例。
+// 新实例与当前实例共用同一个HTTP请求。
+// 在按分数排序的URL边界中，分数越高的请求越先被爬取，
+// 带有分数的请求不会再由调度器的分数计算函数计算分数。
+func (req *Request) WithScore(score float64) *Request {
+	next := *req
+	next.score = score
+	next.scored = true
+	return &next
+}
+
+// NextAttempt 用于生成下一次尝试下载时使用的请求实例。
+// 新实例与当前实例共用同一个HTTP请求。
+func (req *Request) NextAttempt() *Request {
+	next := *req
+	next.attempt++
+	return &next
+}
+
 // Valid 用于判断请求是否有效。
 func (req *Request) Valid() bool {
 	return req.httpReq != nil && req.httpReq.URL != nil
@@ -44,6 +94,8 @@ type Response struct {
 	httpResp *http.Response
 	// depth 代表响应的深度。
 	depth uint32
+	// checkDuplicate 代表检查响应的内容是否重复的函数，可以为nil。
+	checkDuplicate CheckDuplicate
 }
 
 // NewResponse 用于创建一个新的响应实例。
@@ -61,6 +113,20 @@ func (resp *Response) Depth() uint32 {
 	return resp.depth
 }
 
+// WithDuplicateCheck 用于生成带有重复内容检查函数的响应实例。
+// 新实例与当前实例共用同一个HTTP响应。
+func (resp *Response) WithDuplicateCheck(check CheckDuplicate) *Response {
+	next := *resp
+	next.checkDuplicate = check
+	return &next
+}
+
+// DuplicateCheck 用于获取响应的重复内容检查函数，结果值可能为nil。
+// 分析器应该在读取响应体之后、解析响应之前调用它一次。
+func (resp *Response) DuplicateCheck() CheckDuplicate {
+	return resp.checkDuplicate
+}
+
 // Valid 用于判断响应是否有效。
 func (resp *Response) Valid() bool {
 	return resp.httpResp != nil && resp.httpResp.Body != nil
@@ -45,6 +45,34 @@ func TestRequest(t *testing.T) {
 		t.Fatalf("Inconsistent depth for request: expected: %d, actual: %d",
 			expectedDepth, req.Depth())
 	}
+	if req.Attempt() != 0 {
+		t.Fatalf("Inconsistent attempt for request: expected: %d, actual: %d",
+			0, req.Attempt())
+	}
+	nextReq := req.NextAttempt()
+	if nextReq.Attempt() != 1 {
+		t.Fatalf("Inconsistent attempt for request: expected: %d, actual: %d",
+			1, nextReq.Attempt())
+	}
+	if nextReq.HTTPReq() != expectedHTTPReq || nextReq.Depth() != expectedDepth {
+		t.Fatalf("Inconsistent retry request: expected: %#v (depth: %d), actual: %#v (depth: %d)",
+			expectedHTTPReq, expectedDepth, nextReq.HTTPReq(), nextReq.Depth())
+	}
+	if req.Attempt() != 0 {
+		t.Fatalf("The attempt of original request has been changed! (attempt: %d)",
+			req.Attempt())
+	}
+	if _, ok := req.Score(); ok {
+		t.Fatal("The new request has a score!")
+	}
+	scoredReq := req.WithScore(2.5).NextAttempt()
+	if score, ok := scoredReq.Score(); !ok || score != 2.5 {
+		t.Fatalf("Inconsistent score for request: expected: %v, actual: %v (scored: %v)",
+			2.5, score, ok)
+	}
+	if _, ok := req.Score(); ok {
+		t.Fatal("The score of original request has been changed!")
+	}
 	expectedHTTPReq.URL = nil
 	req = NewRequest(expectedHTTPReq, expectedDepth)
 	expectedValidity = false
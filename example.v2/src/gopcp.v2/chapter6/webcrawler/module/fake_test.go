@@ -94,10 +94,10 @@ func (fm *fakeModule) HandlingNumber() uint64 {
 
 func (fm *fakeModule) Counts() Counts {
 	return Counts{
-		fm.CalledCount(),
-		fm.AcceptedCount(),
-		fm.CompletedCount(),
-		fm.HandlingNumber(),
+		CalledCount:    fm.CalledCount(),
+		AcceptedCount:  fm.AcceptedCount(),
+		CompletedCount: fm.CompletedCount(),
+		HandlingNumber: fm.HandlingNumber(),
 	}
 }
 
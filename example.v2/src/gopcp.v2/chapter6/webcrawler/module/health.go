@@ -0,0 +1,271 @@
+package module
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/errors"
+)
+
+// BreakerState 代表熔断器的状态。
+type BreakerState string
+
+// 熔断器状态的常量。
+const (
+	// BREAKER_STATE_CLOSED 代表闭合状态，即组件健康，可以被正常地选择。
+	BREAKER_STATE_CLOSED BreakerState = "closed"
+	// BREAKER_STATE_OPEN 代表断开状态，即组件不健康，不会被选择。
+	BREAKER_STATE_OPEN BreakerState = "open"
+	// BREAKER_STATE_HALF_OPEN 代表半开状态，即只允许少量的试探调用。
+	BREAKER_STATE_HALF_OPEN BreakerState = "half_open"
+)
+
+// 健康策略的默认值。
+const (
+	// defaultHealthWindowSize 代表默认的统计窗口的大小。
+	defaultHealthWindowSize = 20
+	// defaultHealthMinCalls 代表默认的触发熔断所需的最少调用次数。
+	defaultHealthMinCalls = 10
+	// defaultHealthFailureRate 代表默认的触发熔断的失败率。
+	defaultHealthFailureRate = 0.5
+	// defaultHealthOpenTimeout 代表默认的断开状态的持续时间。
+	defaultHealthOpenTimeout = 30 * time.Second
+	// defaultHealthHalfOpenProbes 代表默认的半开状态下的试探调用的次数。
+	defaultHealthHalfOpenProbes = 1
+)
+
+// HealthPolicy 代表组件健康状况的判定和熔断策略的类型。
+// 除Enabled以外，其中的零值字段都代表使用默认值。
+type HealthPolicy struct {
+	// Enabled 代表是否启用熔断。
+	// 若为false，则组件的健康状况仍会被统计，但不会影响组件的选择。
+	Enabled bool `json:"enabled"`
+	// WindowSize 代表统计窗口的大小，即参与统计的最近调用的次数。
+	WindowSize uint32 `json:"window_size"`
+	// MinCalls 代表统计窗口中至少要有多少次调用才可能触发熔断。
+	MinCalls uint32 `json:"min_calls"`
+	// FailureRate 代表触发熔断的失败率，取值范围为(0, 1]。
+	FailureRate float64 `json:"failure_rate"`
+	// SlowThreshold 代表慢调用的阈值。耗时超过此值的调用也会被视为失败。
+	// 若为0，则不判定慢调用。
+	SlowThreshold time.Duration `json:"slow_threshold"`
+	// OpenTimeout 代表熔断器保持断开状态的时间。
+	// 超过此时间之后，熔断器会进入半开状态，以试探组件是否已恢复。
+	OpenTimeout time.Duration `json:"open_timeout"`
+	// HalfOpenProbes 代表半开状态下允许的试探调用的次数。
+	// 这些调用全部成功时熔断器会闭合，任何一次失败都会使其重新断开。
+	HalfOpenProbes uint32 `json:"half_open_probes"`
+}
+
+// Check 用于检查健康策略的有效性。
+func (policy *HealthPolicy) Check() error {
+	if policy.FailureRate < 0 || policy.FailureRate > 1 {
+		errMsg := fmt.Sprintf("illegal failure rate: %f", policy.FailureRate)
+		return errors.NewIllegalParameterError(errMsg)
+	}
+	if policy.SlowThreshold < 0 {
+		return errors.NewIllegalParameterError("negative slow threshold")
+	}
+	if policy.OpenTimeout < 0 {
+		return errors.NewIllegalParameterError("negative open timeout")
+	}
+	if p := policy.withDefaults(); p.MinCalls > p.WindowSize {
+		errMsg := fmt.Sprintf("min calls %d is greater than window size %d",
+			p.MinCalls, p.WindowSize)
+		return errors.NewIllegalParameterError(errMsg)
+	}
+	return nil
+}
+
+// withDefaults 用于返回以默认值填充零值字段之后的健康策略。
+func (policy HealthPolicy) withDefaults() HealthPolicy {
+	if policy.WindowSize == 0 {
+		policy.WindowSize = defaultHealthWindowSize
+	}
+	if policy.MinCalls == 0 {
+		policy.MinCalls = defaultHealthMinCalls
+		if policy.MinCalls > policy.WindowSize {
+			policy.MinCalls = policy.WindowSize
+		}
+	}
+	if policy.FailureRate == 0 {
+		policy.FailureRate = defaultHealthFailureRate
+	}
+	if policy.OpenTimeout == 0 {
+		policy.OpenTimeout = defaultHealthOpenTimeout
+	}
+	if policy.HalfOpenProbes == 0 {
+		policy.HalfOpenProbes = defaultHealthHalfOpenProbes
+	}
+	return policy
+}
+
+// HealthStruct 代表组件健康状况的摘要类型。
+type HealthStruct struct {
+	// State 代表熔断器的状态。
+	State BreakerState `json:"state"`
+	// Calls 代表统计窗口中的调用次数。
+	Calls uint32 `json:"calls"`
+	// Failures 代表统计窗口中的失败次数。
+	Failures uint32 `json:"failures"`
+	// AvgLatency 代表统计窗口中的调用的平均耗时。
+	AvgLatency time.Duration `json:"avg_latency"`
+}
+
+// callResult 代表一次调用的结果。
+type callResult struct {
+	// failed 代表调用是否失败。
+	failed bool
+	// latency 代表调用的耗时。
+	latency time.Duration
+}
+
+// moduleHealth 代表单个组件的健康状况的跟踪器。
+type moduleHealth struct {
+	// policy 代表健康策略。
+	policy HealthPolicy
+	// results 代表统计窗口，即最近的调用结果的环形缓冲区。
+	results []callResult
+	// next 代表下一个调用结果在环形缓冲区中的位置。
+	next int
+	// failures 代表统计窗口中的失败次数。
+	failures uint32
+	// totalLatency 代表统计窗口中的调用的总耗时。
+	totalLatency time.Duration
+	// state 代表熔断器的状态。
+	state BreakerState
+	// openedAt 代表熔断器最近一次断开的时间。
+	openedAt time.Time
+	// probes 代表半开状态下已放行的试探调用的次数。
+	probes uint32
+	// probeSuccesses 代表半开状态下成功的试探调用的次数。
+	probeSuccesses uint32
+	lock           sync.Mutex
+}
+
+// newModuleHealth 用于创建一个组件健康状况的跟踪器。
+func newModuleHealth(policy HealthPolicy) *moduleHealth {
+	policy = policy.withDefaults()
+	return &moduleHealth{
+		policy:  policy,
+		results: make([]callResult, 0, policy.WindowSize),
+		state:   BREAKER_STATE_CLOSED,
+	}
+}
+
+// available 用于判断组件当前是否可以被选择。本方法不会改变熔断器的状态。
+func (health *moduleHealth) available(now time.Time) bool {
+	health.lock.Lock()
+	defer health.lock.Unlock()
+	if !health.policy.Enabled {
+		return true
+	}
+	switch health.state {
+	case BREAKER_STATE_OPEN:
+		return now.Sub(health.openedAt) >= health.policy.OpenTimeout
+	case BREAKER_STATE_HALF_OPEN:
+		return health.probes < health.policy.HalfOpenProbes
+	}
+	return true
+}
+
+// acquire 用于在组件被选中时更新熔断器的状态。
+// 断开时间已到的熔断器会进入半开状态，并记录一次试探调用。
+func (health *moduleHealth) acquire(now time.Time) {
+	health.lock.Lock()
+	defer health.lock.Unlock()
+	if !health.policy.Enabled {
+		return
+	}
+	if health.state == BREAKER_STATE_OPEN &&
+		now.Sub(health.openedAt) >= health.policy.OpenTimeout {
+		health.state = BREAKER_STATE_HALF_OPEN
+		health.probes = 0
+		health.probeSuccesses = 0
+	}
+	if health.state == BREAKER_STATE_HALF_OPEN {
+		health.probes++
+	}
+}
+
+// report 用于记录一次调用的结果。
+func (health *moduleHealth) report(failed bool, latency time.Duration, now time.Time) {
+	health.lock.Lock()
+	defer health.lock.Unlock()
+	if threshold := health.policy.SlowThreshold; threshold > 0 && latency > threshold {
+		failed = true
+	}
+	health.record(callResult{failed: failed, latency: latency})
+	if !health.policy.Enabled {
+		return
+	}
+	switch health.state {
+	case BREAKER_STATE_HALF_OPEN:
+		if failed {
+			health.open(now)
+			return
+		}
+		health.probeSuccesses++
+		if health.probeSuccesses >= health.policy.HalfOpenProbes {
+			health.close()
+		}
+	case BREAKER_STATE_CLOSED:
+		calls := uint32(len(health.results))
+		if calls >= health.policy.MinCalls &&
+			float64(health.failures) >= health.policy.FailureRate*float64(calls) {
+			health.open(now)
+		}
+	}
+}
+
+// record 用于把调用结果放入统计窗口。
+func (health *moduleHealth) record(result callResult) {
+	if len(health.results) < cap(health.results) {
+		health.results = append(health.results, result)
+	} else {
+		old := health.results[health.next]
+		if old.failed {
+			health.failures--
+		}
+		health.totalLatency -= old.latency
+		health.results[health.next] = result
+	}
+	health.next = (health.next + 1) % cap(health.results)
+	if result.failed {
+		health.failures++
+	}
+	health.totalLatency += result.latency
+}
+
+// open 用于断开熔断器。
+func (health *moduleHealth) open(now time.Time) {
+	health.state = BREAKER_STATE_OPEN
+	health.openedAt = now
+}
+
+// close 用于闭合熔断器，并清空统计窗口。
+func (health *moduleHealth) close() {
+	health.state = BREAKER_STATE_CLOSED
+	health.results = health.results[:0]
+	health.next = 0
+	health.failures = 0
+	health.totalLatency = 0
+}
+
+// summary 用于获取健康状况的摘要。
+func (health *moduleHealth) summary() HealthStruct {
+	health.lock.Lock()
+	defer health.lock.Unlock()
+	calls := uint32(len(health.results))
+	var avgLatency time.Duration
+	if calls > 0 {
+		avgLatency = health.totalLatency / time.Duration(calls)
+	}
+	return HealthStruct{
+		State:      health.state,
+		Calls:      calls,
+		Failures:   health.failures,
+		AvgLatency: avgLatency,
+	}
+}
@@ -0,0 +1,190 @@
+package module
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthPolicyCheck(t *testing.T) {
+	legalPolicies := []HealthPolicy{
+		{},
+		{Enabled: true, WindowSize: 5},
+		{Enabled: true, WindowSize: 10, MinCalls: 10, FailureRate: 1},
+	}
+	for _, policy := range legalPolicies {
+		if err := policy.Check(); err != nil {
+			t.Fatalf("An error occurs when checking health policy: %s (policy: %#v)",
+				err, policy)
+		}
+	}
+	illegalPolicies := []HealthPolicy{
+		{FailureRate: -0.1},
+		{FailureRate: 1.1},
+		{SlowThreshold: -time.Second},
+		{OpenTimeout: -time.Second},
+		{WindowSize: 5, MinCalls: 6},
+		{MinCalls: defaultHealthWindowSize + 1},
+	}
+	for _, policy := range illegalPolicies {
+		if err := policy.Check(); err == nil {
+			t.Fatalf("No error when checking illegal health policy: %#v", policy)
+		}
+	}
+}
+
+func TestHealthBreaker(t *testing.T) {
+	policy := HealthPolicy{
+		Enabled:        true,
+		WindowSize:     4,
+		MinCalls:       4,
+		FailureRate:    0.5,
+		SlowThreshold:  time.Second,
+		OpenTimeout:    time.Minute,
+		HalfOpenProbes: 2,
+	}
+	health := newModuleHealth(policy)
+	now := time.Now()
+	// 调用次数不足时不会断开。
+	health.report(true, time.Millisecond, now)
+	health.report(true, time.Millisecond, now)
+	health.report(false, time.Millisecond, now)
+	if summary := health.summary(); summary.State != BREAKER_STATE_CLOSED {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s",
+			BREAKER_STATE_CLOSED, summary.State)
+	}
+	// 慢调用也会被视为失败。
+	health.report(false, 2*time.Second, now)
+	summary := health.summary()
+	if summary.State != BREAKER_STATE_OPEN {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s",
+			BREAKER_STATE_OPEN, summary.State)
+	}
+	if summary.Calls != 4 || summary.Failures != 3 {
+		t.Fatalf("Inconsistent health summary: %#v", summary)
+	}
+	if health.available(now.Add(time.Second)) {
+		t.Fatal("The module is still available when its breaker is open!")
+	}
+	// 断开时间已到之后进入半开状态，并只放行指定次数的试探调用。
+	later := now.Add(time.Minute)
+	if !health.available(later) {
+		t.Fatal("The module is unavailable after the open timeout!")
+	}
+	health.acquire(later)
+	health.acquire(later)
+	if summary := health.summary(); summary.State != BREAKER_STATE_HALF_OPEN {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s",
+			BREAKER_STATE_HALF_OPEN, summary.State)
+	}
+	if health.available(later) {
+		t.Fatal("The module is still available after all probes were acquired!")
+	}
+	// 试探调用失败会使熔断器重新断开。
+	health.report(true, time.Millisecond, later)
+	if summary := health.summary(); summary.State != BREAKER_STATE_OPEN {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s",
+			BREAKER_STATE_OPEN, summary.State)
+	}
+	// 试探调用全部成功会使熔断器闭合，并清空统计窗口。
+	later = later.Add(time.Minute)
+	health.acquire(later)
+	health.acquire(later)
+	health.report(false, time.Millisecond, later)
+	health.report(false, time.Millisecond, later)
+	summary = health.summary()
+	if summary.State != BREAKER_STATE_CLOSED {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s",
+			BREAKER_STATE_CLOSED, summary.State)
+	}
+	if summary.Calls != 0 || summary.Failures != 0 {
+		t.Fatalf("Inconsistent health summary after closing: %#v", summary)
+	}
+}
+
+func TestHealthDisabled(t *testing.T) {
+	health := newModuleHealth(HealthPolicy{WindowSize: 2, MinCalls: 1})
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		health.report(true, 2*time.Millisecond, now)
+	}
+	summary := health.summary()
+	if summary.State != BREAKER_STATE_CLOSED || !health.available(now) {
+		t.Fatalf("The breaker is open when it is disabled! (summary: %#v)", summary)
+	}
+	if summary.Calls != 2 || summary.Failures != 2 ||
+		summary.AvgLatency != 2*time.Millisecond {
+		t.Fatalf("Inconsistent health summary: %#v", summary)
+	}
+}
+
+func TestRegistrarHealth(t *testing.T) {
+	registrar := NewRegistrar()
+	modules := genCountedDownloaders(1, 5)
+	for _, m := range modules {
+		if _, err := registrar.Register(m); err != nil {
+			t.Fatalf("An error occurs when registering module instance: %s (MID: %s)",
+				err, m.ID())
+		}
+	}
+	if err := registrar.SetHealthPolicy(HealthPolicy{FailureRate: 2}); err == nil {
+		t.Fatal("No error when setting illegal health policy!")
+	}
+	err := registrar.SetHealthPolicy(HealthPolicy{
+		Enabled:     true,
+		WindowSize:  2,
+		MinCalls:    2,
+		OpenTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("An error occurs when setting health policy: %s", err)
+	}
+	if _, ok := registrar.Health("D9"); ok {
+		t.Fatal("It still can get the health of nonexistent module instance!")
+	}
+	// 使评分最优的下载器不健康。
+	failure := errors.New("failure")
+	registrar.Report(modules[0].ID(), failure, time.Millisecond)
+	registrar.Report(modules[0].ID(), failure, time.Millisecond)
+	health, ok := registrar.Health(modules[0].ID())
+	if !ok || health.State != BREAKER_STATE_OPEN {
+		t.Fatalf("Inconsistent health: %#v", health)
+	}
+	for i := 0; i < 3; i++ {
+		m, err := registrar.Get(TYPE_DOWNLOADER)
+		if err != nil {
+			t.Fatalf("An error occurs when getting module instance: %s", err)
+		}
+		if m != modules[1] {
+			t.Fatalf("The unhealthy module instance is still selected! (MID: %s)",
+				m.ID())
+		}
+	}
+	// 全部组件都不健康时仍会从全部组件中选择。
+	registrar.Report(modules[1].ID(), failure, time.Millisecond)
+	registrar.Report(modules[1].ID(), failure, time.Millisecond)
+	if m, err := registrar.Get(TYPE_DOWNLOADER); err != nil || m == nil {
+		t.Fatalf("Couldn't get module instance when all breakers are open! (error: %v)",
+			err)
+	}
+	// 断开时间已到之后，组件会被试探并在成功后恢复。
+	time.Sleep(60 * time.Millisecond)
+	m, err := registrar.Get(TYPE_DOWNLOADER)
+	if err != nil || m != modules[0] {
+		t.Fatalf("The recovered module instance isn't probed! (error: %v)", err)
+	}
+	if health, _ := registrar.Health(m.ID()); health.State != BREAKER_STATE_HALF_OPEN {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s",
+			BREAKER_STATE_HALF_OPEN, health.State)
+	}
+	registrar.Report(m.ID(), nil, time.Millisecond)
+	if health, _ := registrar.Health(m.ID()); health.State != BREAKER_STATE_CLOSED {
+		t.Fatalf("Inconsistent breaker state: expected: %s, actual: %s",
+			BREAKER_STATE_CLOSED, health.State)
+	}
+	// 注销后不再统计健康状况。
+	registrar.Unregister(m.ID())
+	if _, ok := registrar.Health(m.ID()); ok {
+		t.Fatal("It still can get the health of unregistered module instance!")
+	}
+}
@@ -2,6 +2,8 @@ package analyzer
 
 import (
 	"fmt"
+	"io"
+	"time"
 
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/module/stub"
@@ -12,11 +14,35 @@ import (
 // logger 代表日志记录器。
 var logger = log.DLogger()
 
+// defaultSpillThreshold 代表默认的响应体溢出阈值。
+// 超过此长度的响应体会被写入临时文件，而不是保存在内存中。
+const defaultSpillThreshold = 8 << 20
+
+// Option 代表分析器的可选项的类型。
+type Option func(analyzer *myAnalyzer) error
+
+// WithSpillThreshold 用于生成设置响应体溢出阈值的可选项。
+// 长度超过参数threshold的响应体会被写入参数dir代表的目录下的临时文件中，
+// 并在所有解析器都使用完毕之后被删除。
+// 若参数dir为空，则使用系统默认的临时目录。
+func WithSpillThreshold(threshold int64, dir string) Option {
+	return func(analyzer *myAnalyzer) error {
+		if threshold < 0 {
+			return genParameterError(fmt.Sprintf("illegal spill threshold: %d", threshold))
+		}
+		analyzer.spillThreshold = threshold
+		analyzer.spillDir = dir
+		return nil
+	}
+}
+
 // New 用于创建一个分析器实例。
+// 参数opts代表可选项，可以为空。
 func New(
 	mid module.MID,
 	respParsers []module.ParseResponse,
-	scoreCalculator module.CalculateScore) (module.Analyzer, error) {
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Analyzer, error) {
 	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
 	if err != nil {
 		return nil, err
@@ -34,10 +60,20 @@ func New(
 		}
 		innerParsers = append(innerParsers, parser)
 	}
-	return &myAnalyzer{
+	analyzer := &myAnalyzer{
 		ModuleInternal: moduleBase,
 		respParsers:    innerParsers,
-	}, nil
+		spillThreshold: defaultSpillThreshold,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(analyzer); err != nil {
+			return nil, err
+		}
+	}
+	return analyzer, nil
 }
 
 // 分析器的实现类型。
@@ -46,6 +82,10 @@ type myAnalyzer struct {
 	stub.ModuleInternal
 	// respParsers 代表响应解析器列表。
 	respParsers []module.ParseResponse
+	// spillThreshold 代表响应体溢出阈值。
+	spillThreshold int64
+	// spillDir 代表存放溢出的响应体的临时目录。
+	spillDir string
 }
 
 func (analyzer *myAnalyzer) RespParsers() []module.ParseResponse {
@@ -59,6 +99,11 @@ func (analyzer *myAnalyzer) Analyze(
 	analyzer.ModuleInternal.IncrHandlingNumber()
 	defer analyzer.ModuleInternal.DecrHandlingNumber()
 	analyzer.ModuleInternal.IncrCalledCount()
+	defer func() {
+		for _, err := range errorList {
+			analyzer.ModuleInternal.RecordError(err)
+		}
+	}()
 	if resp == nil {
 		errorList = append(errorList,
 			genParameterError("nil response"))
@@ -83,23 +128,40 @@ func (analyzer *myAnalyzer) Analyze(
 		return
 	}
 	analyzer.ModuleInternal.IncrAcceptedCount()
+	begin := time.Now()
+	defer func() {
+		analyzer.ModuleInternal.RecordLatency(time.Since(begin))
+	}()
 	respDepth := resp.Depth()
 	logger.Infof("Parse the response (URL: %s, depth: %d)... \n",
 		reqURL, respDepth)
 
 	// 解析HTTP响应。
+	var respBody io.Reader
 	if httpResp.Body != nil {
 		defer httpResp.Body.Close()
+		respBody = reader.NewCountingReadCloser(
+			httpResp.Body, analyzer.ModuleInternal.AddBytes)
 	}
-	multipleReader, err := reader.NewMultipleReader(httpResp.Body)
+	multipleReader, err := reader.NewSpillMultipleReader(
+		respBody, analyzer.spillThreshold, analyzer.spillDir)
 	if err != nil {
 		errorList = append(errorList, genError(err.Error()))
 		return
 	}
+	defer multipleReader.Close()
+	if check := resp.DuplicateCheck(); check != nil {
+		body := multipleReader.Reader()
+		httpResp.Body = body
+		check(httpResp)
+		body.Close()
+	}
 	dataList = []module.Data{}
 	for _, respParser := range analyzer.respParsers {
-		httpResp.Body = multipleReader.Reader()
+		body := multipleReader.Reader()
+		httpResp.Body = body
 		pDataList, pErrorList := respParser(httpResp, respDepth)
+		body.Close()
 		if pDataList != nil {
 			for _, pData := range pDataList {
 				if pData == nil {
@@ -134,7 +196,11 @@ func appendDataList(dataList []module.Data, data module.Data, respDepth uint32)
 	}
 	newDepth := respDepth + 1
 	if req.Depth() != newDepth {
+		score, scored := req.Score()
 		req = module.NewRequest(req.HTTPReq(), newDepth)
+		if scored {
+			req = req.WithScore(score)
+		}
 	}
 	return append(dataList, req)
 }
@@ -3,6 +3,7 @@ package analyzer
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -163,6 +164,85 @@ func TestAnalyze(t *testing.T) {
 	}
 }
 
+func TestAnalyzeSpill(t *testing.T) {
+	mid := module.MID("A1|127.0.0.1:8080")
+	parsers := []module.ParseResponse{
+		genTestingRespParser(false),
+		genTestingRespParser(false),
+	}
+	dir := t.TempDir()
+	a, err := New(mid, parsers, nil, WithSpillThreshold(5, dir))
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s (mid: %s)",
+			err, mid)
+	}
+	number := uint32(3)
+	resps := getTestingResps(number, "GET", "http://127.0.0.1:8080/", 1, t)
+	var expectedBytes uint64
+	for i, resp := range resps {
+		expectedBytes += uint64(len(fmt.Sprintf(fakeHTTPRespBody, i)))
+		dataList, errorList := a.Analyze(resp)
+		if len(errorList) > 0 {
+			t.Fatalf("An error occurs when analyzing response: %s (index: %d)",
+				errorList[0], i)
+		}
+		if len(dataList) != 2*len(parsers) {
+			t.Fatalf("Inconsistent data list length: expected: %d, actual: %d (index: %d)",
+				2*len(parsers), len(dataList), i)
+		}
+		for _, data := range dataList {
+			item, ok := data.(module.Item)
+			if ok && item["index"] != i {
+				t.Fatalf("Inconsistent index: expected: %d, actual: %v",
+					i, item["index"])
+			}
+		}
+	}
+	if summary := a.Summary(); summary.Bytes != expectedBytes {
+		t.Fatalf("Inconsistent bytes processed: expected: %d, actual: %d",
+			expectedBytes, summary.Bytes)
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("An error occurs when reading directory: %s", err)
+	}
+	if len(infos) != 0 {
+		t.Fatalf("The temp files have not been removed! (number: %d)", len(infos))
+	}
+	if _, err := New(mid, parsers, nil, WithSpillThreshold(-1, dir)); err == nil {
+		t.Fatal("No error when create an analyzer with illegal spill threshold!")
+	}
+}
+
+func TestAnalyzeDuplicateCheck(t *testing.T) {
+	mid := module.MID("A1|127.0.0.1:8080")
+	a, err := New(mid, []module.ParseResponse{genTestingRespParser(false)}, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s (mid: %s)",
+			err, mid)
+	}
+	resp := getTestingResps(1, "GET", "http://127.0.0.1:8080/", 1, t)[0]
+	var checked []string
+	resp = resp.WithDuplicateCheck(func(httpResp *http.Response) bool {
+		body, _ := ioutil.ReadAll(httpResp.Body)
+		checked = append(checked, string(body))
+		return true
+	})
+	dataList, errorList := a.Analyze(resp)
+	if len(errorList) > 0 {
+		t.Fatalf("An error occurs when analyzing response: %s", errorList[0])
+	}
+	expectedBody := fmt.Sprintf(fakeHTTPRespBody, 0)
+	if len(checked) != 1 || checked[0] != expectedBody {
+		t.Fatalf("Inconsistent checked bodies: expected: [%s], actual: %v",
+			expectedBody, checked)
+	}
+	if len(dataList) != 2 {
+		t.Fatalf("Inconsistent data list length: expected: %d, actual: %d",
+			2, len(dataList))
+	}
+}
+
 func TestCount(t *testing.T) {
 	mid := module.MID("D1|127.0.0.1:8080")
 	// 测试初始化后的计数。
@@ -0,0 +1,79 @@
+package parsers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// cssURLPattern 代表CSS中url()引用和@import引用的正则表达式。
+var cssURLPattern = regexp.MustCompile(
+	`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^'"\s)]*))\s*\)` +
+		`|@import\s+(?:"([^"]*)"|'([^']*)')`)
+
+// CSSOptions 代表CSS引用解析器的可选项的类型。
+type CSSOptions struct {
+	// SkipStyleAttrs 代表是否忽略HTML文档中标签的style属性。
+	SkipStyleAttrs bool
+	// IgnoreBase 代表是否忽略base标签。
+	IgnoreBase bool
+}
+
+// NewCSSParser 用于创建一个提取CSS中的url()引用和@import引用的响应解析器。
+// 对于内容为CSS的响应，它会解析整个响应体；
+// 对于内容为HTML的响应，它会解析style标签以及标签的style属性。
+// 每个引用都会被作为一个请求，data:协议的引用会被忽略。
+func NewCSSParser(opts CSSOptions) module.ParseResponse {
+	return func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		reqURL, err := checkResponse(httpResp)
+		if err != nil {
+			return nil, []error{err}
+		}
+		if mediaType(httpResp) == "text/css" {
+			content, err := ioutil.ReadAll(httpResp.Body)
+			if err != nil {
+				return nil, []error{err}
+			}
+			collector := newRequestCollector(reqURL, respDepth)
+			addCSSRefs(collector, string(content))
+			return collector.result()
+		}
+		doc, reqURL, err := parseHTML(httpResp)
+		if err != nil {
+			return nil, []error{err}
+		}
+		if doc == nil {
+			return make([]module.Data, 0), nil
+		}
+		collector := newRequestCollector(
+			baseURL(doc, reqURL, opts.IgnoreBase), respDepth)
+		doc.Find("style").Each(func(index int, sel *goquery.Selection) {
+			addCSSRefs(collector, sel.Text())
+		})
+		if !opts.SkipStyleAttrs {
+			doc.Find("[style]").Each(func(index int, sel *goquery.Selection) {
+				addCSSRefs(collector, sel.AttrOr("style", ""))
+			})
+		}
+		return collector.result()
+	}
+}
+
+// addCSSRefs 用于把CSS中的引用添加到请求收集器。
+func addCSSRefs(collector *requestCollector, css string) {
+	for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		for _, ref := range match[1:] {
+			if ref == "" {
+				continue
+			}
+			if !strings.HasPrefix(strings.ToLower(ref), "data:") {
+				collector.add(ref)
+			}
+			break
+		}
+	}
+}
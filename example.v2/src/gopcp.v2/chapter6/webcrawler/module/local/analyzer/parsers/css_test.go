@@ -0,0 +1,42 @@
+package parsers
+
+import "testing"
+
+func TestCSSParser(t *testing.T) {
+	cases := []parserTestCase{
+		{
+			name:        "html",
+			parser:      NewCSSParser(CSSOptions{}),
+			fixture:     "style.html",
+			contentType: "text/html",
+			expectedURLs: []string{
+				"http://example.com/dir/print.css",
+				"http://example.com/dir/bg.png",
+				"http://example.com/logo.svg",
+				"http://example.com/dir/div.jpg",
+			},
+		},
+		{
+			name:        "html without style attributes",
+			parser:      NewCSSParser(CSSOptions{SkipStyleAttrs: true}),
+			fixture:     "style.html",
+			contentType: "text/html",
+			expectedURLs: []string{
+				"http://example.com/dir/print.css",
+				"http://example.com/dir/bg.png",
+				"http://example.com/logo.svg",
+			},
+		},
+		{
+			name:        "css",
+			parser:      NewCSSParser(CSSOptions{}),
+			fixture:     "style.css",
+			contentType: "text/css",
+			expectedURLs: []string{
+				"http://example.com/dir/base.css",
+				"http://example.com/img/hero.jpg",
+			},
+		},
+	}
+	runParserTestCases(cases, t)
+}
@@ -0,0 +1,309 @@
+package parsers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// FieldsConfig 代表基于CSS选择器的字段映射的配置的类型。
+//
+// Fields中的键为CSS选择器，值为条目中的键。
+// 选择器后可以用“@属性名”指定取值的属性，例如“img.main@src”，
+// 否则取元素的文本。条目中的键若以“[]”结尾，则会取所有匹配元素的值
+// 并组成一个列表（键本身不包含“[]”），否则只取第一个匹配元素的值。
+type FieldsConfig struct {
+	// Scope 代表条目所在元素的CSS选择器。
+	// 若为空，则整个文档只产生一个条目；
+	// 否则每个匹配的元素都会产生一个条目，Fields中的选择器会在该元素内匹配。
+	Scope string `json:"scope"`
+	// Fields 代表CSS选择器与条目中的键的映射。
+	Fields map[string]string `json:"fields"`
+	// URLKey 代表在条目中存放请求URL的键。若为空，则不存放。
+	URLKey string `json:"url_key"`
+}
+
+// Check 用于自检配置的有效性。
+func (config *FieldsConfig) Check() error {
+	if len(config.Fields) == 0 {
+		return fmt.Errorf("empty field map")
+	}
+	if config.Scope != "" {
+		if _, err := cascadia.Compile(config.Scope); err != nil {
+			return fmt.Errorf("illegal scope selector %q: %s", config.Scope, err)
+		}
+	}
+	for selector, key := range config.Fields {
+		if strings.TrimSuffix(key, "[]") == "" {
+			return fmt.Errorf("empty item key for selector %q", selector)
+		}
+		field := parseFieldSelector(selector, key)
+		if field.selector == "" {
+			return fmt.Errorf("empty selector for item key %q", key)
+		}
+		if _, err := cascadia.Compile(field.selector); err != nil {
+			return fmt.Errorf("illegal selector %q: %s", selector, err)
+		}
+	}
+	return nil
+}
+
+// field 代表解析后的字段映射。
+type field struct {
+	// selector 代表CSS选择器。
+	selector string
+	// attr 代表取值的属性。若为空，则取元素的文本。
+	attr string
+	// key 代表条目中的键。
+	key string
+	// multiple 代表是否取所有匹配元素的值。
+	multiple bool
+}
+
+// parseFieldSelector 用于解析字段映射中的选择器和键。
+func parseFieldSelector(selector string, key string) field {
+	f := field{selector: strings.TrimSpace(selector), key: key}
+	if i := strings.LastIndex(f.selector, "@"); i >= 0 && isAttrName(f.selector[i+1:]) {
+		f.attr = f.selector[i+1:]
+		f.selector = strings.TrimSpace(f.selector[:i])
+	}
+	if strings.HasSuffix(f.key, "[]") {
+		f.key = strings.TrimSuffix(f.key, "[]")
+		f.multiple = true
+	}
+	return f
+}
+
+// isAttrName 用于判断给定的字符串是否为合法的属性名。
+func isAttrName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == ':', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// LoadFieldsConfig 用于从文件中加载字段映射的配置。
+// 扩展名为“.yaml”或“.yml”的文件会被视为YAML格式，其他文件会被视为JSON格式。
+// 对于YAML格式，只支持如下形式的简单映射：
+//
+//	scope: div.product
+//	url_key: url
+//	fields:
+//	  h1.title: name
+//	  "img.main@src": image
+//	  "ul.tags > li": tags[]
+func LoadFieldsConfig(path string) (*FieldsConfig, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &FieldsConfig{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = parseFieldsYAML(content, config)
+	default:
+		err = json.Unmarshal(content, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse fields config %s: %s", path, err)
+	}
+	if err := config.Check(); err != nil {
+		return nil, fmt.Errorf("illegal fields config %s: %s", path, err)
+	}
+	return config, nil
+}
+
+// parseFieldsYAML 用于解析YAML格式的字段映射的配置。
+func parseFieldsYAML(content []byte, config *FieldsConfig) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	var inFields bool
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		key, value, err := splitYAMLPair(trimmed)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if !inFields {
+				return fmt.Errorf("line %d: unexpected indentation", lineNumber)
+			}
+			if config.Fields == nil {
+				config.Fields = map[string]string{}
+			}
+			config.Fields[key] = value
+			continue
+		}
+		inFields = false
+		switch key {
+		case "scope":
+			config.Scope = value
+		case "url_key":
+			config.URLKey = value
+		case "fields":
+			if value != "" {
+				return fmt.Errorf("line %d: fields should be a mapping", lineNumber)
+			}
+			inFields = true
+		default:
+			return fmt.Errorf("line %d: unknown key %q", lineNumber, key)
+		}
+	}
+	return scanner.Err()
+}
+
+// splitYAMLPair 用于把YAML中的一行拆分为键和值，键和值都可以被引号包围。
+func splitYAMLPair(line string) (string, string, error) {
+	key, rest, err := readYAMLScalar(line, true)
+	if err != nil {
+		return "", "", err
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, ":") {
+		return "", "", fmt.Errorf("missing colon")
+	}
+	value, rest, err := readYAMLScalar(strings.TrimSpace(rest[1:]), false)
+	if err != nil {
+		return "", "", err
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", "", fmt.Errorf("unexpected content %q", rest)
+	}
+	return key, value, nil
+}
+
+// readYAMLScalar 用于读取YAML中的标量，并返回标量和剩余的内容。
+// 参数isKey代表标量是否为键。未被引号包围的键会在“: ”或行尾的“:”处结束。
+func readYAMLScalar(s string, isKey bool) (string, string, error) {
+	if s == "" {
+		return "", "", nil
+	}
+	switch s[0] {
+	case '"':
+		end := 1
+		for ; end < len(s); end++ {
+			if s[end] == '\\' {
+				end++
+			} else if s[end] == '"' {
+				break
+			}
+		}
+		if end >= len(s) {
+			return "", "", fmt.Errorf("unterminated quoted string")
+		}
+		value, err := strconv.Unquote(s[:end+1])
+		return value, s[end+1:], err
+	case '\'':
+		var builder strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				builder.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				builder.WriteByte('\'')
+				i++
+				continue
+			}
+			return builder.String(), s[i+1:], nil
+		}
+		return "", "", fmt.Errorf("unterminated quoted string")
+	}
+	if isKey {
+		if i := strings.Index(s, ": "); i >= 0 {
+			return strings.TrimSpace(s[:i]), s[i:], nil
+		}
+		if strings.HasSuffix(s, ":") {
+			return strings.TrimSpace(s[:len(s)-1]), ":", nil
+		}
+		return "", "", fmt.Errorf("missing colon")
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		return strings.TrimSpace(s[:i]), s[i:], nil
+	}
+	return s, "", nil
+}
+
+// NewFieldsParser 用于创建一个按照字段映射从HTML文档中提取条目的响应解析器。
+// 没有任何字段被匹配的条目会被忽略。内容不是HTML的响应会被忽略。
+func NewFieldsParser(config FieldsConfig) (module.ParseResponse, error) {
+	if err := config.Check(); err != nil {
+		return nil, err
+	}
+	var fields []field
+	for selector, key := range config.Fields {
+		fields = append(fields, parseFieldSelector(selector, key))
+	}
+	return func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		doc, reqURL, err := parseHTML(httpResp)
+		if err != nil {
+			return nil, []error{err}
+		}
+		dataList := make([]module.Data, 0)
+		if doc == nil {
+			return dataList, nil
+		}
+		scopes := doc.Selection
+		if config.Scope != "" {
+			scopes = doc.Find(config.Scope)
+		}
+		scopes.Each(func(index int, scope *goquery.Selection) {
+			item := module.Item{}
+			for _, f := range fields {
+				values := fieldValues(scope.Find(f.selector), f)
+				switch {
+				case len(values) == 0:
+				case f.multiple:
+					item[f.key] = values
+				default:
+					item[f.key] = values[0]
+				}
+			}
+			if len(item) == 0 {
+				return
+			}
+			if config.URLKey != "" {
+				item[config.URLKey] = reqURL.String()
+			}
+			dataList = append(dataList, item)
+		})
+		return dataList, nil
+	}, nil
+}
+
+// fieldValues 用于获取匹配元素的值的列表。没有相应属性的元素会被忽略。
+func fieldValues(sel *goquery.Selection, f field) []string {
+	var values []string
+	sel.EachWithBreak(func(index int, s *goquery.Selection) bool {
+		if f.attr == "" {
+			values = append(values, strings.TrimSpace(s.Text()))
+		} else if value, exists := s.Attr(f.attr); exists {
+			values = append(values, strings.TrimSpace(value))
+		}
+		return f.multiple || len(values) == 0
+	})
+	return values
+}
@@ -0,0 +1,98 @@
+package parsers
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestLoadFieldsConfig(t *testing.T) {
+	expectedConfig := &FieldsConfig{
+		Scope:  "div.product",
+		URLKey: "url",
+		Fields: map[string]string{
+			"h2.title":     "name",
+			"span.price":   "price",
+			"img.main@src": "image",
+			"ul.tags > li": "tags[]",
+		},
+	}
+	for _, name := range []string{"fields.json", "fields.yaml"} {
+		config, err := LoadFieldsConfig(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("An error occurs when loading fields config: %s", err)
+		}
+		if !reflect.DeepEqual(config, expectedConfig) {
+			t.Fatalf("Inconsistent fields config: expected: %#v, actual: %#v (file: %s)",
+				expectedConfig, config, name)
+		}
+	}
+	illegalContents := map[string]string{
+		"empty.json":     `{"scope": "div"}`,
+		"scope.json":     `{"scope": "div[", "fields": {"h1": "name"}}`,
+		"selector.json":  `{"fields": {"h1[": "name"}}`,
+		"key.json":       `{"fields": {"h1": "[]"}}`,
+		"indent.yaml":    "  h1: name\n",
+		"unknown.yaml":   "scopes: div\n",
+		"colon.yaml":     "fields:\n  h1 name\n",
+		"quote.yaml":     "fields:\n  \"h1: name\n",
+		"mapping.yaml":   "fields: h1\n",
+		"trailing.yaml":  "fields:\n  h1: \"name\" extra\n",
+		"malformed.json": `{`,
+	}
+	dir := t.TempDir()
+	for name, content := range illegalContents {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(content), 0644)
+		if _, err := LoadFieldsConfig(path); err == nil {
+			t.Fatalf("No error when loading illegal fields config %s!", name)
+		}
+	}
+}
+
+func TestFieldsParser(t *testing.T) {
+	config, err := LoadFieldsConfig(filepath.Join("testdata", "fields.yaml"))
+	if err != nil {
+		t.Fatalf("An error occurs when loading fields config: %s", err)
+	}
+	parser, err := NewFieldsParser(*config)
+	if err != nil {
+		t.Fatalf("An error occurs when creating fields parser: %s", err)
+	}
+	documentParser, _ := NewFieldsParser(FieldsConfig{
+		Fields: map[string]string{"h1": "title", "li": "first_tag"},
+	})
+	cases := []itemTestCase{
+		{
+			name:   "scope",
+			parser: parser,
+			expectedItems: []module.Item{
+				{
+					"name":  "Blue Widget",
+					"price": "9.99",
+					"image": "/img/w.png",
+					"tags":  []string{"blue", "widget"},
+					"url":   testingURL,
+				},
+				{
+					"name": "Red Widget",
+					"url":  testingURL,
+				},
+			},
+		},
+		{
+			name:   "document",
+			parser: documentParser,
+			expectedItems: []module.Item{
+				{"title": "Blue Widget", "first_tag": "blue"},
+			},
+		},
+	}
+	runItemTestCases(cases, t)
+	if _, err := NewFieldsParser(FieldsConfig{}); err == nil {
+		t.Fatal("No error when creating fields parser with empty config!")
+	}
+}
@@ -0,0 +1,145 @@
+package parsers
+
+import (
+	"reflect"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// itemTestCase 代表条目解析器的测试用例。
+type itemTestCase struct {
+	// name 代表测试用例的名称。
+	name string
+	// parser 代表被测试的响应解析器。
+	parser module.ParseResponse
+	// expectedErrs 代表预期的错误的数量。
+	expectedErrs int
+	// expectedItems 代表预期的条目的列表。
+	expectedItems []module.Item
+}
+
+// runItemTestCases 用于以testdata/product.html为响应体执行条目解析器的测试用例。
+func runItemTestCases(cases []itemTestCase, t *testing.T) {
+	for _, c := range cases {
+		httpResp := genTestingResp("product.html", "text/html", t)
+		dataList, errs := c.parser(httpResp, 1)
+		httpResp.Body.Close()
+		if len(errs) != c.expectedErrs {
+			t.Fatalf("Inconsistent error number: expected: %d, actual: %d (case: %s, errors: %v)",
+				c.expectedErrs, len(errs), c.name, errs)
+		}
+		if len(dataList) != len(c.expectedItems) {
+			t.Fatalf("Inconsistent item number: expected: %d, actual: %d (case: %s)",
+				len(c.expectedItems), len(dataList), c.name)
+		}
+		for i, data := range dataList {
+			item, ok := data.(module.Item)
+			if !ok {
+				t.Fatalf("Inconsistent data type: expected: %T, actual: %T (case: %s)",
+					module.Item{}, data, c.name)
+			}
+			if !reflect.DeepEqual(item, c.expectedItems[i]) {
+				t.Fatalf("Inconsistent item[%d]: expected: %#v, actual: %#v (case: %s)",
+					i, c.expectedItems[i], item, c.name)
+			}
+		}
+	}
+}
+
+func TestJSONLDParser(t *testing.T) {
+	context := "https://schema.org"
+	product := module.Item{"@context": context, "@type": "Product", "name": "Blue Widget", "sku": "W-1"}
+	cases := []itemTestCase{
+		{
+			name:         "all",
+			parser:       NewJSONLDParser(JSONLDOptions{}),
+			expectedErrs: 1,
+			expectedItems: []module.Item{
+				product,
+				{"@context": context, "@type": []interface{}{"BreadcrumbList"},
+					"itemListElement": []interface{}{}},
+				{"@type": "Organization", "name": "ACME"},
+			},
+		},
+		{
+			name:         "types",
+			parser:       NewJSONLDParser(JSONLDOptions{Types: []string{"product", "BreadcrumbList"}, URLKey: "url"}),
+			expectedErrs: 1,
+			expectedItems: []module.Item{
+				{"@context": context, "@type": "Product", "name": "Blue Widget", "sku": "W-1",
+					"url": testingURL},
+				{"@context": context, "@type": []interface{}{"BreadcrumbList"},
+					"itemListElement": []interface{}{}, "url": testingURL},
+			},
+		},
+	}
+	runItemTestCases(cases, t)
+}
+
+func TestMetaParser(t *testing.T) {
+	cases := []itemTestCase{
+		{
+			name:   "default",
+			parser: NewMetaParser(MetaOptions{}),
+			expectedItems: []module.Item{{
+				"og:title": "Blue Widget",
+				"og:image": []interface{}{
+					"http://example.com/1.jpg",
+					"http://example.com/2.jpg",
+				},
+				"twitter:card": "summary",
+			}},
+		},
+		{
+			name: "names",
+			parser: NewMetaParser(MetaOptions{
+				Prefixes: []string{},
+				Names:    []string{"Description"},
+				URLKey:   "url",
+			}),
+			expectedItems: []module.Item{{
+				"description": "A very blue widget.",
+				"url":         testingURL,
+			}},
+		},
+		{
+			name:          "none",
+			parser:        NewMetaParser(MetaOptions{Prefixes: []string{"fb:"}}),
+			expectedItems: nil,
+		},
+	}
+	runItemTestCases(cases, t)
+}
+
+func TestMicrodataParser(t *testing.T) {
+	product := module.Item{
+		"@type": "http://schema.org/Product",
+		"@id":   "urn:sku:W-1",
+		"name":  "Blue Widget",
+		"image": "http://example.com/img/w.png",
+		"color": []interface{}{"blue", "navy"},
+		"offers": map[string]interface{}{
+			"@type":         "http://schema.org/Offer",
+			"priceCurrency": "USD",
+			"price":         "9.99",
+			"validFrom":     "2024-01-01",
+		},
+	}
+	cases := []itemTestCase{
+		{
+			name:   "all",
+			parser: NewMicrodataParser(MicrodataOptions{}),
+			expectedItems: []module.Item{
+				product,
+				{"@type": "http://schema.org/Person", "name": "Ann"},
+			},
+		},
+		{
+			name:          "types",
+			parser:        NewMicrodataParser(MicrodataOptions{Types: []string{"Product"}}),
+			expectedItems: []module.Item{product},
+		},
+	}
+	runItemTestCases(cases, t)
+}
@@ -0,0 +1,113 @@
+package parsers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// JSONLDOptions 代表JSON-LD解析器的可选项的类型。
+type JSONLDOptions struct {
+	// Types 代表需要提取的对象的类型（即@type）的列表，比较时不区分大小写。
+	// 若为空，则提取所有的顶层对象。
+	Types []string
+	// URLKey 代表在条目中存放请求URL的键。若为空，则不存放。
+	URLKey string
+}
+
+// NewJSONLDParser 用于创建一个提取HTML文档中的JSON-LD
+// （即<script type="application/ld+json">）的响应解析器。
+// 其中的每个顶层对象（包括@graph中的对象）都会被作为一个条目。
+// 内容不是HTML的响应会被忽略。
+func NewJSONLDParser(opts JSONLDOptions) module.ParseResponse {
+	return func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		doc, reqURL, err := parseHTML(httpResp)
+		if err != nil {
+			return nil, []error{err}
+		}
+		dataList := make([]module.Data, 0)
+		if doc == nil {
+			return dataList, nil
+		}
+		var errs []error
+		doc.Find("script[type]").Each(func(index int, sel *goquery.Selection) {
+			scriptType := strings.ToLower(strings.TrimSpace(sel.AttrOr("type", "")))
+			if scriptType != "application/ld+json" {
+				return
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(sel.Text()), &value); err != nil {
+				errs = append(errs, err)
+				return
+			}
+			for _, object := range jsonLDObjects(value) {
+				if !matchJSONLDType(object, opts.Types) {
+					continue
+				}
+				item := module.Item(object)
+				if opts.URLKey != "" {
+					item[opts.URLKey] = reqURL.String()
+				}
+				dataList = append(dataList, item)
+			}
+		})
+		return dataList, errs
+	}
+}
+
+// jsonLDObjects 用于获取JSON-LD中的顶层对象的列表。
+// 数组会被展开，带有@graph的对象会被替换为@graph中的对象。
+func jsonLDObjects(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		var objects []map[string]interface{}
+		for _, element := range v {
+			objects = append(objects, jsonLDObjects(element)...)
+		}
+		return objects
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			objects := jsonLDObjects(graph)
+			if context, ok := v["@context"]; ok {
+				for _, object := range objects {
+					if _, ok := object["@context"]; !ok {
+						object["@context"] = context
+					}
+				}
+			}
+			return objects
+		}
+		return []map[string]interface{}{v}
+	}
+	return nil
+}
+
+// matchJSONLDType 用于判断对象的类型是否在给定的类型列表中。
+// 对象的类型可以是字符串，也可以是字符串的数组。
+func matchJSONLDType(object map[string]interface{}, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	var objectTypes []interface{}
+	switch v := object["@type"].(type) {
+	case string:
+		objectTypes = []interface{}{v}
+	case []interface{}:
+		objectTypes = v
+	}
+	for _, objectType := range objectTypes {
+		s, ok := objectType.(string)
+		if !ok {
+			continue
+		}
+		for _, t := range types {
+			if strings.EqualFold(s, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package parsers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// LinkSource 代表链接来源的类型。
+type LinkSource uint32
+
+// 链接来源的常量。
+const (
+	// LINK_SOURCE_A 代表a标签的href属性。
+	LINK_SOURCE_A LinkSource = 1 << iota
+	// LINK_SOURCE_LINK 代表link标签的href属性。
+	LINK_SOURCE_LINK
+	// LINK_SOURCE_AREA 代表area标签的href属性。
+	LINK_SOURCE_AREA
+	// LINK_SOURCE_IFRAME 代表iframe标签和frame标签的src属性。
+	LINK_SOURCE_IFRAME
+	// LINK_SOURCE_IMG 代表img标签的src属性和srcset属性，以及source标签的srcset属性。
+	LINK_SOURCE_IMG
+	// LINK_SOURCE_ALL 代表所有的链接来源。
+	LINK_SOURCE_ALL = LINK_SOURCE_A | LINK_SOURCE_LINK | LINK_SOURCE_AREA |
+		LINK_SOURCE_IFRAME | LINK_SOURCE_IMG
+)
+
+// LinkOptions 代表链接解析器的可选项的类型。
+type LinkOptions struct {
+	// Sources 代表需要提取的链接来源，可以用“|”组合。
+	// 若为0，则代表LINK_SOURCE_ALL。
+	Sources LinkSource
+	// FollowNofollow 代表是否提取带有rel="nofollow"的链接。
+	// 若为false，则带有rel="nofollow"的链接会被忽略，
+	// 且在文档中有<meta name="robots" content="nofollow">时不提取任何链接。
+	FollowNofollow bool
+	// IgnoreBase 代表是否忽略base标签。
+	// 若为false，则相对地址会以base标签的href属性为基准进行解析。
+	IgnoreBase bool
+}
+
+// linkSelector 代表链接的选择器、地址所在的属性及其来源。
+type linkSelector struct {
+	selector string
+	attr     string
+	source   LinkSource
+}
+
+// linkSelectors 代表所有链接的选择器的列表。
+var linkSelectors = []linkSelector{
+	{"a[href]", "href", LINK_SOURCE_A},
+	{"link[href]", "href", LINK_SOURCE_LINK},
+	{"area[href]", "href", LINK_SOURCE_AREA},
+	{"iframe[src]", "src", LINK_SOURCE_IFRAME},
+	{"frame[src]", "src", LINK_SOURCE_IFRAME},
+	{"img[src]", "src", LINK_SOURCE_IMG},
+	{"img[srcset]", "srcset", LINK_SOURCE_IMG},
+	{"source[srcset]", "srcset", LINK_SOURCE_IMG},
+}
+
+// NewLinkParser 用于创建一个提取HTML文档中的链接的响应解析器。
+// 每个链接都会被作为一个请求，重复的链接只会保留一个。
+// 内容不是HTML的响应会被忽略。
+func NewLinkParser(opts LinkOptions) module.ParseResponse {
+	sources := opts.Sources
+	if sources == 0 {
+		sources = LINK_SOURCE_ALL
+	}
+	return func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		doc, reqURL, err := parseHTML(httpResp)
+		if err != nil {
+			return nil, []error{err}
+		}
+		if doc == nil {
+			return make([]module.Data, 0), nil
+		}
+		collector := newRequestCollector(
+			baseURL(doc, reqURL, opts.IgnoreBase), respDepth)
+		if !opts.FollowNofollow && metaNofollow(doc) {
+			return collector.result()
+		}
+		for _, ls := range linkSelectors {
+			if sources&ls.source == 0 {
+				continue
+			}
+			doc.Find(ls.selector).Each(func(index int, sel *goquery.Selection) {
+				if !opts.FollowNofollow && hasToken(sel.AttrOr("rel", ""), "nofollow") {
+					return
+				}
+				value := sel.AttrOr(ls.attr, "")
+				if ls.attr == "srcset" {
+					for _, candidate := range parseSrcset(value) {
+						collector.add(candidate)
+					}
+					return
+				}
+				collector.add(value)
+			})
+		}
+		return collector.result()
+	}
+}
+
+// metaNofollow 用于判断文档是否通过meta标签禁止了跟踪其中的链接。
+func metaNofollow(doc *goquery.Document) bool {
+	var nofollow bool
+	doc.Find("meta[name][content]").Each(func(index int, sel *goquery.Selection) {
+		if !strings.EqualFold(sel.AttrOr("name", ""), "robots") {
+			return
+		}
+		content := strings.Replace(sel.AttrOr("content", ""), ",", " ", -1)
+		if hasToken(content, "nofollow") || hasToken(content, "none") {
+			nofollow = true
+		}
+	})
+	return nofollow
+}
+
+// parseSrcset 用于解析srcset属性，并返回其中的地址的列表。
+func parseSrcset(srcset string) []string {
+	var refs []string
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 {
+			refs = append(refs, fields[0])
+		}
+	}
+	return refs
+}
@@ -0,0 +1,74 @@
+package parsers
+
+import "testing"
+
+func TestLinkParser(t *testing.T) {
+	cases := []parserTestCase{
+		{
+			name:        "all",
+			parser:      NewLinkParser(LinkOptions{}),
+			fixture:     "links.html",
+			contentType: "text/html; charset=utf-8",
+			expectedURLs: []string{
+				"http://cdn.example.com/static/a.html",
+				"http://cdn.example.com/static/main.css",
+				"http://example.com/page",
+				"https://example.com/area",
+				"http://frames.example.com/frame.html",
+				"http://cdn.example.com/static/img/1.png",
+				"http://cdn.example.com/static/img/1x.png",
+				"http://cdn.example.com/static/img/2x.png",
+				"http://cdn.example.com/static/img/s.webp",
+			},
+		},
+		{
+			name: "anchors without base",
+			parser: NewLinkParser(LinkOptions{
+				Sources:        LINK_SOURCE_A,
+				FollowNofollow: true,
+				IgnoreBase:     true,
+			}),
+			fixture:     "links.html",
+			contentType: "text/html",
+			expectedURLs: []string{
+				"http://example.com/dir/a.html",
+				"http://example.com/b.html",
+			},
+		},
+		{
+			name:        "images",
+			parser:      NewLinkParser(LinkOptions{Sources: LINK_SOURCE_IMG | LINK_SOURCE_IFRAME}),
+			fixture:     "links.html",
+			contentType: "application/xhtml+xml",
+			expectedURLs: []string{
+				"http://frames.example.com/frame.html",
+				"http://cdn.example.com/static/img/1.png",
+				"http://cdn.example.com/static/img/1x.png",
+				"http://cdn.example.com/static/img/2x.png",
+				"http://cdn.example.com/static/img/s.webp",
+			},
+		},
+		{
+			name:         "meta nofollow",
+			parser:       NewLinkParser(LinkOptions{}),
+			fixture:      "nofollow.html",
+			contentType:  "text/html",
+			expectedURLs: nil,
+		},
+		{
+			name:         "meta nofollow followed",
+			parser:       NewLinkParser(LinkOptions{FollowNofollow: true}),
+			fixture:      "nofollow.html",
+			contentType:  "text/html",
+			expectedURLs: []string{"http://example.com/a.html"},
+		},
+		{
+			name:         "not html",
+			parser:       NewLinkParser(LinkOptions{}),
+			fixture:      "links.html",
+			contentType:  "text/plain",
+			expectedURLs: nil,
+		},
+	}
+	runParserTestCases(cases, t)
+}
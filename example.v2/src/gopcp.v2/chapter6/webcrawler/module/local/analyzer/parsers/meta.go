@@ -0,0 +1,111 @@
+package parsers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// RefreshOptions 代表元刷新解析器的可选项的类型。
+type RefreshOptions struct {
+	// MaxDelay 代表元刷新的最大延迟时间。
+	// 延迟时间大于此值的元刷新会被忽略。若为0，则不做限制。
+	MaxDelay time.Duration
+	// IgnoreBase 代表是否忽略base标签。
+	IgnoreBase bool
+}
+
+// NewRefreshParser 用于创建一个提取HTML文档中的元刷新
+// （即<meta http-equiv="refresh" content="5; url=...">）的目标地址的响应解析器。
+// 目标地址会被作为一个请求。内容不是HTML的响应会被忽略。
+func NewRefreshParser(opts RefreshOptions) module.ParseResponse {
+	return func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		doc, reqURL, err := parseHTML(httpResp)
+		if err != nil {
+			return nil, []error{err}
+		}
+		if doc == nil {
+			return make([]module.Data, 0), nil
+		}
+		collector := newRequestCollector(
+			baseURL(doc, reqURL, opts.IgnoreBase), respDepth)
+		doc.Find("meta[http-equiv][content]").Each(func(index int, sel *goquery.Selection) {
+			if !strings.EqualFold(sel.AttrOr("http-equiv", ""), "refresh") {
+				return
+			}
+			delay, ref, ok := parseRefresh(sel.AttrOr("content", ""))
+			if !ok || (opts.MaxDelay > 0 && delay > opts.MaxDelay) {
+				return
+			}
+			collector.add(ref)
+		})
+		return collector.result()
+	}
+}
+
+// parseRefresh 用于解析元刷新的content属性，并返回延迟时间和目标地址。
+// 若没有目标地址，则第三个结果值为false。
+func parseRefresh(content string) (time.Duration, string, bool) {
+	content = strings.TrimSpace(content)
+	i := strings.IndexAny(content, ";,")
+	if i < 0 {
+		return 0, "", false
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(content[:i]), 64)
+	if err != nil || seconds < 0 {
+		return 0, "", false
+	}
+	ref := strings.TrimSpace(content[i+1:])
+	if len(ref) >= 4 && strings.EqualFold(ref[:3], "url") {
+		if rest := strings.TrimSpace(ref[3:]); strings.HasPrefix(rest, "=") {
+			ref = strings.TrimSpace(rest[1:])
+		}
+	}
+	ref = strings.Trim(ref, `"'`)
+	if ref == "" {
+		return 0, "", false
+	}
+	return time.Duration(seconds * float64(time.Second)), ref, true
+}
+
+// CanonicalOptions 代表规范链接解析器的可选项的类型。
+type CanonicalOptions struct {
+	// IncludeSelf 代表在规范链接与请求的URL相同时是否仍然产生请求。
+	IncludeSelf bool
+	// IgnoreBase 代表是否忽略base标签。
+	IgnoreBase bool
+}
+
+// NewCanonicalParser 用于创建一个提取HTML文档中的规范链接
+// （即<link rel="canonical" href="...">）的响应解析器。
+// 规范链接会被作为一个请求。内容不是HTML的响应会被忽略。
+func NewCanonicalParser(opts CanonicalOptions) module.ParseResponse {
+	return func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		doc, reqURL, err := parseHTML(httpResp)
+		if err != nil {
+			return nil, []error{err}
+		}
+		if doc == nil {
+			return make([]module.Data, 0), nil
+		}
+		collector := newRequestCollector(
+			baseURL(doc, reqURL, opts.IgnoreBase), respDepth)
+		if !opts.IncludeSelf {
+			self := *reqURL
+			self.Fragment = ""
+			collector.seen[self.String()] = true
+		}
+		doc.Find("link[rel][href]").EachWithBreak(func(index int, sel *goquery.Selection) bool {
+			if !hasToken(sel.AttrOr("rel", ""), "canonical") {
+				return true
+			}
+			collector.add(sel.AttrOr("href", ""))
+			return false
+		})
+		return collector.result()
+	}
+}
@@ -0,0 +1,73 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetaParsers(t *testing.T) {
+	cases := []parserTestCase{
+		{
+			name:        "refresh",
+			parser:      NewRefreshParser(RefreshOptions{}),
+			fixture:     "meta.html",
+			contentType: "text/html",
+			expectedURLs: []string{
+				"http://example.com/next.html",
+				"http://example.com/later.html",
+			},
+		},
+		{
+			name:         "refresh with max delay",
+			parser:       NewRefreshParser(RefreshOptions{MaxDelay: time.Minute}),
+			fixture:      "meta.html",
+			contentType:  "text/html",
+			expectedURLs: []string{"http://example.com/next.html"},
+		},
+		{
+			name:         "canonical",
+			parser:       NewCanonicalParser(CanonicalOptions{}),
+			fixture:      "meta.html",
+			contentType:  "text/html",
+			expectedURLs: []string{"http://example.com/canonical.html"},
+		},
+		{
+			name:         "canonical self",
+			parser:       NewCanonicalParser(CanonicalOptions{}),
+			fixture:      "self.html",
+			contentType:  "text/html",
+			expectedURLs: nil,
+		},
+		{
+			name:         "canonical self included",
+			parser:       NewCanonicalParser(CanonicalOptions{IncludeSelf: true}),
+			fixture:      "self.html",
+			contentType:  "text/html",
+			expectedURLs: []string{testingURL},
+		},
+	}
+	runParserTestCases(cases, t)
+}
+
+func TestParseRefresh(t *testing.T) {
+	cases := []struct {
+		content string
+		delay   time.Duration
+		ref     string
+		ok      bool
+	}{
+		{"0; url=/a", 0, "/a", true},
+		{"1.5,URL = \"/b\"", 1500 * time.Millisecond, "/b", true},
+		{"3;/c", 3 * time.Second, "/c", true},
+		{"3; url=", 0, "", false},
+		{"5", 0, "", false},
+		{"x; url=/d", 0, "", false},
+	}
+	for _, c := range cases {
+		delay, ref, ok := parseRefresh(c.content)
+		if delay != c.delay || ref != c.ref || ok != c.ok {
+			t.Fatalf("Inconsistent result for %q: expected: %s %q %v, actual: %s %q %v",
+				c.content, c.delay, c.ref, c.ok, delay, ref, ok)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package parsers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// MicrodataOptions 代表微数据解析器的可选项的类型。
+type MicrodataOptions struct {
+	// Types 代表需要提取的微数据项的类型（即itemtype）的列表。
+	// 只要itemtype中的某个URL以其中的某一项结尾就算匹配，
+	// 例如“Product”可以匹配“http://schema.org/Product”。
+	// 若为空，则提取所有的顶层微数据项。
+	Types []string
+	// URLKey 代表在条目中存放请求URL的键。若为空，则不存放。
+	URLKey string
+}
+
+// NewMicrodataParser 用于创建一个提取HTML文档中的微数据的响应解析器。
+// 每个顶层的微数据项（即带有itemscope但没有itemprop的元素）都会被作为一个条目，
+// 其类型和ID会分别被存放在“@type”和“@id”键中，嵌套的微数据项会被作为子字典。
+// 重复出现的属性的值会被合并为一个列表。内容不是HTML的响应会被忽略。
+func NewMicrodataParser(opts MicrodataOptions) module.ParseResponse {
+	return func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		doc, reqURL, err := parseHTML(httpResp)
+		if err != nil {
+			return nil, []error{err}
+		}
+		dataList := make([]module.Data, 0)
+		if doc == nil {
+			return dataList, nil
+		}
+		base := baseURL(doc, reqURL, false)
+		doc.Find("[itemscope]").Each(func(index int, sel *goquery.Selection) {
+			if _, exists := sel.Attr("itemprop"); exists {
+				return
+			}
+			if !matchItemType(sel.AttrOr("itemtype", ""), opts.Types) {
+				return
+			}
+			item := module.Item(microdataItem(sel.Get(0), base))
+			if opts.URLKey != "" {
+				item[opts.URLKey] = reqURL.String()
+			}
+			dataList = append(dataList, item)
+		})
+		return dataList, nil
+	}
+}
+
+// matchItemType 用于判断微数据项的类型是否在给定的类型列表中。
+func matchItemType(itemType string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range strings.Fields(itemType) {
+		for _, expected := range types {
+			if strings.HasSuffix(strings.ToLower(t), strings.ToLower(expected)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// microdataItem 用于把带有itemscope的元素转换为字典。
+func microdataItem(node *html.Node, base *url.URL) map[string]interface{} {
+	item := module.Item{}
+	if itemType := nodeAttr(node, "itemtype"); itemType != "" {
+		item["@type"] = itemType
+	}
+	if itemID := nodeAttr(node, "itemid"); itemID != "" {
+		item["@id"] = itemID
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		collectMicrodataProps(child, base, item)
+	}
+	return item
+}
+
+// collectMicrodataProps 用于把属于当前微数据项的属性收集到字典中。
+// 嵌套的微数据项的内部属性不属于当前微数据项。
+func collectMicrodataProps(node *html.Node, base *url.URL, item module.Item) {
+	if node.Type != html.ElementNode {
+		return
+	}
+	_, scoped := nodeAttrOK(node, "itemscope")
+	if props := strings.Fields(nodeAttr(node, "itemprop")); len(props) > 0 {
+		var value interface{}
+		if scoped {
+			value = microdataItem(node, base)
+		} else {
+			value = microdataValue(node, base)
+		}
+		for _, prop := range props {
+			addItemValue(item, prop, value)
+		}
+	}
+	if scoped {
+		return
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		collectMicrodataProps(child, base, item)
+	}
+}
+
+// microdataValue 用于获取不带有itemscope的属性元素的值。
+func microdataValue(node *html.Node, base *url.URL) string {
+	var attr string
+	var isURL bool
+	switch node.Data {
+	case "meta":
+		attr = "content"
+	case "a", "area", "link":
+		attr, isURL = "href", true
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		attr, isURL = "src", true
+	case "object":
+		attr, isURL = "data", true
+	case "data", "meter":
+		attr = "value"
+	case "time":
+		if _, ok := nodeAttrOK(node, "datetime"); ok {
+			attr = "datetime"
+		}
+	}
+	if attr == "" {
+		return strings.TrimSpace(nodeText(node))
+	}
+	value := strings.TrimSpace(nodeAttr(node, attr))
+	if isURL && value != "" {
+		if ref, err := url.Parse(value); err == nil {
+			value = base.ResolveReference(ref).String()
+		}
+	}
+	return value
+}
+
+// nodeAttrOK 用于获取元素的属性值，第二个结果值代表属性是否存在。
+func nodeAttrOK(node *html.Node, key string) (string, bool) {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// nodeAttr 用于获取元素的属性值。
+func nodeAttr(node *html.Node, key string) string {
+	value, _ := nodeAttrOK(node, key)
+	return value
+}
+
+// nodeText 用于获取节点中所有文本的拼接。
+func nodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var builder strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		builder.WriteString(nodeText(child))
+	}
+	return builder.String()
+}
@@ -0,0 +1,93 @@
+package parsers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// DefaultMetaPrefixes 代表默认会被提取的meta标签的名称前缀的列表。
+var DefaultMetaPrefixes = []string{"og:", "twitter:", "article:", "product:"}
+
+// MetaOptions 代表meta标签解析器的可选项的类型。
+type MetaOptions struct {
+	// Prefixes 代表需要提取的meta标签的property或name属性的前缀的列表，
+	// 比较时不区分大小写。若为nil，则使用DefaultMetaPrefixes。
+	Prefixes []string
+	// Names 代表需要完整匹配的meta标签的name属性的列表，例如“description”。
+	Names []string
+	// URLKey 代表在条目中存放请求URL的键。若为空，则不存放。
+	URLKey string
+}
+
+// NewMetaParser 用于创建一个提取HTML文档中的OpenGraph等meta标签的响应解析器。
+// 匹配的meta标签的property或name属性会被作为键，content属性会被作为值，
+// 它们会被一并放入一个条目。重复出现的键的值会被合并为一个列表。
+// 没有匹配的meta标签或内容不是HTML的响应不会产生条目。
+func NewMetaParser(opts MetaOptions) module.ParseResponse {
+	prefixes := opts.Prefixes
+	if prefixes == nil {
+		prefixes = DefaultMetaPrefixes
+	}
+	return func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		doc, reqURL, err := parseHTML(httpResp)
+		if err != nil {
+			return nil, []error{err}
+		}
+		dataList := make([]module.Data, 0)
+		if doc == nil {
+			return dataList, nil
+		}
+		item := module.Item{}
+		doc.Find("meta[content]").Each(func(index int, sel *goquery.Selection) {
+			key := sel.AttrOr("property", "")
+			if key == "" {
+				key = sel.AttrOr("name", "")
+			}
+			key = strings.ToLower(strings.TrimSpace(key))
+			if key == "" || !matchMetaKey(key, prefixes, opts.Names) {
+				return
+			}
+			addItemValue(item, key, strings.TrimSpace(sel.AttrOr("content", "")))
+		})
+		if len(item) == 0 {
+			return dataList, nil
+		}
+		if opts.URLKey != "" {
+			item[opts.URLKey] = reqURL.String()
+		}
+		return append(dataList, item), nil
+	}
+}
+
+// matchMetaKey 用于判断meta标签的键是否需要被提取。
+func matchMetaKey(key string, prefixes []string, names []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	for _, name := range names {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// addItemValue 用于向条目中添加值。
+// 若键已存在，则已有的值和新的值会被合并为一个列表。
+func addItemValue(item module.Item, key string, value interface{}) {
+	existing, ok := item[key]
+	if !ok {
+		item[key] = value
+		return
+	}
+	if values, ok := existing.([]interface{}); ok {
+		item[key] = append(values, value)
+		return
+	}
+	item[key] = []interface{}{existing, value}
+}
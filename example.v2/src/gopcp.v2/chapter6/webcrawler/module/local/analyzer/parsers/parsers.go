@@ -0,0 +1,166 @@
+// Package parsers 提供了一些现成的响应解析函数，
+// 可以直接被用作分析器的响应解析器。
+package parsers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// checkResponse 用于检查响应，并返回响应对应的请求的URL。
+func checkResponse(httpResp *http.Response) (*url.URL, error) {
+	if httpResp == nil {
+		return nil, fmt.Errorf("nil HTTP response")
+	}
+	httpReq := httpResp.Request
+	if httpReq == nil {
+		return nil, fmt.Errorf("nil HTTP request")
+	}
+	reqURL := httpReq.URL
+	if reqURL == nil {
+		return nil, fmt.Errorf("nil HTTP request URL")
+	}
+	if httpResp.StatusCode != 200 {
+		return nil, fmt.Errorf("unsupported status code %d (requestURL: %s)",
+			httpResp.StatusCode, reqURL)
+	}
+	if httpResp.Body == nil {
+		return nil, fmt.Errorf("nil HTTP response body (requestURL: %s)",
+			reqURL)
+	}
+	return reqURL, nil
+}
+
+// mediaType 用于获取响应的媒体类型。
+func mediaType(httpResp *http.Response) string {
+	if httpResp.Header == nil {
+		return ""
+	}
+	contentType := httpResp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// isHTML 用于判断响应的内容是否为HTML。
+func isHTML(httpResp *http.Response) bool {
+	switch mediaType(httpResp) {
+	case "text/html", "application/xhtml+xml":
+		return true
+	}
+	return false
+}
+
+// parseHTML 用于检查响应并把响应体解析为HTML文档。
+// 若响应的内容不是HTML，则第一个结果值为nil。
+func parseHTML(httpResp *http.Response) (*goquery.Document, *url.URL, error) {
+	reqURL, err := checkResponse(httpResp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isHTML(httpResp) {
+		return nil, reqURL, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(httpResp.Body)
+	if err != nil {
+		return nil, reqURL, err
+	}
+	return doc, reqURL, nil
+}
+
+// baseURL 用于获取文档中相对地址的基准URL。
+// 若文档中有带href属性的base标签，且不被忽略，则使用它，否则使用请求的URL。
+func baseURL(doc *goquery.Document, reqURL *url.URL, ignoreBase bool) *url.URL {
+	if ignoreBase {
+		return reqURL
+	}
+	href, exists := doc.Find("base[href]").First().Attr("href")
+	if !exists {
+		return reqURL
+	}
+	base, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return reqURL
+	}
+	return reqURL.ResolveReference(base)
+}
+
+// hasToken 用于判断以空白分隔的属性值中是否包含给定的记号（不区分大小写）。
+func hasToken(value string, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestCollector 代表请求收集器。
+// 它会把地址解析为绝对URL，并忽略不可爬取和重复的地址。
+type requestCollector struct {
+	// base 代表相对地址的基准URL。
+	base *url.URL
+	// depth 代表响应的深度。
+	depth uint32
+	// seen 代表已收集的URL的集合。
+	seen map[string]bool
+	// dataList 代表已收集的请求的列表。
+	dataList []module.Data
+	// errs 代表收集过程中发生的错误的列表。
+	errs []error
+}
+
+// newRequestCollector 用于创建一个请求收集器。
+func newRequestCollector(base *url.URL, depth uint32) *requestCollector {
+	return &requestCollector{
+		base:     base,
+		depth:    depth,
+		seen:     map[string]bool{},
+		dataList: make([]module.Data, 0),
+	}
+}
+
+// add 用于收集给定地址对应的请求。
+// 空地址、片段地址以及协议不是HTTP或HTTPS的地址都会被忽略。
+func (collector *requestCollector) add(ref string) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		collector.errs = append(collector.errs, err)
+		return
+	}
+	absURL := collector.base.ResolveReference(refURL)
+	scheme := strings.ToLower(absURL.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return
+	}
+	absURL.Fragment = ""
+	urlStr := absURL.String()
+	if collector.seen[urlStr] {
+		return
+	}
+	collector.seen[urlStr] = true
+	httpReq, err := http.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		collector.errs = append(collector.errs, err)
+		return
+	}
+	collector.dataList = append(collector.dataList,
+		module.NewRequest(httpReq, collector.depth))
+}
+
+// result 用于获取收集的结果。
+func (collector *requestCollector) result() ([]module.Data, []error) {
+	return collector.dataList, collector.errs
+}
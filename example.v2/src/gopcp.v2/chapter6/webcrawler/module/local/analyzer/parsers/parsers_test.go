@@ -0,0 +1,94 @@
+package parsers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// testingURL 代表测试用的请求的URL。
+const testingURL = "http://example.com/dir/page.html"
+
+// parserTestCase 代表响应解析器的测试用例。
+type parserTestCase struct {
+	// name 代表测试用例的名称。
+	name string
+	// parser 代表被测试的响应解析器。
+	parser module.ParseResponse
+	// fixture 代表testdata目录下的响应体文件的名称。
+	fixture string
+	// contentType 代表响应的内容类型。
+	contentType string
+	// expectedURLs 代表预期的请求的URL的列表。
+	expectedURLs []string
+}
+
+// genTestingResp 用于以testdata目录下的文件为响应体生成响应。
+func genTestingResp(fixture string, contentType string, t *testing.T) *http.Response {
+	file, err := os.Open(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("An error occurs when opening fixture: %s", err)
+	}
+	httpReq, _ := http.NewRequest("GET", testingURL, nil)
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     header,
+		Body:       file,
+		Request:    httpReq,
+	}
+}
+
+// runParserTestCases 用于执行响应解析器的测试用例。
+func runParserTestCases(cases []parserTestCase, t *testing.T) {
+	for _, c := range cases {
+		httpResp := genTestingResp(c.fixture, c.contentType, t)
+		dataList, errs := c.parser(httpResp, 1)
+		httpResp.Body.Close()
+		if len(errs) > 0 {
+			t.Fatalf("An error occurs when parsing response: %s (case: %s)",
+				errs[0], c.name)
+		}
+		if len(dataList) != len(c.expectedURLs) {
+			t.Fatalf("Inconsistent data number: expected: %d, actual: %d (case: %s)",
+				len(c.expectedURLs), len(dataList), c.name)
+		}
+		for i, data := range dataList {
+			req, ok := data.(*module.Request)
+			if !ok {
+				t.Fatalf("Inconsistent data type: expected: %T, actual: %T (case: %s)",
+					&module.Request{}, data, c.name)
+			}
+			if req.Depth() != 1 {
+				t.Fatalf("Inconsistent depth: expected: %d, actual: %d (case: %s)",
+					1, req.Depth(), c.name)
+			}
+			if url := req.HTTPReq().URL.String(); url != c.expectedURLs[i] {
+				t.Fatalf("Inconsistent URL[%d]: expected: %s, actual: %s (case: %s)",
+					i, c.expectedURLs[i], url, c.name)
+			}
+		}
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	parser := NewLinkParser(LinkOptions{})
+	httpReq, _ := http.NewRequest("GET", testingURL, nil)
+	body := ioutil.NopCloser(nil)
+	illegalResps := []*http.Response{
+		nil,
+		{StatusCode: 200, Body: body},
+		{StatusCode: 404, Body: body, Request: httpReq},
+		{StatusCode: 200, Request: httpReq},
+	}
+	for i, httpResp := range illegalResps {
+		if _, errs := parser(httpResp, 0); len(errs) == 0 {
+			t.Fatalf("No error when parsing illegal response! (index: %d)", i)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/toolkit/canonical"
+)
+
+// cacheEntry 代表磁盘缓存中单个响应的元数据。
+type cacheEntry struct {
+	// URL 代表响应对应的规范化之后的URL。
+	URL string `json:"url"`
+	// StatusCode 代表响应的状态码。
+	StatusCode int `json:"status_code"`
+	// Header 代表响应的头部。
+	Header http.Header `json:"header"`
+	// ETag 代表响应的ETag。
+	ETag string `json:"etag,omitempty"`
+	// LastModified 代表响应的Last-Modified。
+	LastModified string `json:"last_modified,omitempty"`
+	// StoredAt 代表响应被缓存的时间。
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// responseCache 代表以规范化之后的URL为键的磁盘响应缓存。
+// 响应的元数据和响应体会分别被保存在目录下的两个文件中。
+type responseCache struct {
+	// dir 代表缓存目录。
+	dir string
+	// rules 代表生成键时使用的URL规范化规则。
+	rules canonical.Rules
+	// hits 代表缓存命中（即收到304响应）的次数。
+	hits uint64
+	// misses 代表缓存未命中的次数。
+	misses uint64
+}
+
+// newResponseCache 用于创建一个磁盘响应缓存。
+func newResponseCache(dir string) (*responseCache, error) {
+	if dir == "" {
+		return nil, genParameterError("empty cache directory")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, genError(fmt.Sprintf("couldn't create cache directory: %s", err))
+	}
+	return &responseCache{dir: dir}, nil
+}
+
+// paths 用于获取给定请求对应的元数据文件和响应体文件的路径。
+func (cache *responseCache) paths(httpReq *http.Request) (string, string, string) {
+	key := cache.rules.Canonicalize(httpReq.URL)
+	sum := sha1.Sum([]byte(key))
+	name := filepath.Join(cache.dir, hex.EncodeToString(sum[:]))
+	return key, name + ".json", name + ".body"
+}
+
+// load 用于加载给定请求对应的缓存条目和响应体。
+// 若缓存不存在或已损坏，则第三个结果值为false。
+func (cache *responseCache) load(httpReq *http.Request) (*cacheEntry, []byte, bool) {
+	key, metaPath, bodyPath := cache.paths(httpReq)
+	metaBytes, err := ioutil.ReadFile(metaPath)
+	if err != nil {
+		return nil, nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(metaBytes, &entry); err != nil || entry.URL != key {
+		return nil, nil, false
+	}
+	body, err := ioutil.ReadFile(bodyPath)
+	if err != nil {
+		return nil, nil, false
+	}
+	return &entry, body, true
+}
+
+// store 用于以给定的请求为键缓存响应及其响应体。
+// 只有带有ETag或Last-Modified的响应才会被缓存。
+func (cache *responseCache) store(
+	httpReq *http.Request, httpResp *http.Response, body []byte) error {
+	etag := httpResp.Header.Get("ETag")
+	lastModified := httpResp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return nil
+	}
+	key, metaPath, bodyPath := cache.paths(httpReq)
+	entry := cacheEntry{
+		URL:          key,
+		StatusCode:   httpResp.StatusCode,
+		Header:       httpResp.Header,
+		ETag:         etag,
+		LastModified: lastModified,
+		StoredAt:     time.Now(),
+	}
+	metaBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	// 先写响应体再写元数据，以保证元数据存在时响应体一定是完整的。
+	if err := writeFileAtomically(bodyPath, body); err != nil {
+		return err
+	}
+	return writeFileAtomically(metaPath, metaBytes)
+}
+
+// writeFileAtomically 会先写入临时文件再重命名，以免留下不完整的文件。
+// 每次写入都会使用独立的临时文件，因此可以被多个下载器并发地调用。
+func writeFileAtomically(path string, data []byte) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Chmod(0644)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// do 会在缓存存在时发送条件请求，并在收到304响应时用缓存还原出响应。
+// 对于新获取的可缓存响应，它会读取并缓存其响应体。
+// 参数send代表实际发送请求的函数。
+func (cache *responseCache) do(
+	send func(*http.Request) (*http.Response, error),
+	httpReq *http.Request) (*http.Response, error) {
+	if httpReq.Method != "" && httpReq.Method != http.MethodGet {
+		atomic.AddUint64(&cache.misses, 1)
+		return send(httpReq)
+	}
+	entry, body, ok := cache.load(httpReq)
+	actualReq := httpReq
+	if ok {
+		// 复制请求，以免条件请求的头部影响到原有的请求。
+		actualReq = httpReq.Clone(httpReq.Context())
+		if entry.ETag != "" {
+			actualReq.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			actualReq.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	httpResp, err := send(actualReq)
+	if err != nil {
+		return nil, err
+	}
+	if ok && httpResp.StatusCode == http.StatusNotModified {
+		httpResp.Body.Close()
+		atomic.AddUint64(&cache.hits, 1)
+		logger.Infof("Use the cached response (URL: %s)... \n", httpReq.URL)
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+			StatusCode:    entry.StatusCode,
+			Proto:         httpResp.Proto,
+			ProtoMajor:    httpResp.ProtoMajor,
+			ProtoMinor:    httpResp.ProtoMinor,
+			Header:        entry.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       httpReq,
+		}, nil
+	}
+	atomic.AddUint64(&cache.misses, 1)
+	if httpResp.Request == actualReq {
+		httpResp.Request = httpReq
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return httpResp, nil
+	}
+	if httpResp.Header.Get("ETag") == "" && httpResp.Header.Get("Last-Modified") == "" {
+		return httpResp, nil
+	}
+	body, err = ioutil.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := cache.store(httpReq, httpResp, body); err != nil {
+		logger.Warnf("Couldn't cache the response: %s (URL: %s)\n", err, httpReq.URL)
+	}
+	return httpResp, nil
+}
+
+// Hits 用于获取缓存命中的次数。
+func (cache *responseCache) Hits() uint64 {
+	return atomic.LoadUint64(&cache.hits)
+}
+
+// Misses 用于获取缓存未命中的次数。
+func (cache *responseCache) Misses() uint64 {
+	return atomic.LoadUint64(&cache.misses)
+}
@@ -0,0 +1,155 @@
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// cacheTestingServer 代表测试缓存用的服务器。
+type cacheTestingServer struct {
+	*httptest.Server
+	// conditionalHits 代表收到的条件请求的数量。
+	conditionalHits int
+	lock            sync.Mutex
+}
+
+// newCacheTestingServer 用于创建一个测试缓存用的服务器。
+// 路径/etag的响应带有ETag，路径/modified的响应带有Last-Modified，
+// 路径/plain的响应则两者都没有。
+func newCacheTestingServer() *cacheTestingServer {
+	cs := &cacheTestingServer{}
+	lastModified := "Mon, 02 Jan 2006 15:04:05 GMT"
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				cs.countConditionalHit()
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/modified":
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				cs.countConditionalHit()
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "content of %s", r.URL.Path)
+	}))
+	return cs
+}
+
+func (cs *cacheTestingServer) countConditionalHit() {
+	cs.lock.Lock()
+	cs.conditionalHits++
+	cs.lock.Unlock()
+}
+
+func (cs *cacheTestingServer) ConditionalHits() int {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	return cs.conditionalHits
+}
+
+func TestCacheDownload(t *testing.T) {
+	server := newCacheTestingServer()
+	defer server.Close()
+	mid := module.MID("D1|127.0.0.1:8080")
+	d, err := New(mid, &http.Client{}, nil, WithCache(t.TempDir()))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", err)
+	}
+	download := func(path string) (int, string) {
+		httpReq, _ := http.NewRequest("GET", server.URL+path, nil)
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			t.Fatalf("An error occurs when downloading content: %s (path: %s)",
+				err, path)
+		}
+		httpResp := resp.HTTPResp()
+		if httpResp.Request != httpReq {
+			t.Fatalf("Inconsistent HTTP request of response! (path: %s)", path)
+		}
+		defer httpResp.Body.Close()
+		body, err := ioutil.ReadAll(httpResp.Body)
+		if err != nil {
+			t.Fatalf("An error occurs when reading response body: %s (path: %s)",
+				err, path)
+		}
+		if httpReq.Header.Get("If-None-Match") != "" ||
+			httpReq.Header.Get("If-Modified-Since") != "" {
+			t.Fatalf("The original HTTP request has been changed! (path: %s)", path)
+		}
+		return httpResp.StatusCode, string(body)
+	}
+	paths := []string{"/etag", "/modified", "/plain"}
+	for i := 0; i < 2; i++ {
+		for _, path := range paths {
+			statusCode, body := download(path)
+			expectedBody := "content of " + path
+			if statusCode != http.StatusOK || body != expectedBody {
+				t.Fatalf("Inconsistent response: expected: %d %q, actual: %d %q",
+					http.StatusOK, expectedBody, statusCode, body)
+			}
+		}
+	}
+	if hits := server.ConditionalHits(); hits != 2 {
+		t.Fatalf("Inconsistent conditional request number: expected: %d, actual: %d",
+			2, hits)
+	}
+	expectedExtra := extraSummaryStruct{CacheHits: 2, CacheMisses: 4}
+	if extra := d.Summary().Extra; extra != expectedExtra {
+		t.Fatalf("Inconsistent extra summary: expected: %#v, actual: %#v",
+			expectedExtra, extra)
+	}
+	d, _ = New(mid, &http.Client{}, nil)
+	if extra := d.Summary().Extra; extra != nil {
+		t.Fatalf("Non-nil extra summary without cache: %#v", extra)
+	}
+	if _, err := New(mid, &http.Client{}, nil, WithCache("")); err == nil {
+		t.Fatal("No error when create a downloader with empty cache directory!")
+	}
+}
+
+func TestCacheWriteConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "entry")
+	number := 20
+	var wg sync.WaitGroup
+	for i := 0; i < number; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(strings.Repeat(fmt.Sprintf("%02d", i), 1024))
+			if err := writeFileAtomically(path, data); err != nil {
+				t.Errorf("An error occurs when writing file: %s (index: %d)", err, i)
+			}
+		}(i)
+	}
+	wg.Wait()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("An error occurs when reading file: %s", err)
+	}
+	if len(data) != 2048 || string(data) != strings.Repeat(string(data[:2]), 1024) {
+		t.Fatalf("The file has been corrupted! (length: %d)", len(data))
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("An error occurs when reading directory: %s", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("The temp files have not been removed! (number: %d)", len(infos)-1)
+	}
+}
@@ -2,20 +2,41 @@ package downloader
 
 import (
 	"net/http"
+	"time"
 
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/module/stub"
+	"gopcp.v2/chapter6/webcrawler/toolkit/reader"
 	"gopcp.v2/helper/log"
 )
 
 // logger 代表日志记录器。
 var logger = log.DLogger()
 
+// Option 代表下载器的可选项的类型。
+type Option func(downloader *myDownloader) error
+
+// WithCache 用于生成启用磁盘响应缓存的可选项。
+// 参数dir代表缓存目录，响应体及其ETag和Last-Modified都会以规范化之后的URL为键保存在其中。
+// 之后对同一URL的下载会发送条件请求，收到的304响应会被替换为缓存中的响应。
+func WithCache(dir string) Option {
+	return func(downloader *myDownloader) error {
+		cache, err := newResponseCache(dir)
+		if err != nil {
+			return err
+		}
+		downloader.cache = cache
+		return nil
+	}
+}
+
 // New 用于创建一个下载器实例。
+// 参数opts代表可选项，可以为空。
 func New(
 	mid module.MID,
 	client *http.Client,
-	scoreCalculator module.CalculateScore) (module.Downloader, error) {
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Downloader, error) {
 	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
 	if err != nil {
 		return nil, err
@@ -23,10 +44,19 @@ func New(
 	if client == nil {
 		return nil, genParameterError("nil http client")
 	}
-	return &myDownloader{
+	downloader := &myDownloader{
 		ModuleInternal: moduleBase,
 		httpClient:     *client,
-	}, nil
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(downloader); err != nil {
+			return nil, err
+		}
+	}
+	return downloader, nil
 }
 
 // myDownloader 代表下载器的实现类型。
@@ -35,6 +65,16 @@ type myDownloader struct {
 	stub.ModuleInternal
 	// httpClient 代表下载用的HTTP客户端。
 	httpClient http.Client
+	// cache 代表磁盘响应缓存。若为nil，则不使用缓存。
+	cache *responseCache
+	// maxBodyBytes 代表响应体的最大字节数。若为0，则不限制。
+	maxBodyBytes int64
+	// truncateBody 代表响应体超出限制时是否截断。
+	truncateBody bool
+	// allowedContentTypes 代表允许的内容类型的列表。若为空，则不限制。
+	allowedContentTypes []string
+	// timeout 代表单个请求的超时时间。若为0，则不限制。
+	timeout time.Duration
 }
 
 func (downloader *myDownloader) Download(req *module.Request) (*module.Response, error) {
@@ -42,18 +82,47 @@ func (downloader *myDownloader) Download(req *module.Request) (*module.Response,
 	defer downloader.ModuleInternal.DecrHandlingNumber()
 	downloader.ModuleInternal.IncrCalledCount()
 	if req == nil {
-		return nil, genParameterError("nil request")
+		err := genParameterError("nil request")
+		downloader.ModuleInternal.RecordError(err)
+		return nil, err
 	}
 	httpReq := req.HTTPReq()
 	if httpReq == nil {
-		return nil, genParameterError("nil HTTP request")
+		err := genParameterError("nil HTTP request")
+		downloader.ModuleInternal.RecordError(err)
+		return nil, err
 	}
 	downloader.ModuleInternal.IncrAcceptedCount()
 	logger.Infof("Do the request (URL: %s, depth: %d)... \n", httpReq.URL, req.Depth())
-	httpResp, err := downloader.httpClient.Do(httpReq)
+	begin := time.Now()
+	httpResp, err := downloader.do(httpReq, req.Unlimited())
+	downloader.ModuleInternal.RecordLatency(time.Since(begin))
 	if err != nil {
+		downloader.ModuleInternal.RecordError(err)
 		return nil, err
 	}
+	// 响应体的字节数会在其被读取时统计。
+	if httpResp.Body != nil {
+		httpResp.Body = reader.NewCountingReadCloser(
+			httpResp.Body, downloader.ModuleInternal.AddBytes)
+	}
 	downloader.ModuleInternal.IncrCompletedCount()
 	return module.NewResponse(httpResp, req.Depth()), nil
 }
+
+// extraSummaryStruct 代表下载器额外信息的摘要类型。
+type extraSummaryStruct struct {
+	CacheHits   uint64 `json:"cache_hits"`
+	CacheMisses uint64 `json:"cache_misses"`
+}
+
+func (downloader *myDownloader) Summary() module.SummaryStruct {
+	summary := downloader.ModuleInternal.Summary()
+	if downloader.cache != nil {
+		summary.Extra = extraSummaryStruct{
+			CacheHits:   downloader.cache.Hits(),
+			CacheMisses: downloader.cache.Misses(),
+		}
+	}
+	return summary
+}
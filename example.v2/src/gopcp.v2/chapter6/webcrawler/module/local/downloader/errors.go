@@ -13,3 +13,8 @@ func genParameterError(errMsg string) error {
 	return errors.NewCrawlerErrorBy(errors.ERROR_TYPE_DOWNLOADER,
 		errors.NewIllegalParameterError(errMsg))
 }
+
+// genErrorBy 用于生成包装了给定错误值的爬虫错误值。
+func genErrorBy(err error) error {
+	return errors.NewCrawlerErrorBy(errors.ERROR_TYPE_DOWNLOADER, err)
+}
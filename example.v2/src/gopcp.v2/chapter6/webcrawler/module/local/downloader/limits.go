@@ -0,0 +1,240 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/errors"
+)
+
+// WithMaxBodyBytes 用于生成限制响应体大小的可选项。
+// 参数max代表响应体的最大字节数，必须大于0。
+// 参数truncate代表响应体超出限制时的处理方式：
+// 若为true，则响应体会被截断；否则会以BodyTooLargeError拒绝该响应。
+func WithMaxBodyBytes(max int64, truncate bool) Option {
+	return func(downloader *myDownloader) error {
+		if max <= 0 {
+			return genParameterError(fmt.Sprintf("illegal max body bytes: %d", max))
+		}
+		downloader.maxBodyBytes = max
+		downloader.truncateBody = truncate
+		return nil
+	}
+}
+
+// WithAllowedContentTypes 用于生成限制响应的内容类型的可选项。
+// 参数contentTypes代表允许的媒体类型的列表，例如“text/html”，
+// 其中也可以使用“text/*”这样的通配形式。
+// 内容类型不被允许的响应会在读取响应体之前以ContentTypeError拒绝。
+func WithAllowedContentTypes(contentTypes ...string) Option {
+	return func(downloader *myDownloader) error {
+		if len(contentTypes) == 0 {
+			return genParameterError("empty allowed content type list")
+		}
+		allowed := make([]string, 0, len(contentTypes))
+		for _, contentType := range contentTypes {
+			contentType = strings.ToLower(strings.TrimSpace(contentType))
+			if strings.Count(contentType, "/") != 1 ||
+				strings.HasPrefix(contentType, "/") ||
+				strings.HasSuffix(contentType, "/") {
+				errMsg := fmt.Sprintf("illegal content type: %q", contentType)
+				return genParameterError(errMsg)
+			}
+			allowed = append(allowed, contentType)
+		}
+		downloader.allowedContentTypes = allowed
+		return nil
+	}
+}
+
+// WithMaxRedirects 用于生成限制重定向次数的可选项。
+// 参数max代表最大的重定向次数，为0时不允许重定向。
+// 超出限制时会返回TooManyRedirectsError。
+func WithMaxRedirects(max int) Option {
+	return func(downloader *myDownloader) error {
+		if max < 0 {
+			return genParameterError(fmt.Sprintf("illegal max redirects: %d", max))
+		}
+		downloader.httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) > max {
+				return errors.NewTooManyRedirectsError(via[0].URL.String(), max)
+			}
+			return nil
+		}
+		return nil
+	}
+}
+
+// WithTimeout 用于生成限制单个请求的下载时间的可选项。
+// 参数timeout代表超时时间，它包含读取响应体的时间，必须大于0。
+// 超时时会返回DownloadTimeoutError。
+func WithTimeout(timeout time.Duration) Option {
+	return func(downloader *myDownloader) error {
+		if timeout <= 0 {
+			return genParameterError(fmt.Sprintf("illegal timeout: %s", timeout))
+		}
+		downloader.timeout = timeout
+		return nil
+	}
+}
+
+// do 用于在各项限制之下执行给定的HTTP请求。
+// 参数unlimited为true时，响应的内容类型和响应体的大小不受限制，
+// 但超时时间和重定向次数的限制仍然有效。
+func (downloader *myDownloader) do(httpReq *http.Request, unlimited bool) (*http.Response, error) {
+	var cancel context.CancelFunc
+	if downloader.timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(httpReq.Context(), downloader.timeout)
+		httpReq = httpReq.WithContext(ctx)
+	}
+	send := downloader.send
+	if unlimited {
+		send = downloader.httpClient.Do
+	}
+	var httpResp *http.Response
+	var err error
+	if downloader.cache != nil {
+		httpResp, err = downloader.cache.do(send, httpReq)
+	} else {
+		httpResp, err = send(httpReq)
+	}
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return nil, downloader.convertError(httpReq, err)
+	}
+	if cancel != nil {
+		httpResp.Body = &cancelBody{ReadCloser: httpResp.Body, cancel: cancel}
+	}
+	return httpResp, nil
+}
+
+// send 用于发送HTTP请求，并检查响应的内容类型和响应体的大小。
+func (downloader *myDownloader) send(httpReq *http.Request) (*http.Response, error) {
+	httpResp, err := downloader.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode == http.StatusNotModified {
+		return httpResp, nil
+	}
+	urlStr := httpReq.URL.String()
+	if contentType, ok := downloader.contentTypeAllowed(httpResp); !ok {
+		httpResp.Body.Close()
+		return nil, genErrorBy(errors.NewContentTypeError(urlStr, contentType))
+	}
+	if max := downloader.maxBodyBytes; max > 0 {
+		if !downloader.truncateBody && httpResp.ContentLength > max {
+			httpResp.Body.Close()
+			return nil, genErrorBy(errors.NewBodyTooLargeError(urlStr, max))
+		}
+		httpResp.Body = &limitedBody{
+			ReadCloser: httpResp.Body,
+			remaining:  max,
+			truncate:   downloader.truncateBody,
+			url:        urlStr,
+			limit:      max,
+		}
+		if httpResp.ContentLength > max {
+			httpResp.ContentLength = max
+		}
+	}
+	return httpResp, nil
+}
+
+// contentTypeAllowed 用于判断响应的内容类型是否被允许。
+// 第一个结果值代表响应的媒体类型。
+func (downloader *myDownloader) contentTypeAllowed(httpResp *http.Response) (string, bool) {
+	if len(downloader.allowedContentTypes) == 0 {
+		return "", true
+	}
+	contentType := httpResp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType, false
+	}
+	for _, allowed := range downloader.allowedContentTypes {
+		if allowed == mediaType || allowed == "*/*" {
+			return mediaType, true
+		}
+		if strings.HasSuffix(allowed, "/*") &&
+			strings.HasPrefix(mediaType, allowed[:len(allowed)-1]) {
+			return mediaType, true
+		}
+	}
+	return mediaType, false
+}
+
+// convertError 用于把HTTP客户端返回的超时和重定向错误转换为相应的错误类型。
+func (downloader *myDownloader) convertError(httpReq *http.Request, err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	if tmre, ok := urlErr.Err.(errors.TooManyRedirectsError); ok {
+		return genErrorBy(tmre)
+	}
+	if urlErr.Timeout() {
+		timeout := downloader.timeout
+		if timeout <= 0 {
+			timeout = downloader.httpClient.Timeout
+		}
+		return genErrorBy(errors.NewDownloadTimeoutError(httpReq.URL.String(), timeout))
+	}
+	return err
+}
+
+// limitedBody 代表限制了读取长度的响应体。
+type limitedBody struct {
+	io.ReadCloser
+	// remaining 代表还可以读取的字节数。
+	remaining int64
+	// truncate 代表超出限制时是否截断。
+	truncate bool
+	// url 代表请求的URL。
+	url string
+	// limit 代表响应体的最大字节数。
+	limit int64
+}
+
+func (body *limitedBody) Read(p []byte) (int, error) {
+	if body.remaining <= 0 {
+		if body.truncate {
+			return 0, io.EOF
+		}
+		// 多读取一个字节以判断响应体是否超出了限制。
+		var b [1]byte
+		n, err := body.ReadCloser.Read(b[:])
+		if n > 0 {
+			return 0, genErrorBy(errors.NewBodyTooLargeError(body.url, body.limit))
+		}
+		return 0, err
+	}
+	if int64(len(p)) > body.remaining {
+		p = p[:body.remaining]
+	}
+	n, err := body.ReadCloser.Read(p)
+	body.remaining -= int64(n)
+	return n, err
+}
+
+// cancelBody 代表会在关闭时释放请求上下文的响应体。
+type cancelBody struct {
+	io.ReadCloser
+	// cancel 代表释放请求上下文的函数。
+	cancel context.CancelFunc
+}
+
+func (body *cancelBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
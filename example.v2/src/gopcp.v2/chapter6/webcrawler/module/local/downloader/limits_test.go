@@ -0,0 +1,140 @@
+package downloader
+
+import (
+	stderrors "errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/errors"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// newLimitsTestingServer 用于创建一个测试下载限制用的服务器。
+func newLimitsTestingServer() *httptest.Server {
+	body := strings.Repeat("a", 100)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sized":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(body))
+		case "/chunked":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(body[:50]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(body[50:]))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(body))
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(body))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestLimitsDownload(t *testing.T) {
+	server := newLimitsTestingServer()
+	defer server.Close()
+	mid := module.MID("D1|127.0.0.1:8080")
+	newDownloader := func(opts ...Option) module.Downloader {
+		d, err := New(mid, &http.Client{}, nil, opts...)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a downloader: %s", err)
+		}
+		return d
+	}
+	download := func(d module.Downloader, path string) (string, error) {
+		httpReq, _ := http.NewRequest("GET", server.URL+path, nil)
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			return "", err
+		}
+		defer resp.HTTPResp().Body.Close()
+		body, err := ioutil.ReadAll(resp.HTTPResp().Body)
+		return string(body), err
+	}
+	// 截断。
+	d := newDownloader(WithMaxBodyBytes(10, true))
+	for _, path := range []string{"/sized", "/chunked"} {
+		body, err := download(d, path)
+		if err != nil {
+			t.Fatalf("An error occurs when downloading content: %s (path: %s)", err, path)
+		}
+		if body != strings.Repeat("a", 10) {
+			t.Fatalf("Inconsistent truncated body: %q (path: %s)", body, path)
+		}
+	}
+	// 拒绝。
+	d = newDownloader(WithMaxBodyBytes(10, false))
+	for _, path := range []string{"/sized", "/chunked"} {
+		_, err := download(d, path)
+		var target errors.BodyTooLargeError
+		if !stderrors.As(err, &target) {
+			t.Fatalf("Inconsistent error: expected: %T, actual: %#v (path: %s)",
+				target, err, path)
+		}
+	}
+	if _, err := download(newDownloader(WithMaxBodyBytes(100, false)), "/sized"); err != nil {
+		t.Fatalf("An error occurs when downloading content: %s", err)
+	}
+	// 内容类型。
+	d = newDownloader(WithAllowedContentTypes("text/*"))
+	if _, err := download(d, "/sized"); err != nil {
+		t.Fatalf("An error occurs when downloading content: %s", err)
+	}
+	_, err := download(d, "/image")
+	var contentTypeErr errors.ContentTypeError
+	if !stderrors.As(err, &contentTypeErr) {
+		t.Fatalf("Inconsistent error: expected: %T, actual: %#v", contentTypeErr, err)
+	}
+	// 不受限制的请求。
+	d = newDownloader(WithAllowedContentTypes("text/*"), WithMaxBodyBytes(10, false))
+	httpReq, _ := http.NewRequest("GET", server.URL+"/image", nil)
+	resp, err := d.Download(module.NewUnlimitedRequest(httpReq))
+	if err != nil {
+		t.Fatalf("An error occurs when downloading content without limits: %s", err)
+	}
+	body, err := ioutil.ReadAll(resp.HTTPResp().Body)
+	resp.HTTPResp().Body.Close()
+	if err != nil || len(body) != 100 {
+		t.Fatalf("Inconsistent unlimited body length: expected: %d, actual: %d (error: %v)",
+			100, len(body), err)
+	}
+	// 重定向。
+	_, err = download(newDownloader(WithMaxRedirects(2)), "/loop")
+	var redirectsErr errors.TooManyRedirectsError
+	if !stderrors.As(err, &redirectsErr) {
+		t.Fatalf("Inconsistent error: expected: %T, actual: %#v", redirectsErr, err)
+	}
+	// 超时。
+	_, err = download(newDownloader(WithTimeout(50*time.Millisecond)), "/slow")
+	var timeoutErr errors.DownloadTimeoutError
+	if !stderrors.As(err, &timeoutErr) || !timeoutErr.Timeout() {
+		t.Fatalf("Inconsistent error: expected: %T, actual: %#v", timeoutErr, err)
+	}
+	if _, err := download(newDownloader(WithTimeout(time.Second)), "/slow"); err != nil {
+		t.Fatalf("An error occurs when downloading content: %s", err)
+	}
+	// 非法的可选项。
+	illegalOpts := []Option{
+		WithMaxBodyBytes(0, true),
+		WithAllowedContentTypes(),
+		WithAllowedContentTypes("text"),
+		WithMaxRedirects(-1),
+		WithTimeout(0),
+	}
+	for i, opt := range illegalOpts {
+		if _, err := New(mid, &http.Client{}, nil, opt); err == nil {
+			t.Fatalf("No error when creating a downloader with illegal option! (index: %d)", i)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package pipeline
 
 import (
 	"fmt"
+	"io"
+	"sync"
+	"time"
 
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/module/stub"
@@ -11,11 +14,31 @@ import (
 // logger 代表日志记录器。
 var logger = log.DLogger()
 
+// Option 代表条目处理管道的可选项的类型。
+type Option func(pipeline *myPipeline) error
+
+// WithClosers 用于生成设置需要随条目处理管道一同关闭的对象的可选项。
+// 条目处理管道实现了io.Closer接口，调度器会在停止时关闭它，
+// 进而按顺序关闭这些对象，例如写出缓冲中的条目的输出端。
+func WithClosers(closers ...io.Closer) Option {
+	return func(pipeline *myPipeline) error {
+		for i, closer := range closers {
+			if closer == nil {
+				return genParameterError(fmt.Sprintf("nil closer[%d]", i))
+			}
+		}
+		pipeline.closers = append(pipeline.closers, closers...)
+		return nil
+	}
+}
+
 // New 用于创建一个条目处理管道实例。
+// 参数opts代表可选项，可以为空。
 func New(
 	mid module.MID,
 	itemProcessors []module.ProcessItem,
-	scoreCalculator module.CalculateScore) (module.Pipeline, error) {
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Pipeline, error) {
 	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
 	if err != nil {
 		return nil, err
@@ -34,10 +57,19 @@ func New(
 		}
 		innerProcessors = append(innerProcessors, pipeline)
 	}
-	return &myPipeline{
+	pipeline := &myPipeline{
 		ModuleInternal: moduleBase,
 		itemProcessors: innerProcessors,
-	}, nil
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(pipeline); err != nil {
+			return nil, err
+		}
+	}
+	return pipeline, nil
 }
 
 // myPipeline 代表条目处理管道的实现类型。
@@ -48,6 +80,10 @@ type myPipeline struct {
 	itemProcessors []module.ProcessItem
 	// failFast 代表处理是否需要快速失败。
 	failFast bool
+	// closers 代表需要随条目处理管道一同关闭的对象的列表。
+	closers []io.Closer
+	// closeOnce 用于保证只关闭一次。
+	closeOnce sync.Once
 }
 
 func (pipeline *myPipeline) ItemProcessors() []module.ProcessItem {
@@ -61,12 +97,21 @@ func (pipeline *myPipeline) Send(item module.Item) []error {
 	defer pipeline.ModuleInternal.DecrHandlingNumber()
 	pipeline.ModuleInternal.IncrCalledCount()
 	var errs []error
+	defer func() {
+		for _, err := range errs {
+			pipeline.ModuleInternal.RecordError(err)
+		}
+	}()
 	if item == nil {
 		err := genParameterError("nil item")
 		errs = append(errs, err)
 		return errs
 	}
 	pipeline.ModuleInternal.IncrAcceptedCount()
+	begin := time.Now()
+	defer func() {
+		pipeline.ModuleInternal.RecordLatency(time.Since(begin))
+	}()
 	logger.Infof("Process item %+v... \n", item)
 	var currentItem = item
 	for _, processor := range pipeline.itemProcessors {
@@ -87,6 +132,20 @@ func (pipeline *myPipeline) Send(item module.Item) []error {
 	return errs
 }
 
+// Close 用于按顺序关闭需要随条目处理管道一同关闭的对象。
+// 结果值为其中第一个错误。多次调用只会关闭一次。
+func (pipeline *myPipeline) Close() error {
+	var err error
+	pipeline.closeOnce.Do(func() {
+		for _, closer := range pipeline.closers {
+			if closeErr := closer.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	})
+	return err
+}
+
 func (pipeline *myPipeline) FailFast() bool {
 	return pipeline.failFast
 }
@@ -3,6 +3,7 @@ package pipeline
 import (
 	"errors"
 	"fmt"
+	"io"
 	"testing"
 
 	"gopcp.v2/chapter6/webcrawler/module"
@@ -318,3 +319,46 @@ func genTestingItemProccessor(fail bool) module.ProcessItem {
 		return item, nil
 	}
 }
+
+// testingCloser 代表测试专用的可关闭对象。
+type testingCloser struct {
+	closed int
+	err    error
+}
+
+func (c *testingCloser) Close() error {
+	c.closed++
+	return c.err
+}
+
+func TestClose(t *testing.T) {
+	mid := module.MID("D1|127.0.0.1:8080")
+	processors := []module.ProcessItem{genTestingItemProccessor(false)}
+	closers := []*testingCloser{{}, {err: errors.New("close error")}, {}}
+	p, err := New(mid, processors, nil,
+		WithClosers(closers[0], closers[1], closers[2]))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a pipeline: %s (mid: %s)",
+			err, mid)
+	}
+	closer, ok := p.(io.Closer)
+	if !ok {
+		t.Fatal("The pipeline is not a closer!")
+	}
+	for i := 0; i < 2; i++ {
+		err := closer.Close()
+		if i == 0 && err != closers[1].err {
+			t.Fatalf("Inconsistent error: expected: %s, actual: %v",
+				closers[1].err, err)
+		}
+	}
+	for i, c := range closers {
+		if c.closed != 1 {
+			t.Fatalf("Inconsistent close count for closer[%d]: expected: %d, actual: %d",
+				i, 1, c.closed)
+		}
+	}
+	if _, err := New(mid, processors, nil, WithClosers(nil)); err == nil {
+		t.Fatal("No error when create a pipeline with nil closer!")
+	}
+}
@@ -0,0 +1,157 @@
+package sinks
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// Column 代表表格中的列的类型。
+type Column struct {
+	// Name 代表列名。若为空，则使用Key。
+	Name string
+	// Key 代表列的值在条目中的键。
+	Key string
+	// Type 代表列的类型，只对数据库输出端有效。若为空，则使用“TEXT”。
+	Type string
+}
+
+// name 用于获取实际的列名。
+func (column Column) name() string {
+	if column.Name != "" {
+		return column.Name
+	}
+	return column.Key
+}
+
+// checkColumns 用于检查列的列表。
+func checkColumns(columns []Column) error {
+	if len(columns) == 0 {
+		return genParameterError("empty column list")
+	}
+	names := map[string]bool{}
+	for i, column := range columns {
+		if column.Key == "" {
+			return genParameterError(fmt.Sprintf("empty key of column[%d]", i))
+		}
+		if names[column.name()] {
+			return genParameterError(fmt.Sprintf("duplicate column %q", column.name()))
+		}
+		names[column.name()] = true
+	}
+	return nil
+}
+
+// CSVArgs 代表CSV输出端的参数容器的类型。
+type CSVArgs struct {
+	// Path 代表文件的路径。若文件已存在，则条目会被追加到文件末尾。
+	Path string
+	// Columns 代表列的列表。
+	Columns []Column
+	// Batch 代表批量写出相关的参数。
+	Batch BatchArgs
+}
+
+// NewCSVSink 用于创建一个把条目按照列的定义写入CSV文件的输出端。
+// 若文件为空，则会先写入表头。条目中缺少的值会被写为空字符串，
+// 字符串以外的值会被格式化，其中的字典和列表会被编码为JSON。
+func NewCSVSink(args CSVArgs) (Sink, error) {
+	if args.Path == "" {
+		return nil, genParameterError("empty path")
+	}
+	if err := checkColumns(args.Columns); err != nil {
+		return nil, err
+	}
+	if err := args.Batch.Check(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(args.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, genError(fmt.Sprintf("couldn't open file: %s", err))
+	}
+	writer := &csvWriter{args: args, file: file, csv: csv.NewWriter(file)}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, genError(fmt.Sprintf("couldn't stat file: %s", err))
+	}
+	if info.Size() == 0 {
+		header := make([]string, len(args.Columns))
+		for i, column := range args.Columns {
+			header[i] = column.name()
+		}
+		writer.csv.Write(header)
+		writer.csv.Flush()
+		if err := writer.csv.Error(); err != nil {
+			file.Close()
+			return nil, genError(fmt.Sprintf("couldn't write file: %s", err))
+		}
+	}
+	return newBatchSink(args.Batch, writer), nil
+}
+
+// csvWriter 代表CSV文件的写出器。
+type csvWriter struct {
+	// args 代表参数。
+	args CSVArgs
+	// file 代表文件。
+	file *os.File
+	// csv 代表CSV编码器。
+	csv *csv.Writer
+}
+
+func (writer *csvWriter) write(items []module.Item) error {
+	record := make([]string, len(writer.args.Columns))
+	for _, item := range items {
+		for i, column := range writer.args.Columns {
+			record[i] = formatValue(item[column.Key])
+		}
+		writer.csv.Write(record)
+	}
+	writer.csv.Flush()
+	if err := writer.csv.Error(); err != nil {
+		return genError(fmt.Sprintf("couldn't write file: %s", err))
+	}
+	return nil
+}
+
+func (writer *csvWriter) sync() error {
+	if writer.args.Batch.Sync != SYNC_POLICY_BATCH {
+		return nil
+	}
+	return writer.file.Sync()
+}
+
+func (writer *csvWriter) close() error {
+	if writer.args.Batch.Sync != SYNC_POLICY_NONE {
+		if err := writer.file.Sync(); err != nil {
+			writer.file.Close()
+			return genError(fmt.Sprintf("couldn't sync file: %s", err))
+		}
+	}
+	return writer.file.Close()
+}
+
+// formatValue 用于把条目中的值格式化为字符串。
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	case map[string]interface{}, module.Item, []interface{}, []string:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,60 @@
+package sinks
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestCSVSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.csv")
+	args := CSVArgs{
+		Path: path,
+		Columns: []Column{
+			{Name: "title", Key: "name"},
+			{Key: "price"},
+			{Key: "tags"},
+		},
+	}
+	items := []module.Item{
+		{"name": "Blue, Widget", "price": 9.99, "tags": []string{"a", "b"}},
+		{"name": "Red Widget"},
+	}
+	for i := 0; i < 2; i++ {
+		sink, err := NewCSVSink(args)
+		if err != nil {
+			t.Fatalf("An error occurs when creating CSV sink: %s", err)
+		}
+		if _, err := sink.Process(items[i]); err != nil {
+			t.Fatalf("An error occurs when processing item: %s", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("An error occurs when closing sink: %s", err)
+		}
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("An error occurs when reading file: %s", err)
+	}
+	expectedContent := "title,price,tags\n" +
+		"\"Blue, Widget\",9.99,\"[\"\"a\"\",\"\"b\"\"]\"\n" +
+		"Red Widget,,\n"
+	if string(content) != expectedContent {
+		t.Fatalf("Inconsistent content: expected:\n%s\nactual:\n%s",
+			expectedContent, content)
+	}
+	illegalArgsList := []CSVArgs{
+		{Columns: args.Columns},
+		{Path: path},
+		{Path: path, Columns: []Column{{Name: "a"}}},
+		{Path: path, Columns: []Column{{Key: "a"}, {Name: "a", Key: "b"}}},
+	}
+	for _, args := range illegalArgsList {
+		if _, err := NewCSVSink(args); err == nil {
+			t.Fatalf("No error when creating CSV sink with illegal args! (args: %#v)",
+				args)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package sinks
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// JSONLinesArgs 代表JSON Lines输出端的参数容器的类型。
+type JSONLinesArgs struct {
+	// Dir 代表存放文件的目录。
+	Dir string
+	// Prefix 代表文件名的前缀。若为空，则使用“items”。
+	// 文件名的形式为“<前缀>-<序号>.jsonl”，序号从1开始。
+	Prefix string
+	// MaxBytes 代表单个文件的最大字节数。
+	// 写入的数据会使文件超出此大小时，会轮转到下一个文件。若为0，则不轮转。
+	MaxBytes int64
+	// Batch 代表批量写出相关的参数。
+	Batch BatchArgs
+}
+
+// NewJSONLinesSink 用于创建一个把条目写入可轮转的JSON Lines文件的输出端。
+// 每个条目都会被编码为一行JSON。已存在的文件不会被覆盖，
+// 新的条目总会被写入序号最大的文件之后的新文件中。
+func NewJSONLinesSink(args JSONLinesArgs) (Sink, error) {
+	if args.Dir == "" {
+		return nil, genParameterError("empty directory")
+	}
+	if args.MaxBytes < 0 {
+		return nil, genParameterError("negative max bytes")
+	}
+	if err := args.Batch.Check(); err != nil {
+		return nil, err
+	}
+	if args.Prefix == "" {
+		args.Prefix = "items"
+	}
+	if err := os.MkdirAll(args.Dir, 0755); err != nil {
+		return nil, genError(fmt.Sprintf("couldn't create directory: %s", err))
+	}
+	index, err := lastJSONLinesIndex(args.Dir, args.Prefix)
+	if err != nil {
+		return nil, genError(fmt.Sprintf("couldn't read directory: %s", err))
+	}
+	writer := &jsonLinesWriter{args: args, index: index}
+	if err := writer.rotate(); err != nil {
+		return nil, err
+	}
+	return newBatchSink(args.Batch, writer), nil
+}
+
+// lastJSONLinesIndex 用于获取目录中已存在的文件的最大序号。
+func lastJSONLinesIndex(dir string, prefix string) (int, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var last int
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasPrefix(name, prefix+"-") || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		indexStr := strings.TrimSuffix(strings.TrimPrefix(name, prefix+"-"), ".jsonl")
+		if index, err := strconv.Atoi(indexStr); err == nil && index > last {
+			last = index
+		}
+	}
+	return last, nil
+}
+
+// jsonLinesWriter 代表JSON Lines文件的写出器。
+type jsonLinesWriter struct {
+	// args 代表参数。
+	args JSONLinesArgs
+	// index 代表当前文件的序号。
+	index int
+	// file 代表当前文件。
+	file *os.File
+	// buf 代表当前文件的写缓冲。
+	buf *bufio.Writer
+	// size 代表当前文件的大小。
+	size int64
+}
+
+// path 用于获取给定序号的文件的路径。
+func (writer *jsonLinesWriter) path(index int) string {
+	name := fmt.Sprintf("%s-%06d.jsonl", writer.args.Prefix, index)
+	return filepath.Join(writer.args.Dir, name)
+}
+
+// rotate 用于关闭当前文件并创建下一个文件。
+func (writer *jsonLinesWriter) rotate() error {
+	if writer.file != nil {
+		if err := writer.close(); err != nil {
+			return err
+		}
+	}
+	writer.index++
+	path := writer.path(writer.index)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return genError(fmt.Sprintf("couldn't create file: %s", err))
+	}
+	logger.Infof("Write items to %s...", path)
+	writer.file = file
+	writer.buf = bufio.NewWriter(file)
+	writer.size = 0
+	return nil
+}
+
+func (writer *jsonLinesWriter) write(items []module.Item) error {
+	var errs []string
+	for _, item := range items {
+		line, err := json.Marshal(item)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		line = append(line, '\n')
+		lineSize := int64(len(line))
+		if max := writer.args.MaxBytes; max > 0 && writer.size > 0 &&
+			writer.size+lineSize > max {
+			if err := writer.rotate(); err != nil {
+				return err
+			}
+		}
+		if _, err := writer.buf.Write(line); err != nil {
+			return genError(fmt.Sprintf("couldn't write file: %s", err))
+		}
+		writer.size += lineSize
+	}
+	if err := writer.buf.Flush(); err != nil {
+		return genError(fmt.Sprintf("couldn't write file: %s", err))
+	}
+	if len(errs) > 0 {
+		errMsg := fmt.Sprintf("couldn't encode %d item(s): %s",
+			len(errs), strings.Join(errs, "; "))
+		return &skippedItemsError{genError(errMsg)}
+	}
+	return nil
+}
+
+func (writer *jsonLinesWriter) sync() error {
+	if writer.args.Batch.Sync != SYNC_POLICY_BATCH {
+		return nil
+	}
+	return writer.file.Sync()
+}
+
+func (writer *jsonLinesWriter) close() error {
+	if err := writer.buf.Flush(); err != nil {
+		writer.file.Close()
+		return genError(fmt.Sprintf("couldn't write file: %s", err))
+	}
+	if writer.args.Batch.Sync != SYNC_POLICY_NONE {
+		if err := writer.file.Sync(); err != nil {
+			writer.file.Close()
+			return genError(fmt.Sprintf("couldn't sync file: %s", err))
+		}
+	}
+	return writer.file.Close()
+}
@@ -0,0 +1,98 @@
+package sinks
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestJSONLinesSink(t *testing.T) {
+	dir := t.TempDir()
+	// 每行为{"index":N}，长度为12个字节（包括换行符）。
+	args := JSONLinesArgs{
+		Dir:      dir,
+		Prefix:   "products",
+		MaxBytes: 30,
+		Batch:    BatchArgs{Size: 2, Sync: SYNC_POLICY_BATCH},
+	}
+	sink, err := NewJSONLinesSink(args)
+	if err != nil {
+		t.Fatalf("An error occurs when creating JSON Lines sink: %s", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := sink.Process(module.Item{"index": i}); err != nil {
+			t.Fatalf("An error occurs when processing item: %s", err)
+		}
+	}
+	if _, err := sink.Process(module.Item{"ch": make(chan int)}); err == nil {
+		t.Fatal("No error when writing an unencodable item!")
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("An error occurs when closing sink: %s", err)
+	}
+	expectedFiles := map[string][]int{
+		"products-000001.jsonl": {0, 1},
+		"products-000002.jsonl": {2, 3},
+		"products-000003.jsonl": {4},
+	}
+	checkFiles := func(expectedFiles map[string][]int) {
+		matches, _ := filepath.Glob(filepath.Join(dir, "products-*.jsonl"))
+		if len(matches) != len(expectedFiles) {
+			t.Fatalf("Inconsistent file number: expected: %d, actual: %d",
+				len(expectedFiles), len(matches))
+		}
+		for name, expectedIndexes := range expectedFiles {
+			file, err := os.Open(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("An error occurs when opening file: %s", err)
+			}
+			var indexes []int
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				var item struct{ Index int }
+				if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+					t.Fatalf("An error occurs when decoding line: %s (file: %s)", err, name)
+				}
+				indexes = append(indexes, item.Index)
+			}
+			file.Close()
+			if len(indexes) != len(expectedIndexes) {
+				t.Fatalf("Inconsistent indexes: expected: %v, actual: %v (file: %s)",
+					expectedIndexes, indexes, name)
+			}
+			for i, index := range indexes {
+				if index != expectedIndexes[i] {
+					t.Fatalf("Inconsistent indexes: expected: %v, actual: %v (file: %s)",
+						expectedIndexes, indexes, name)
+				}
+			}
+		}
+	}
+	checkFiles(expectedFiles)
+	// 已存在的文件不会被覆盖。
+	sink, err = NewJSONLinesSink(args)
+	if err != nil {
+		t.Fatalf("An error occurs when creating JSON Lines sink: %s", err)
+	}
+	sink.Process(module.Item{"index": 5})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("An error occurs when closing sink: %s", err)
+	}
+	expectedFiles["products-000004.jsonl"] = []int{5}
+	checkFiles(expectedFiles)
+	illegalArgsList := []JSONLinesArgs{
+		{},
+		{Dir: dir, MaxBytes: -1},
+		{Dir: dir, Batch: BatchArgs{FlushInterval: -1}},
+	}
+	for _, args := range illegalArgsList {
+		if _, err := NewJSONLinesSink(args); err == nil {
+			t.Fatalf("No error when creating JSON Lines sink with illegal args! (args: %#v)",
+				args)
+		}
+	}
+}
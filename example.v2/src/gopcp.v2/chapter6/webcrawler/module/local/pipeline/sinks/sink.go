@@ -0,0 +1,192 @@
+// Package sinks 提供了一些现成的条目输出端，
+// 它们可以把条目写入JSON Lines文件、CSV文件或数据库表。
+package sinks
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/helper/log"
+)
+
+// logger 代表日志记录器。
+var logger = log.DLogger()
+
+// Sink 代表条目输出端的接口类型。
+// 其实现类型都是并发安全的。
+type Sink interface {
+	// Process 用于输出条目。它会原样返回条目，因此可以被用作条目处理函数。
+	// 条目会先被放入缓冲，然后再被批量写出。
+	Process(item module.Item) (result module.Item, err error)
+	// Flush 用于立即写出缓冲中的条目。
+	Flush() error
+	// Close 用于写出缓冲中的条目并关闭输出端。
+	// 关闭之后，Process方法和Flush方法都会返回错误。
+	Close() error
+}
+
+// SyncPolicy 代表文件同步策略的类型，即何时把写出的数据同步到磁盘上。
+type SyncPolicy uint8
+
+// 文件同步策略的常量。
+const (
+	// SYNC_POLICY_CLOSE 代表只在文件被关闭（包括轮转）时同步。
+	SYNC_POLICY_CLOSE SyncPolicy = iota
+	// SYNC_POLICY_BATCH 代表在每次批量写出之后都同步。
+	SYNC_POLICY_BATCH
+	// SYNC_POLICY_NONE 代表从不主动同步，由操作系统决定。
+	SYNC_POLICY_NONE
+)
+
+// BatchArgs 代表批量写出相关的参数容器的类型。
+type BatchArgs struct {
+	// Size 代表批量写出的条目数量。
+	// 缓冲中的条目达到此数量时会被写出。若为0，则每个条目都会被立即写出。
+	Size uint32
+	// FlushInterval 代表定期写出缓冲中的条目的时间间隔。
+	// 若为0，则不会定期写出。
+	FlushInterval time.Duration
+	// Sync 代表文件同步策略。对数据库输出端无效。
+	Sync SyncPolicy
+}
+
+// Check 用于自检参数的有效性。
+func (args *BatchArgs) Check() error {
+	if args.FlushInterval < 0 {
+		return genParameterError("negative flush interval")
+	}
+	if args.Sync > SYNC_POLICY_NONE {
+		return genParameterError(fmt.Sprintf("illegal sync policy: %d", args.Sync))
+	}
+	return nil
+}
+
+// batchWriter 代表实际写出条目的接口类型。
+type batchWriter interface {
+	// write 用于写出一批条目。
+	// 若返回的错误不是*skippedItemsError类型的值，则这批条目会被重试。
+	write(items []module.Item) error
+	// sync 用于在批量写出之后按照同步策略进行同步。
+	sync() error
+	// close 用于关闭底层的文件或数据库。
+	close() error
+}
+
+// skippedItemsError 代表部分条目因无法被写出而被跳过的错误类型。
+// 其余的条目都已被写出，因此这批条目不会被重试。
+type skippedItemsError struct {
+	err error
+}
+
+func (e *skippedItemsError) Error() string {
+	return e.err.Error()
+}
+
+// batchSink 代表基于批量写出的输出端的实现类型。
+type batchSink struct {
+	// args 代表批量写出相关的参数。
+	args BatchArgs
+	// writer 代表实际写出条目的写出器。
+	writer batchWriter
+	// items 代表缓冲中的条目。
+	items []module.Item
+	// closed 代表是否已被关闭。
+	closed bool
+	// done 会在关闭时被关闭，以停止定期写出。
+	done chan struct{}
+	lock sync.Mutex
+}
+
+// newBatchSink 用于创建一个基于批量写出的输出端。
+func newBatchSink(args BatchArgs, writer batchWriter) *batchSink {
+	sink := &batchSink{
+		args:   args,
+		writer: writer,
+		done:   make(chan struct{}),
+	}
+	if args.FlushInterval > 0 {
+		go sink.flushPeriodically()
+	}
+	return sink
+}
+
+func (sink *batchSink) Process(item module.Item) (module.Item, error) {
+	if item == nil {
+		return nil, genParameterError("nil item")
+	}
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if sink.closed {
+		return nil, genError("closed sink")
+	}
+	sink.items = append(sink.items, item)
+	if uint32(len(sink.items)) < sink.args.Size {
+		return item, nil
+	}
+	return item, sink.flush()
+}
+
+func (sink *batchSink) Flush() error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if sink.closed {
+		return genError("closed sink")
+	}
+	return sink.flush()
+}
+
+// flush 用于写出缓冲中的条目。调用方需持有锁。
+// 条目只有在被写出并同步之后才会被移出缓冲。
+// 否则它们会留在缓冲中，并在下一次写出或关闭时被重试，
+// 因此文件输出端可能会重复写出其中已被写出的部分。
+func (sink *batchSink) flush() error {
+	if len(sink.items) == 0 {
+		return nil
+	}
+	err := sink.writer.write(sink.items)
+	if _, skipped := err.(*skippedItemsError); err != nil && !skipped {
+		return err
+	}
+	if err := sink.writer.sync(); err != nil {
+		return err
+	}
+	sink.items = nil
+	return err
+}
+
+func (sink *batchSink) Close() error {
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if sink.closed {
+		return nil
+	}
+	sink.closed = true
+	close(sink.done)
+	err := sink.flush()
+	if closeErr := sink.writer.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// flushPeriodically 用于定期写出缓冲中的条目，直到输出端被关闭。
+func (sink *batchSink) flushPeriodically() {
+	ticker := time.NewTicker(sink.args.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sink.done:
+			return
+		case <-ticker.C:
+		}
+		sink.lock.Lock()
+		if !sink.closed {
+			if err := sink.flush(); err != nil {
+				logger.Errorf("An error occurs when flushing items: %s", err)
+			}
+		}
+		sink.lock.Unlock()
+	}
+}
@@ -0,0 +1,157 @@
+package sinks
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// testingWriter 代表测试专用的写出器。
+type testingWriter struct {
+	// failures 代表接下来需要失败的写出次数。
+	failures int
+	batches  [][]module.Item
+	syncs    int
+	closed   int
+	lock     sync.Mutex
+}
+
+func (writer *testingWriter) write(items []module.Item) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	if writer.failures > 0 {
+		writer.failures--
+		return errors.New("testing write failure")
+	}
+	writer.batches = append(writer.batches, items)
+	return nil
+}
+
+func (writer *testingWriter) sync() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	writer.syncs++
+	return nil
+}
+
+func (writer *testingWriter) close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	writer.closed++
+	return nil
+}
+
+func (writer *testingWriter) batchSizes() []int {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	var sizes []int
+	for _, batch := range writer.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func TestBatchArgs(t *testing.T) {
+	legalArgsList := []BatchArgs{
+		{},
+		{Size: 100, FlushInterval: time.Second, Sync: SYNC_POLICY_NONE},
+	}
+	for _, args := range legalArgsList {
+		if err := args.Check(); err != nil {
+			t.Fatalf("An error occurs when checking batch arguments: %s (args: %#v)",
+				err, args)
+		}
+	}
+	illegalArgsList := []BatchArgs{
+		{FlushInterval: -1},
+		{Sync: SYNC_POLICY_NONE + 1},
+	}
+	for _, args := range illegalArgsList {
+		if err := args.Check(); err == nil {
+			t.Fatalf("No error when checking illegal batch arguments! (args: %#v)",
+				args)
+		}
+	}
+}
+
+func TestBatchSink(t *testing.T) {
+	writer := &testingWriter{}
+	sink := newBatchSink(BatchArgs{Size: 3}, writer)
+	if _, err := sink.Process(nil); err == nil {
+		t.Fatal("No error when processing nil item!")
+	}
+	for i := 0; i < 7; i++ {
+		item := module.Item{"index": i}
+		result, err := sink.Process(item)
+		if err != nil {
+			t.Fatalf("An error occurs when processing item: %s", err)
+		}
+		if result["index"] != i {
+			t.Fatalf("Inconsistent result item: %#v", result)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("An error occurs when flushing items: %s", err)
+	}
+	sink.Process(module.Item{"index": 7})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("An error occurs when closing sink: %s", err)
+	}
+	sink.Close()
+	expectedSizes := []int{3, 3, 1, 1}
+	sizes := writer.batchSizes()
+	if len(sizes) != len(expectedSizes) {
+		t.Fatalf("Inconsistent batch sizes: expected: %v, actual: %v",
+			expectedSizes, sizes)
+	}
+	for i, size := range sizes {
+		if size != expectedSizes[i] {
+			t.Fatalf("Inconsistent batch sizes: expected: %v, actual: %v",
+				expectedSizes, sizes)
+		}
+	}
+	if writer.syncs != len(expectedSizes) || writer.closed != 1 {
+		t.Fatalf("Inconsistent sync or close count: syncs: %d, closed: %d",
+			writer.syncs, writer.closed)
+	}
+	if _, err := sink.Process(module.Item{}); err == nil {
+		t.Fatal("No error when processing item with closed sink!")
+	}
+	if err := sink.Flush(); err == nil {
+		t.Fatal("No error when flushing closed sink!")
+	}
+	// 写出失败的条目会在下一次写出时被重试。
+	writer = &testingWriter{failures: 2}
+	sink = newBatchSink(BatchArgs{Size: 2}, writer)
+	sink.Process(module.Item{"index": 0})
+	if _, err := sink.Process(module.Item{"index": 1}); err == nil {
+		t.Fatal("No error when writing items fails!")
+	}
+	if err := sink.Flush(); err == nil {
+		t.Fatal("No error when writing items fails!")
+	}
+	sink.Process(module.Item{"index": 2})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("An error occurs when closing sink: %s", err)
+	}
+	sizes = writer.batchSizes()
+	if len(sizes) != 1 || sizes[0] != 3 {
+		t.Fatalf("Inconsistent batch sizes after write failures: expected: %v, actual: %v",
+			[]int{3}, sizes)
+	}
+	// 定期写出。
+	writer = &testingWriter{}
+	sink = newBatchSink(BatchArgs{Size: 100, FlushInterval: 10 * time.Millisecond}, writer)
+	defer sink.Close()
+	sink.Process(module.Item{"index": 0})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(writer.batchSizes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("The items have not been flushed periodically!")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,203 @@
+package sinks
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// identifierPattern 代表合法的表名和列名的正则表达式。
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PlaceholderStyle 代表SQL语句中的参数占位符的风格的类型。
+type PlaceholderStyle uint8
+
+// 参数占位符风格的常量。
+const (
+	// PLACEHOLDER_STYLE_QUESTION 代表“?”风格，适用于SQLite、MySQL等。
+	PLACEHOLDER_STYLE_QUESTION PlaceholderStyle = iota
+	// PLACEHOLDER_STYLE_DOLLAR 代表“$1”、“$2”风格，适用于PostgreSQL等。
+	PLACEHOLDER_STYLE_DOLLAR
+)
+
+// placeholder 用于生成第i个（从1开始）参数的占位符。
+func (style PlaceholderStyle) placeholder(i int) string {
+	if style == PLACEHOLDER_STYLE_DOLLAR {
+		return "$" + strconv.Itoa(i)
+	}
+	return "?"
+}
+
+// SQLArgs 代表数据库输出端的参数容器的类型。
+type SQLArgs struct {
+	// DriverName 代表数据库驱动的名称，不能为空。
+	// 本包不包含任何数据库驱动（包括SQLite驱动），相应的驱动包需要由调用方导入。
+	// 例如，导入纯Go实现的SQLite驱动modernc.org/sqlite之后，其名称为“sqlite”。
+	DriverName string
+	// DataSourceName 代表数据源的名称，对于SQLite即为数据库文件的路径。
+	DataSourceName string
+	// Table 代表表名。若表不存在，则会被创建。
+	Table string
+	// Columns 代表列的列表。
+	Columns []Column
+	// Placeholder 代表驱动所使用的参数占位符的风格，默认为“?”风格。
+	Placeholder PlaceholderStyle
+	// Batch 代表批量写出相关的参数。其中的Sync对数据库输出端无效。
+	Batch BatchArgs
+}
+
+// NewSQLSink 用于创建一个把条目写入数据库表的输出端。
+// 参数args中的DriverName代表的驱动必须已被注册。
+// 每批条目都会在一个事务中被插入。条目中缺少的值会被写为NULL，
+// 驱动不支持的值会被格式化为字符串。
+func NewSQLSink(args SQLArgs) (Sink, error) {
+	if args.DriverName == "" {
+		return nil, genParameterError("empty driver name")
+	}
+	if !driverRegistered(args.DriverName) {
+		errMsg := fmt.Sprintf("unregistered driver %q (its package must be imported)",
+			args.DriverName)
+		return nil, genParameterError(errMsg)
+	}
+	if args.DataSourceName == "" {
+		return nil, genParameterError("empty data source name")
+	}
+	if args.Placeholder > PLACEHOLDER_STYLE_DOLLAR {
+		errMsg := fmt.Sprintf("illegal placeholder style: %d", args.Placeholder)
+		return nil, genParameterError(errMsg)
+	}
+	if !identifierPattern.MatchString(args.Table) {
+		return nil, genParameterError(fmt.Sprintf("illegal table name %q", args.Table))
+	}
+	if err := checkColumns(args.Columns); err != nil {
+		return nil, err
+	}
+	for _, column := range args.Columns {
+		if !identifierPattern.MatchString(column.name()) {
+			errMsg := fmt.Sprintf("illegal column name %q", column.name())
+			return nil, genParameterError(errMsg)
+		}
+		if strings.ContainsAny(column.Type, ";,()") {
+			errMsg := fmt.Sprintf("illegal column type %q", column.Type)
+			return nil, genParameterError(errMsg)
+		}
+	}
+	if err := args.Batch.Check(); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(args.DriverName, args.DataSourceName)
+	if err != nil {
+		return nil, genError(fmt.Sprintf("couldn't open database: %s", err))
+	}
+	writer := &sqlWriter{args: args, db: db}
+	if err := writer.createTable(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return newBatchSink(args.Batch, writer), nil
+}
+
+// driverRegistered 用于判断给定名称的数据库驱动是否已被注册。
+func driverRegistered(name string) bool {
+	for _, registered := range sql.Drivers() {
+		if registered == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlWriter 代表数据库表的写出器。
+type sqlWriter struct {
+	// args 代表参数。
+	args SQLArgs
+	// db 代表数据库。
+	db *sql.DB
+}
+
+// createTable 用于在表不存在时创建表。
+func (writer *sqlWriter) createTable() error {
+	defs := make([]string, len(writer.args.Columns))
+	for i, column := range writer.args.Columns {
+		columnType := column.Type
+		if columnType == "" {
+			columnType = "TEXT"
+		}
+		defs[i] = column.name() + " " + columnType
+	}
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)",
+		writer.args.Table, strings.Join(defs, ", "))
+	if _, err := writer.db.Exec(stmt); err != nil {
+		return genError(fmt.Sprintf("couldn't create table: %s", err))
+	}
+	return nil
+}
+
+func (writer *sqlWriter) write(items []module.Item) (err error) {
+	names := make([]string, len(writer.args.Columns))
+	placeholders := make([]string, len(writer.args.Columns))
+	for i, column := range writer.args.Columns {
+		names[i] = column.name()
+		placeholders[i] = writer.args.Placeholder.placeholder(i + 1)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", writer.args.Table,
+		strings.Join(names, ", "), strings.Join(placeholders, ", "))
+	tx, err := writer.db.Begin()
+	if err != nil {
+		return genError(fmt.Sprintf("couldn't begin transaction: %s", err))
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return genError(fmt.Sprintf("couldn't prepare statement: %s", err))
+	}
+	defer stmt.Close()
+	values := make([]interface{}, len(writer.args.Columns))
+	for _, item := range items {
+		for i, column := range writer.args.Columns {
+			values[i] = sqlValue(item[column.Key])
+		}
+		if _, err = stmt.Exec(values...); err != nil {
+			return genError(fmt.Sprintf("couldn't insert item: %s", err))
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return genError(fmt.Sprintf("couldn't commit transaction: %s", err))
+	}
+	return nil
+}
+
+func (writer *sqlWriter) sync() error {
+	return nil
+}
+
+func (writer *sqlWriter) close() error {
+	return writer.db.Close()
+}
+
+// sqlValue 用于把条目中的值转换为数据库驱动支持的值。
+func sqlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	}
+	if driver.IsValue(value) {
+		return value
+	}
+	return formatValue(value)
+}
@@ -0,0 +1,201 @@
+package sinks
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// testingDriverName 代表测试专用的数据库驱动的名称。
+const testingDriverName = "sinks-testing"
+
+// testingDB 代表测试专用的数据库，它会记录执行过的语句。
+type testingDB struct {
+	// execs 代表已提交的语句及其参数的列表。
+	execs []string
+	// commits 代表提交事务的次数。
+	commits int
+	lock    sync.Mutex
+}
+
+// testingDBs 代表数据源名称与测试专用数据库的映射。
+var testingDBs = map[string]*testingDB{}
+
+var testingDBsLock sync.Mutex
+
+func init() {
+	sql.Register(testingDriverName, testingDriver{})
+}
+
+// testingDriver 代表测试专用的数据库驱动。
+type testingDriver struct{}
+
+func (testingDriver) Open(name string) (driver.Conn, error) {
+	testingDBsLock.Lock()
+	defer testingDBsLock.Unlock()
+	db, ok := testingDBs[name]
+	if !ok {
+		db = &testingDB{}
+		testingDBs[name] = db
+	}
+	return &testingConn{db: db}, nil
+}
+
+// testingConn 代表测试专用的数据库连接。
+type testingConn struct {
+	db *testingDB
+	// pending 代表当前事务中尚未提交的语句。
+	pending []string
+	inTx    bool
+}
+
+func (conn *testingConn) Prepare(query string) (driver.Stmt, error) {
+	return &testingStmt{conn: conn, query: query}, nil
+}
+
+func (conn *testingConn) Close() error { return nil }
+
+func (conn *testingConn) Begin() (driver.Tx, error) {
+	conn.inTx = true
+	return conn, nil
+}
+
+func (conn *testingConn) Commit() error {
+	conn.db.lock.Lock()
+	defer conn.db.lock.Unlock()
+	conn.db.execs = append(conn.db.execs, conn.pending...)
+	conn.db.commits++
+	conn.pending = nil
+	conn.inTx = false
+	return nil
+}
+
+func (conn *testingConn) Rollback() error {
+	conn.pending = nil
+	conn.inTx = false
+	return nil
+}
+
+// testingStmt 代表测试专用的预编译语句。
+type testingStmt struct {
+	conn  *testingConn
+	query string
+}
+
+func (stmt *testingStmt) Close() error { return nil }
+
+func (stmt *testingStmt) NumInput() int { return -1 }
+
+func (stmt *testingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	record := stmt.query
+	if len(args) > 0 {
+		record += fmt.Sprintf(" %v", args)
+	}
+	if stmt.conn.inTx {
+		stmt.conn.pending = append(stmt.conn.pending, record)
+	} else {
+		stmt.conn.db.lock.Lock()
+		stmt.conn.db.execs = append(stmt.conn.db.execs, record)
+		stmt.conn.db.lock.Unlock()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (stmt *testingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, io.EOF
+}
+
+func TestSQLSink(t *testing.T) {
+	args := SQLArgs{
+		DriverName:     testingDriverName,
+		DataSourceName: "items.db",
+		Table:          "products",
+		Columns: []Column{
+			{Key: "name"},
+			{Name: "price", Key: "price", Type: "REAL"},
+			{Name: "tags", Key: "tags"},
+		},
+		Batch: BatchArgs{Size: 2},
+	}
+	sink, err := NewSQLSink(args)
+	if err != nil {
+		t.Fatalf("An error occurs when creating SQL sink: %s", err)
+	}
+	items := []module.Item{
+		{"name": "Blue Widget", "price": 9.99, "tags": []string{"a"}},
+		{"name": "Red Widget", "price": 10},
+		{"name": "Green Widget"},
+	}
+	for _, item := range items {
+		if _, err := sink.Process(item); err != nil {
+			t.Fatalf("An error occurs when processing item: %s", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("An error occurs when closing sink: %s", err)
+	}
+	db := testingDBs[args.DataSourceName]
+	insert := "INSERT INTO products (name, price, tags) VALUES (?, ?, ?)"
+	expectedExecs := []string{
+		"CREATE TABLE IF NOT EXISTS products (name TEXT, price REAL, tags TEXT)",
+		insert + ` [Blue Widget 9.99 ["a"]]`,
+		insert + " [Red Widget 10 <nil>]",
+		insert + " [Green Widget <nil> <nil>]",
+	}
+	if len(db.execs) != len(expectedExecs) {
+		t.Fatalf("Inconsistent statement number: expected: %d, actual: %d (statements: %q)",
+			len(expectedExecs), len(db.execs), db.execs)
+	}
+	for i, exec := range db.execs {
+		if exec != expectedExecs[i] {
+			t.Fatalf("Inconsistent statement[%d]: expected: %q, actual: %q",
+				i, expectedExecs[i], exec)
+		}
+	}
+	if db.commits != 2 {
+		t.Fatalf("Inconsistent commit number: expected: %d, actual: %d",
+			2, db.commits)
+	}
+	// “$1”风格的参数占位符。
+	args.DataSourceName = "items-dollar.db"
+	args.Placeholder = PLACEHOLDER_STYLE_DOLLAR
+	sink, err = NewSQLSink(args)
+	if err != nil {
+		t.Fatalf("An error occurs when creating SQL sink: %s", err)
+	}
+	sink.Process(items[2])
+	if err := sink.Close(); err != nil {
+		t.Fatalf("An error occurs when closing sink: %s", err)
+	}
+	db = testingDBs[args.DataSourceName]
+	expectedExec := "INSERT INTO products (name, price, tags) VALUES ($1, $2, $3)" +
+		" [Green Widget <nil> <nil>]"
+	if len(db.execs) != 2 || db.execs[1] != expectedExec {
+		t.Fatalf("Inconsistent statements: expected: %q, actual: %q",
+			expectedExec, db.execs)
+	}
+	name := testingDriverName
+	illegalArgsList := []SQLArgs{
+		{DriverName: name, Table: "t", Columns: args.Columns},
+		{DataSourceName: "x", Table: "t", Columns: args.Columns},
+		{DriverName: name, DataSourceName: "x", Table: "t;", Columns: args.Columns},
+		{DriverName: name, DataSourceName: "x", Table: "t"},
+		{DriverName: name, DataSourceName: "x", Table: "t", Columns: []Column{{Key: "a b"}}},
+		{DriverName: name, DataSourceName: "x", Table: "t",
+			Columns: []Column{{Key: "a", Type: "TEXT)"}}},
+		{DriverName: "unknown", DataSourceName: "x", Table: "t", Columns: args.Columns},
+		{DriverName: name, DataSourceName: "x", Table: "t", Columns: args.Columns,
+			Placeholder: PLACEHOLDER_STYLE_DOLLAR + 1},
+	}
+	for _, args := range illegalArgsList {
+		if _, err := NewSQLSink(args); err == nil {
+			t.Fatalf("No error when creating SQL sink with illegal args! (args: %#v)",
+				args)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package module
+
+import (
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/errors"
+)
+
+// LatencyStruct 代表组件处理耗时的分布的类型。
+// 其中的各个分位数都是近似值。
+type LatencyStruct struct {
+	// P50 代表耗时的中位数。
+	P50 time.Duration `json:"p50"`
+	// P90 代表耗时的90分位数。
+	P90 time.Duration `json:"p90"`
+	// P99 代表耗时的99分位数。
+	P99 time.Duration `json:"p99"`
+	// Max 代表最大的耗时。
+	Max time.Duration `json:"max"`
+}
+
+// ErrorCounts 代表按错误类型划分的错误计数的类型。
+type ErrorCounts struct {
+	// Downloader 代表下载器错误的计数。
+	Downloader uint64 `json:"downloader"`
+	// Analyzer 代表分析器错误的计数。
+	Analyzer uint64 `json:"analyzer"`
+	// Pipeline 代表条目处理管道错误的计数。
+	Pipeline uint64 `json:"pipeline"`
+	// Scheduler 代表调度器错误的计数。
+	Scheduler uint64 `json:"scheduler"`
+}
+
+// Get 用于获取给定错误类型的计数。
+func (counts ErrorCounts) Get(errorType errors.ErrorType) uint64 {
+	switch errorType {
+	case errors.ERROR_TYPE_DOWNLOADER:
+		return counts.Downloader
+	case errors.ERROR_TYPE_ANALYZER:
+		return counts.Analyzer
+	case errors.ERROR_TYPE_PIPELINE:
+		return counts.Pipeline
+	case errors.ERROR_TYPE_SCHEDULER:
+		return counts.Scheduler
+	}
+	return 0
+}
+
+// Total 用于获取所有错误的计数之和。
+func (counts ErrorCounts) Total() uint64 {
+	return counts.Downloader + counts.Analyzer +
+		counts.Pipeline + counts.Scheduler
+}
@@ -2,7 +2,9 @@ package module
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"gopcp.v2/chapter6/webcrawler/errors"
 )
@@ -16,6 +18,19 @@ type Registrar interface {
 	// Get 用于获取一个指定类型的组件的实例。
 	// 本函数应该基于负载均衡策略返回实例。
 	Get(moduleType Type) (Module, error)
+	// SetSelector 用于设置指定类型的组件使用的选择器，即负载均衡策略。
+	// 若参数selector为nil，则使用默认的基于评分的选择器。
+	SetSelector(moduleType Type, selector Selector) error
+	// SetHealthPolicy 用于设置组件健康状况的判定和熔断策略。
+	// 所有组件已有的健康状况统计都会被重置。
+	SetHealthPolicy(policy HealthPolicy) error
+	// Report 用于报告对组件的一次调用的结果。
+	// 参数err代表调用时发生的错误，为nil时代表调用成功。
+	// 参数latency代表调用的耗时。
+	Report(mid MID, err error, latency time.Duration)
+	// Health 用于获取组件的健康状况。
+	// 若组件未被注册，则第二个结果值为false。
+	Health(mid MID) (HealthStruct, bool)
 	// GetAllByType 用于获取指定类型的所有组件实例。
 	GetAllByType(moduleType Type) (map[MID]Module, error)
 	// GetAll 用于获取所有组件实例。
@@ -28,6 +43,10 @@ type Registrar interface {
 func NewRegistrar() Registrar {
 	return &myRegistrar{
 		moduleTypeMap: map[Type]map[MID]Module{},
+		moduleListMap: map[Type][]Module{},
+		healthListMap: map[Type][]*moduleHealth{},
+		healthMap:     map[MID]*moduleHealth{},
+		selectorMap:   map[Type]Selector{},
 	}
 }
 
@@ -35,6 +54,18 @@ func NewRegistrar() Registrar {
 type myRegistrar struct {
 	// moduleTypeMap 代表组件类型与对应组件实例的映射。
 	moduleTypeMap map[Type]map[MID]Module
+	// moduleListMap 代表组件类型与按ID排序的组件实例列表的映射。
+	// 其中的列表在注册和注销时会被整体替换，因此可以在锁之外安全地使用。
+	moduleListMap map[Type][]Module
+	// healthListMap 代表组件类型与健康状况跟踪器列表的映射。
+	// 其中的列表与moduleListMap中的列表一一对应。
+	healthListMap map[Type][]*moduleHealth
+	// healthMap 代表组件ID与健康状况跟踪器的映射。
+	healthMap map[MID]*moduleHealth
+	// healthPolicy 代表健康策略。
+	healthPolicy HealthPolicy
+	// selectorMap 代表组件类型与对应选择器的映射。
+	selectorMap map[Type]Selector
 	// rwlock 代表组件注册专用读写锁。
 	rwlock sync.RWMutex
 }
@@ -64,6 +95,8 @@ func (registrar *myRegistrar) Register(module Module) (bool, error) {
 	}
 	modules[mid] = module
 	registrar.moduleTypeMap[moduleType] = modules
+	registrar.healthMap[mid] = newModuleHealth(registrar.healthPolicy)
+	registrar.updateModuleList(moduleType)
 	return true, nil
 }
 
@@ -79,30 +112,103 @@ func (registrar *myRegistrar) Unregister(mid MID) (bool, error) {
 	if modules, ok := registrar.moduleTypeMap[moduleType]; ok {
 		if _, ok := modules[mid]; ok {
 			delete(modules, mid)
+			delete(registrar.healthMap, mid)
+			registrar.updateModuleList(moduleType)
 			deleted = true
 		}
 	}
 	return deleted, nil
 }
 
+// updateModuleList 用于重新生成指定类型的组件实例列表。
+// 调用方必须持有写锁。
+func (registrar *myRegistrar) updateModuleList(moduleType Type) {
+	modules := registrar.moduleTypeMap[moduleType]
+	if len(modules) == 0 {
+		delete(registrar.moduleListMap, moduleType)
+		delete(registrar.healthListMap, moduleType)
+		return
+	}
+	list := make([]Module, 0, len(modules))
+	for _, module := range modules {
+		list = append(list, module)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID() < list[j].ID()
+	})
+	healthList := make([]*moduleHealth, len(list))
+	for i, module := range list {
+		healthList[i] = registrar.healthMap[module.ID()]
+	}
+	registrar.moduleListMap[moduleType] = list
+	registrar.healthListMap[moduleType] = healthList
+}
+
 // Get 用于获取一个指定类型的组件的实例。
-// 本函数会基于负载均衡策略返回实例。
+// 本函数会基于为该类型设置的选择器返回实例。
+// 若启用了熔断，则熔断器断开的组件不会被选择。
+// 但若所有组件的熔断器都已断开，则仍会从全部组件中选择，以免爬取流程中断。
 func (registrar *myRegistrar) Get(moduleType Type) (Module, error) {
-	modules, err := registrar.GetAllByType(moduleType)
-	if err != nil {
-		return nil, err
+	if !LegalType(moduleType) {
+		errMsg := fmt.Sprintf("illegal module type: %s", moduleType)
+		return nil, errors.NewIllegalParameterError(errMsg)
 	}
-	minScore := uint64(0)
-	var selectedModule Module
-	for _, module := range modules {
-		SetScore(module)
-		score := module.Score()
-		if minScore == 0 || score < minScore {
-			selectedModule = module
-			minScore = score
+	registrar.rwlock.RLock()
+	modules := registrar.moduleListMap[moduleType]
+	healthList := registrar.healthListMap[moduleType]
+	selector := registrar.selectorMap[moduleType]
+	breakerEnabled := registrar.healthPolicy.Enabled
+	registrar.rwlock.RUnlock()
+	if len(modules) == 0 {
+		return nil, ErrNotFoundModuleInstance
+	}
+	if selector == nil {
+		selector = defaultSelector
+	}
+	if !breakerEnabled {
+		return selector.Select(modules), nil
+	}
+	now := time.Now()
+	candidates := modules
+	var filtered []Module
+	for i, health := range healthList {
+		if health.available(now) {
+			if filtered != nil {
+				filtered = append(filtered, modules[i])
+			}
+		} else if filtered == nil {
+			filtered = append(make([]Module, 0, len(modules)), modules[:i]...)
 		}
 	}
-	return selectedModule, nil
+	if len(filtered) > 0 {
+		candidates = filtered
+	}
+	selected := selector.Select(candidates)
+	for i, module := range modules {
+		if module == selected {
+			healthList[i].acquire(now)
+			break
+		}
+	}
+	return selected, nil
+}
+
+// defaultSelector 代表默认的选择器。
+var defaultSelector = NewScoreSelector()
+
+func (registrar *myRegistrar) SetSelector(moduleType Type, selector Selector) error {
+	if !LegalType(moduleType) {
+		errMsg := fmt.Sprintf("illegal module type: %s", moduleType)
+		return errors.NewIllegalParameterError(errMsg)
+	}
+	registrar.rwlock.Lock()
+	defer registrar.rwlock.Unlock()
+	if selector == nil {
+		delete(registrar.selectorMap, moduleType)
+	} else {
+		registrar.selectorMap[moduleType] = selector
+	}
+	return nil
 }
 
 // GetAllByType 用于获取指定类型的所有组件实例。
@@ -138,8 +244,47 @@ func (registrar *myRegistrar) GetAll() map[MID]Module {
 }
 
 // Clear 会清除所有的组件注册记录。
+// 为各类组件设置的选择器和健康策略会被保留。
 func (registrar *myRegistrar) Clear() {
 	registrar.rwlock.Lock()
 	defer registrar.rwlock.Unlock()
 	registrar.moduleTypeMap = map[Type]map[MID]Module{}
+	registrar.moduleListMap = map[Type][]Module{}
+	registrar.healthListMap = map[Type][]*moduleHealth{}
+	registrar.healthMap = map[MID]*moduleHealth{}
+}
+
+func (registrar *myRegistrar) SetHealthPolicy(policy HealthPolicy) error {
+	if err := policy.Check(); err != nil {
+		return err
+	}
+	registrar.rwlock.Lock()
+	defer registrar.rwlock.Unlock()
+	registrar.healthPolicy = policy
+	for mid := range registrar.healthMap {
+		registrar.healthMap[mid] = newModuleHealth(policy)
+	}
+	for moduleType := range registrar.moduleTypeMap {
+		registrar.updateModuleList(moduleType)
+	}
+	return nil
+}
+
+func (registrar *myRegistrar) Report(mid MID, err error, latency time.Duration) {
+	registrar.rwlock.RLock()
+	health := registrar.healthMap[mid]
+	registrar.rwlock.RUnlock()
+	if health != nil {
+		health.report(err != nil, latency, time.Now())
+	}
+}
+
+func (registrar *myRegistrar) Health(mid MID) (HealthStruct, bool) {
+	registrar.rwlock.RLock()
+	health := registrar.healthMap[mid]
+	registrar.rwlock.RUnlock()
+	if health == nil {
+		return HealthStruct{}, false
+	}
+	return health.summary(), true
 }
@@ -0,0 +1,58 @@
+package remote
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// NewAnalyzer 用于创建一个远程分析器的客户端。
+// 参数mid代表组件ID，其中的网络地址即远程分析器的服务端的地址。
+// 参数opts代表可选项，可以为空。
+func NewAnalyzer(
+	mid module.MID,
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Analyzer, error) {
+	client, err := newClient(module.TYPE_ANALYZER, mid, scoreCalculator, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteAnalyzer{client: client}, nil
+}
+
+// remoteAnalyzer 代表远程分析器的客户端的实现类型。
+type remoteAnalyzer struct {
+	*client
+}
+
+// RespParsers 总会返回nil，因为响应解析函数无法在网络上传输。
+func (analyzer *remoteAnalyzer) RespParsers() []module.ParseResponse {
+	return nil
+}
+
+// Analyze 会把响应发送给远程分析器，并返回其分析得到的请求和条目。
+// 响应体会被读出并关闭。条目中的值会以JSON的形式传输，
+// 因此例如数字都会被还原为float64类型的值。
+func (analyzer *remoteAnalyzer) Analyze(resp *module.Response) ([]module.Data, []error) {
+	wireResp, err := toWireResponse(resp)
+	if err != nil {
+		return nil, []error{genParameterError(analyzer.mtype, err.Error())}
+	}
+	// 响应体已被读出，因此在本地检查重复内容。
+	if check := resp.DuplicateCheck(); check != nil {
+		httpResp := *resp.HTTPResp()
+		httpResp.Body = ioutil.NopCloser(bytes.NewReader(wireResp.Body))
+		check(&httpResp)
+	}
+	var reply AnalyzeReply
+	if err := analyzer.call(serviceAnalyzer+".Analyze", wireResp, &reply); err != nil {
+		return nil, []error{err}
+	}
+	analyzer.syncCounts(reply.Counts)
+	dataList, errs := fromWireDataList(reply.DataList)
+	for i, err := range errs {
+		errs[i] = genErrorByError(analyzer.mtype, err)
+	}
+	return dataList, append(fromWireErrors(reply.Errors), errs...)
+}
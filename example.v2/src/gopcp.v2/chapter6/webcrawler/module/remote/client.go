@@ -0,0 +1,295 @@
+package remote
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/module/stub"
+)
+
+// 远程组件客户端的默认参数。
+const (
+	// defaultTimeout 代表默认的单次调用的超时时间。
+	defaultTimeout = 30 * time.Second
+	// defaultHealthCheckInterval 代表默认的健康检查的间隔时间。
+	defaultHealthCheckInterval = 10 * time.Second
+)
+
+// Option 代表远程组件客户端的可选项的类型。
+type Option func(client *client) error
+
+// WithTimeout 用于生成设置单次调用的超时时间的可选项。
+// 参数timeout包含建立连接的时间，必须大于0。
+func WithTimeout(timeout time.Duration) Option {
+	return func(client *client) error {
+		if timeout <= 0 {
+			return genParameterError(client.mtype,
+				fmt.Sprintf("illegal timeout: %s", timeout))
+		}
+		client.timeout = timeout
+		return nil
+	}
+}
+
+// WithHealthCheckInterval 用于生成设置健康检查的间隔时间的可选项。
+// 客户端会按此间隔在后台检查远程组件的健康状况，并同步其计数。
+// 若参数interval为0，则不进行后台的健康检查。
+func WithHealthCheckInterval(interval time.Duration) Option {
+	return func(client *client) error {
+		if interval < 0 {
+			return genParameterError(client.mtype,
+				fmt.Sprintf("illegal health check interval: %s", interval))
+		}
+		client.healthCheckInterval = interval
+		return nil
+	}
+}
+
+// SummaryExtraStruct 代表远程组件客户端的摘要中的额外信息的类型。
+type SummaryExtraStruct struct {
+	// Addr 代表远程组件的网络地址。
+	Addr string `json:"addr"`
+	// Healthy 代表远程组件是否健康。
+	Healthy bool `json:"healthy"`
+}
+
+// client 代表远程组件客户端的基础类型。
+// 除实时处理数以外，它的各项计数都会与远程组件同步。
+// 实时处理数代表当前客户端正在等待的调用的数量。
+type client struct {
+	// stub.ModuleInternal 代表组件基础实例，用于提供组件ID、网络地址和评分。
+	stub.ModuleInternal
+	// mtype 代表组件的类型。
+	mtype module.Type
+	// timeout 代表单次调用的超时时间。
+	timeout time.Duration
+	// healthCheckInterval 代表健康检查的间隔时间。
+	healthCheckInterval time.Duration
+	// rpcClient 代表RPC客户端，在连接断开之后会被重新创建。
+	rpcClient *rpc.Client
+	// counts 代表从远程组件同步的计数。
+	counts module.Counts
+	// handlingNumber 代表正在等待的调用的数量。
+	handlingNumber uint64
+	// healthy 代表远程组件是否健康。1代表是，0代表否。
+	healthy uint32
+	// closed 代表客户端是否已被关闭。
+	closed bool
+	// closeCh 代表用于停止后台健康检查的通道。
+	closeCh chan struct{}
+	lock    sync.Mutex
+}
+
+// newClient 用于创建远程组件客户端的基础实例。
+// 组件ID中必须包含远程组件的网络地址，且其类型必须与参数mtype一致。
+// 创建时会进行一次健康检查，远程组件不可用时会返回错误。
+func newClient(
+	mtype module.Type,
+	mid module.MID,
+	scoreCalculator module.CalculateScore,
+	opts []Option) (*client, error) {
+	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
+	if err != nil {
+		return nil, genErrorByError(mtype, err)
+	}
+	if _, midType := module.GetType(mid); midType != mtype {
+		return nil, genParameterError(mtype,
+			fmt.Sprintf("incorrect module type of MID %q: %s", mid, midType))
+	}
+	if moduleBase.Addr() == "" {
+		return nil, genParameterError(mtype,
+			fmt.Sprintf("no network address in MID %q", mid))
+	}
+	client := &client{
+		ModuleInternal:      moduleBase,
+		mtype:               mtype,
+		timeout:             defaultTimeout,
+		healthCheckInterval: defaultHealthCheckInterval,
+		closeCh:             make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(client); err != nil {
+			return nil, err
+		}
+	}
+	var reply PingReply
+	if err := client.call(serviceModule+".Ping", &Empty{}, &reply); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if reply.Type != mtype {
+		client.Close()
+		return nil, genError(mtype,
+			fmt.Sprintf("incorrect remote module type: %s (MID: %s)", reply.Type, reply.MID))
+	}
+	client.syncCounts(reply.Counts)
+	if client.healthCheckInterval > 0 {
+		go client.checkHealthPeriodically()
+	}
+	return client, nil
+}
+
+// conn 用于获取RPC客户端，必要时会建立新的连接。
+func (client *client) conn() (*rpc.Client, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	if client.closed {
+		return nil, genError(client.mtype, "closed client")
+	}
+	if client.rpcClient != nil {
+		return client.rpcClient, nil
+	}
+	conn, err := net.DialTimeout("tcp", client.Addr(), client.timeout)
+	if err != nil {
+		return nil, genErrorByError(client.mtype, err)
+	}
+	client.rpcClient = jsonrpc.NewClient(conn)
+	return client.rpcClient, nil
+}
+
+// reset 用于关闭出错的RPC客户端，以便之后重新建立连接。
+func (client *client) reset(rpcClient *rpc.Client) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	if client.rpcClient == rpcClient {
+		client.rpcClient = nil
+	}
+	rpcClient.Close()
+}
+
+// call 用于调用远程组件的方法。
+// 远程组件不可达或调用超时时，远程组件会被视为不健康的。
+func (client *client) call(method string, args interface{}, reply interface{}) error {
+	atomic.AddUint64(&client.handlingNumber, 1)
+	defer atomic.AddUint64(&client.handlingNumber, ^uint64(0))
+	rpcClient, err := client.conn()
+	if err != nil {
+		atomic.StoreUint32(&client.healthy, 0)
+		return err
+	}
+	call := rpcClient.Go(method, args, reply, make(chan *rpc.Call, 1))
+	timer := time.NewTimer(client.timeout)
+	defer timer.Stop()
+	select {
+	case <-call.Done:
+		err = call.Error
+	case <-timer.C:
+		err = fmt.Errorf("call %s timeout: %s", method, client.timeout)
+	}
+	if err == nil {
+		atomic.StoreUint32(&client.healthy, 1)
+		return nil
+	}
+	if _, ok := err.(rpc.ServerError); !ok {
+		atomic.StoreUint32(&client.healthy, 0)
+		client.reset(rpcClient)
+	}
+	return genError(client.mtype,
+		fmt.Sprintf("remote call %s to %s failed: %s", method, client.Addr(), err))
+}
+
+// checkHealthPeriodically 用于在后台定期检查远程组件的健康状况。
+func (client *client) checkHealthPeriodically() {
+	ticker := time.NewTicker(client.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-client.closeCh:
+			return
+		case <-ticker.C:
+			if err := client.Ping(); err != nil {
+				logger.Warnf("The remote module %s is unhealthy: %s", client.ID(), err)
+			}
+		}
+	}
+}
+
+// Ping 用于检查远程组件的健康状况并同步其计数。
+func (client *client) Ping() error {
+	var reply PingReply
+	if err := client.call(serviceModule+".Ping", &Empty{}, &reply); err != nil {
+		return err
+	}
+	client.syncCounts(reply.Counts)
+	return nil
+}
+
+// Healthy 用于判断远程组件在最近一次调用或健康检查时是否健康。
+func (client *client) Healthy() bool {
+	return atomic.LoadUint32(&client.healthy) == 1
+}
+
+// syncCounts 用于同步远程组件的计数。
+func (client *client) syncCounts(counts module.Counts) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	client.counts = counts
+}
+
+// Close 用于关闭客户端，包括后台的健康检查和连接。
+// 远程组件本身不会被关闭。
+func (client *client) Close() error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	if client.closed {
+		return nil
+	}
+	client.closed = true
+	close(client.closeCh)
+	if client.rpcClient != nil {
+		client.rpcClient.Close()
+		client.rpcClient = nil
+	}
+	return nil
+}
+
+func (client *client) CalledCount() uint64 {
+	return client.Counts().CalledCount
+}
+
+func (client *client) AcceptedCount() uint64 {
+	return client.Counts().AcceptedCount
+}
+
+func (client *client) CompletedCount() uint64 {
+	return client.Counts().CompletedCount
+}
+
+func (client *client) HandlingNumber() uint64 {
+	return atomic.LoadUint64(&client.handlingNumber)
+}
+
+func (client *client) Counts() module.Counts {
+	client.lock.Lock()
+	counts := client.counts
+	client.lock.Unlock()
+	counts.HandlingNumber = client.HandlingNumber()
+	return counts
+}
+
+func (client *client) Summary() module.SummaryStruct {
+	counts := client.Counts()
+	return module.SummaryStruct{
+		ID:        client.ID(),
+		Called:    counts.CalledCount,
+		Accepted:  counts.AcceptedCount,
+		Completed: counts.CompletedCount,
+		Handling:  counts.HandlingNumber,
+		Latency:   counts.Latency,
+		Bytes:     counts.BytesProcessed,
+		Errors:    counts.ErrorCounts,
+		Extra: SummaryExtraStruct{
+			Addr:    client.Addr(),
+			Healthy: client.Healthy(),
+		},
+	}
+}
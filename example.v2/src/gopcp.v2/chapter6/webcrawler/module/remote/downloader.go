@@ -0,0 +1,47 @@
+package remote
+
+import (
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// NewDownloader 用于创建一个远程下载器的客户端。
+// 参数mid代表组件ID，其中的网络地址即远程下载器的服务端的地址。
+// 参数opts代表可选项，可以为空。
+func NewDownloader(
+	mid module.MID,
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Downloader, error) {
+	client, err := newClient(module.TYPE_DOWNLOADER, mid, scoreCalculator, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteDownloader{client: client}, nil
+}
+
+// remoteDownloader 代表远程下载器的客户端的实现类型。
+type remoteDownloader struct {
+	*client
+}
+
+func (downloader *remoteDownloader) Download(req *module.Request) (*module.Response, error) {
+	wireReq, err := toWireRequest(req)
+	if err != nil {
+		return nil, genParameterError(downloader.mtype, err.Error())
+	}
+	var reply DownloadReply
+	if err := downloader.call(serviceDownloader+".Download", wireReq, &reply); err != nil {
+		return nil, err
+	}
+	downloader.syncCounts(reply.Counts)
+	var resp *module.Response
+	if reply.Response != nil {
+		resp, err = fromWireResponse(reply.Response, req.HTTPReq())
+		if err != nil {
+			return nil, genErrorByError(downloader.mtype, err)
+		}
+	}
+	if reply.Error != nil {
+		return resp, fromWireError(*reply.Error)
+	}
+	return resp, nil
+}
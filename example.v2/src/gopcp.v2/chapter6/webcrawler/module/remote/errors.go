@@ -0,0 +1,100 @@
+package remote
+
+import (
+	stderrors "errors"
+
+	"gopcp.v2/chapter6/webcrawler/errors"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// errorTypeOf 用于获取与组件类型对应的错误类型。
+func errorTypeOf(mtype module.Type) errors.ErrorType {
+	switch mtype {
+	case module.TYPE_DOWNLOADER:
+		return errors.ERROR_TYPE_DOWNLOADER
+	case module.TYPE_ANALYZER:
+		return errors.ERROR_TYPE_ANALYZER
+	case module.TYPE_PIPELINE:
+		return errors.ERROR_TYPE_PIPELINE
+	}
+	return errors.ERROR_TYPE_SCHEDULER
+}
+
+// genError 用于生成爬虫错误值。
+func genError(mtype module.Type, errMsg string) error {
+	return errors.NewCrawlerError(errorTypeOf(mtype), errMsg)
+}
+
+// genErrorByError 用于基于给定的错误值生成爬虫错误值。
+func genErrorByError(mtype module.Type, err error) error {
+	return errors.NewCrawlerErrorBy(errorTypeOf(mtype), err)
+}
+
+// genParameterError 用于生成爬虫参数错误值。
+func genParameterError(mtype module.Type, errMsg string) error {
+	return errors.NewCrawlerErrorBy(errorTypeOf(mtype),
+		errors.NewIllegalParameterError(errMsg))
+}
+
+// Error 代表在网络上传输的错误。
+type Error struct {
+	// Type 代表爬虫错误的类型。若原错误不是爬虫错误，则为空。
+	Type errors.ErrorType `json:"type"`
+	// Msg 代表错误提示信息。
+	Msg string `json:"msg"`
+}
+
+// toWireError 用于把错误值转换为在网络上传输的错误。
+func toWireError(err error) Error {
+	var wireErr Error
+	if ce, ok := err.(errors.CrawlerError); ok {
+		wireErr.Type = ce.Type()
+	}
+	wireErr.Msg = err.Error()
+	return wireErr
+}
+
+// toWireErrors 用于把错误值的列表转换为在网络上传输的错误的列表。
+func toWireErrors(errs []error) []Error {
+	var wireErrs []Error
+	for _, err := range errs {
+		if err != nil {
+			wireErrs = append(wireErrs, toWireError(err))
+		}
+	}
+	return wireErrs
+}
+
+// fromWireError 用于把在网络上传输的错误还原为错误值。
+// 爬虫错误会被还原为具有相同类型和提示信息的爬虫错误。
+func fromWireError(wireErr Error) error {
+	if wireErr.Type == "" {
+		return stderrors.New(wireErr.Msg)
+	}
+	return &remoteCrawlerError{errType: wireErr.Type, msg: wireErr.Msg}
+}
+
+// fromWireErrors 用于把在网络上传输的错误的列表还原为错误值的列表。
+func fromWireErrors(wireErrs []Error) []error {
+	var errs []error
+	for _, wireErr := range wireErrs {
+		errs = append(errs, fromWireError(wireErr))
+	}
+	return errs
+}
+
+// remoteCrawlerError 代表从远程组件得到的爬虫错误的类型。
+type remoteCrawlerError struct {
+	// errType 代表错误的类型。
+	errType errors.ErrorType
+	// msg 代表完整的错误提示信息。
+	msg string
+}
+
+func (rce *remoteCrawlerError) Type() errors.ErrorType {
+	return rce.errType
+}
+
+func (rce *remoteCrawlerError) Error() string {
+	return rce.msg
+}
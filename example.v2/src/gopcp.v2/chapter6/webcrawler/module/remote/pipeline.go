@@ -0,0 +1,58 @@
+package remote
+
+import (
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// NewPipeline 用于创建一个远程条目处理管道的客户端。
+// 参数mid代表组件ID，其中的网络地址即远程条目处理管道的服务端的地址。
+// 参数opts代表可选项，可以为空。
+func NewPipeline(
+	mid module.MID,
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Pipeline, error) {
+	client, err := newClient(module.TYPE_PIPELINE, mid, scoreCalculator, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &remotePipeline{client: client}, nil
+}
+
+// remotePipeline 代表远程条目处理管道的客户端的实现类型。
+type remotePipeline struct {
+	*client
+}
+
+// ItemProcessors 总会返回nil，因为条目处理函数无法在网络上传输。
+func (pipeline *remotePipeline) ItemProcessors() []module.ProcessItem {
+	return nil
+}
+
+// Send 会把条目发送给远程条目处理管道。
+// 条目中的值会以JSON的形式传输。
+func (pipeline *remotePipeline) Send(item module.Item) []error {
+	var reply SendReply
+	if err := pipeline.call(servicePipeline+".Send", &SendArgs{Item: item}, &reply); err != nil {
+		return []error{err}
+	}
+	pipeline.syncCounts(reply.Counts)
+	return fromWireErrors(reply.Errors)
+}
+
+// FailFast 会获取远程条目处理管道是否是快速失败的。
+// 调用失败时，结果值为false。
+func (pipeline *remotePipeline) FailFast() bool {
+	var failFast bool
+	if err := pipeline.call(servicePipeline+".FailFast", &Empty{}, &failFast); err != nil {
+		logger.Errorf("Couldn't get fail fast of remote pipeline %s: %s", pipeline.ID(), err)
+		return false
+	}
+	return failFast
+}
+
+// SetFailFast 会设置远程条目处理管道是否快速失败。
+func (pipeline *remotePipeline) SetFailFast(failFast bool) {
+	if err := pipeline.call(servicePipeline+".SetFailFast", &failFast, &Empty{}); err != nil {
+		logger.Errorf("Couldn't set fail fast of remote pipeline %s: %s", pipeline.ID(), err)
+	}
+}
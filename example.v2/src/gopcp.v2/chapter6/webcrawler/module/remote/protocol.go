@@ -0,0 +1,265 @@
+package remote
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// 远程组件的服务名称。
+const (
+	// serviceModule 代表所有远程组件都会提供的基础服务的名称。
+	serviceModule = "Module"
+	// serviceDownloader 代表下载器服务的名称。
+	serviceDownloader = "Downloader"
+	// serviceAnalyzer 代表分析器服务的名称。
+	serviceAnalyzer = "Analyzer"
+	// servicePipeline 代表条目处理管道服务的名称。
+	servicePipeline = "Pipeline"
+)
+
+// Empty 代表空的参数或结果。
+type Empty struct{}
+
+// PingReply 代表健康检查的结果。
+type PingReply struct {
+	// MID 代表远程组件的ID。
+	MID module.MID `json:"mid"`
+	// Type 代表远程组件的类型。
+	Type module.Type `json:"type"`
+	// Counts 代表远程组件的计数。
+	Counts module.Counts `json:"counts"`
+}
+
+// Request 代表在网络上传输的请求。
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body,omitempty"`
+	// Depth 代表请求的深度。
+	Depth uint32 `json:"depth"`
+	// Attempt 代表请求的尝试序号。
+	Attempt uint32 `json:"attempt"`
+	// Unlimited 代表请求是否不受下载器的内容类型和响应体大小的限制。
+	Unlimited bool `json:"unlimited,omitempty"`
+	// Score 代表请求的分数。若为nil，则请求不带有分数。
+	Score *float64 `json:"score,omitempty"`
+}
+
+// Response 代表在网络上传输的响应。
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Proto      string      `json:"proto"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// Request 代表得到该响应的请求。
+	Request Request `json:"request"`
+	// Depth 代表响应的深度。
+	Depth uint32 `json:"depth"`
+}
+
+// Data 代表在网络上传输的数据，其中只有一个字段不为nil。
+type Data struct {
+	Request *Request    `json:"request,omitempty"`
+	Item    module.Item `json:"item,omitempty"`
+}
+
+// DownloadReply 代表下载的结果。
+type DownloadReply struct {
+	Response *Response     `json:"response"`
+	Error    *Error        `json:"error"`
+	Counts   module.Counts `json:"counts"`
+}
+
+// AnalyzeReply 代表分析的结果。
+type AnalyzeReply struct {
+	DataList []Data        `json:"data_list"`
+	Errors   []Error       `json:"errors"`
+	Counts   module.Counts `json:"counts"`
+}
+
+// SendArgs 代表向条目处理管道发送条目时的参数。
+type SendArgs struct {
+	Item module.Item `json:"item"`
+}
+
+// SendReply 代表向条目处理管道发送条目的结果。
+type SendReply struct {
+	Errors []Error       `json:"errors"`
+	Counts module.Counts `json:"counts"`
+}
+
+// toWireRequest 用于把请求转换为在网络上传输的请求。
+// 若HTTP请求带有请求体，则请求体会被读出并还原。
+func toWireRequest(req *module.Request) (*Request, error) {
+	if req == nil || !req.Valid() {
+		return nil, fmt.Errorf("invalid request")
+	}
+	httpReq := req.HTTPReq()
+	wireReq := &Request{
+		Method:    httpReq.Method,
+		URL:       httpReq.URL.String(),
+		Header:    httpReq.Header,
+		Depth:     req.Depth(),
+		Attempt:   req.Attempt(),
+		Unlimited: req.Unlimited(),
+	}
+	if score, ok := req.Score(); ok {
+		wireReq.Score = &score
+	}
+	if httpReq.Body != nil && httpReq.Body != http.NoBody {
+		body, err := ioutil.ReadAll(httpReq.Body)
+		httpReq.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		wireReq.Body = body
+	}
+	return wireReq, nil
+}
+
+// toHTTPRequest 用于把在网络上传输的请求还原为HTTP请求。
+func toHTTPRequest(wireReq *Request) (*http.Request, error) {
+	var body io.Reader
+	if len(wireReq.Body) > 0 {
+		body = bytes.NewReader(wireReq.Body)
+	}
+	httpReq, err := http.NewRequest(wireReq.Method, wireReq.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	if wireReq.Header != nil {
+		httpReq.Header = wireReq.Header
+	}
+	return httpReq, nil
+}
+
+// fromWireRequest 用于把在网络上传输的请求还原为请求。
+func fromWireRequest(wireReq *Request) (*module.Request, error) {
+	httpReq, err := toHTTPRequest(wireReq)
+	if err != nil {
+		return nil, err
+	}
+	var req *module.Request
+	if wireReq.Unlimited {
+		req = module.NewUnlimitedRequest(httpReq)
+	} else {
+		req = module.NewRequest(httpReq, wireReq.Depth)
+	}
+	for i := uint32(0); i < wireReq.Attempt; i++ {
+		req = req.NextAttempt()
+	}
+	if wireReq.Score != nil {
+		req = req.WithScore(*wireReq.Score)
+	}
+	return req, nil
+}
+
+// toWireResponse 用于把响应转换为在网络上传输的响应。
+// 响应体会被读出并关闭。
+func toWireResponse(resp *module.Response) (*Response, error) {
+	if resp == nil || resp.HTTPResp() == nil {
+		return nil, fmt.Errorf("invalid response")
+	}
+	httpResp := resp.HTTPResp()
+	wireResp := &Response{
+		StatusCode: httpResp.StatusCode,
+		Status:     httpResp.Status,
+		Proto:      httpResp.Proto,
+		Header:     httpResp.Header,
+		Depth:      resp.Depth(),
+	}
+	if httpResp.Body != nil {
+		body, err := ioutil.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		wireResp.Body = body
+	}
+	if httpReq := httpResp.Request; httpReq != nil && httpReq.URL != nil {
+		wireResp.Request = Request{
+			Method: httpReq.Method,
+			URL:    httpReq.URL.String(),
+			Header: httpReq.Header,
+		}
+	}
+	return wireResp, nil
+}
+
+// fromWireResponse 用于把在网络上传输的响应还原为响应。
+// 参数httpReq代表得到该响应的HTTP请求，若为nil，则会根据响应中的请求生成。
+func fromWireResponse(
+	wireResp *Response, httpReq *http.Request) (*module.Response, error) {
+	if httpReq == nil {
+		var err error
+		httpReq, err = toHTTPRequest(&wireResp.Request)
+		if err != nil {
+			return nil, err
+		}
+	}
+	httpResp := &http.Response{
+		StatusCode:    wireResp.StatusCode,
+		Status:        wireResp.Status,
+		Proto:         wireResp.Proto,
+		Header:        wireResp.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(wireResp.Body)),
+		ContentLength: int64(len(wireResp.Body)),
+		Request:       httpReq,
+	}
+	if httpResp.Header == nil {
+		httpResp.Header = http.Header{}
+	}
+	return module.NewResponse(httpResp, wireResp.Depth), nil
+}
+
+// toWireDataList 用于把数据的列表转换为在网络上传输的数据的列表。
+// 无法转换的数据会产生相应的错误。
+func toWireDataList(dataList []module.Data) ([]Data, []error) {
+	var wireDataList []Data
+	var errs []error
+	for _, data := range dataList {
+		switch d := data.(type) {
+		case *module.Request:
+			wireReq, err := toWireRequest(d)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			wireDataList = append(wireDataList, Data{Request: wireReq})
+		case module.Item:
+			wireDataList = append(wireDataList, Data{Item: d})
+		case nil:
+		default:
+			errs = append(errs, fmt.Errorf("unsupported data type %T", d))
+		}
+	}
+	return wireDataList, errs
+}
+
+// fromWireDataList 用于把在网络上传输的数据的列表还原为数据的列表。
+func fromWireDataList(wireDataList []Data) ([]module.Data, []error) {
+	var dataList []module.Data
+	var errs []error
+	for _, wireData := range wireDataList {
+		switch {
+		case wireData.Request != nil:
+			req, err := fromWireRequest(wireData.Request)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			dataList = append(dataList, req)
+		case wireData.Item != nil:
+			dataList = append(dataList, wireData.Item)
+		}
+	}
+	return dataList, errs
+}
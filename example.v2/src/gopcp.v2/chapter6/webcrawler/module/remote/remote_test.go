@@ -0,0 +1,241 @@
+package remote
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/errors"
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/module/local/analyzer"
+	"gopcp.v2/chapter6/webcrawler/module/local/downloader"
+	"gopcp.v2/chapter6/webcrawler/module/local/pipeline"
+)
+
+// startServer 用于为给定的本地组件启动一个服务端，并返回用于创建客户端的组件ID。
+func startServer(m module.Module, t *testing.T) (*Server, module.MID) {
+	server, err := NewServer(m)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a server: %s", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("An error occurs when listening: %s", err)
+	}
+	go server.Serve(listener)
+	ok, mtype := module.GetType(m.ID())
+	if !ok {
+		t.Fatalf("Illegal MID: %s", m.ID())
+	}
+	mid, err := module.GenMID(mtype, module.DefaultSNGen.Get(), listener.Addr())
+	if err != nil {
+		t.Fatalf("An error occurs when generating a MID: %s", err)
+	}
+	return server, mid
+}
+
+func TestRemoteDownloader(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/big" {
+			io.WriteString(w, strings.Repeat("a", 100))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("X-Test"), r.URL.Path)
+	}))
+	defer site.Close()
+	local, err := downloader.New("D1", &http.Client{}, nil, downloader.WithMaxBodyBytes(50, false))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", err)
+	}
+	server, mid := startServer(local, t)
+	defer server.Close()
+	d, err := NewDownloader(mid, module.CalculateScoreSimple)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a remote downloader: %s", err)
+	}
+	defer d.(io.Closer).Close()
+	if d.ID() != mid || d.Addr() == "" {
+		t.Fatalf("Inconsistent ID or address: %s, %s", d.ID(), d.Addr())
+	}
+	httpReq, _ := http.NewRequest("GET", site.URL+"/page", nil)
+	httpReq.Header.Set("X-Test", "header")
+	resp, err := d.Download(module.NewRequest(httpReq, 2).NextAttempt())
+	if err != nil {
+		t.Fatalf("An error occurs when downloading content: %s", err)
+	}
+	body, _ := ioutil.ReadAll(resp.HTTPResp().Body)
+	if string(body) != "GET header /page" {
+		t.Fatalf("Inconsistent body: %q", body)
+	}
+	if resp.Depth() != 2 || resp.HTTPResp().Request != httpReq ||
+		resp.HTTPResp().Header.Get("Content-Type") != "text/html" {
+		t.Fatalf("Inconsistent response: %#v", resp.HTTPResp())
+	}
+	// 保留类型的错误。
+	httpReq, _ = http.NewRequest("GET", site.URL+"/big", nil)
+	if _, err = d.Download(module.NewRequest(httpReq, 0)); err == nil {
+		t.Fatal("No error when downloading too large content!")
+	}
+	ce, ok := err.(errors.CrawlerError)
+	if !ok || ce.Type() != errors.ERROR_TYPE_DOWNLOADER ||
+		!strings.Contains(ce.Error(), "response body too large") {
+		t.Fatalf("Inconsistent error: %#v", err)
+	}
+	// 同步的计数。
+	expected := local.Counts()
+	if counts := d.Counts(); counts != expected || counts.CalledCount != 2 {
+		t.Fatalf("Inconsistent counts: expected: %+v, actual: %+v", expected, counts)
+	}
+	summary := d.Summary()
+	if summary.Called != 2 || summary.Extra != (SummaryExtraStruct{Addr: d.Addr(), Healthy: true}) {
+		t.Fatalf("Inconsistent summary: %+v", summary)
+	}
+	// 混合本地和远程的组件。
+	registrar := module.NewRegistrar()
+	for _, m := range []module.Module{local, d} {
+		if ok, err := registrar.Register(m); !ok || err != nil {
+			t.Fatalf("Couldn't register module %s: %v", m.ID(), err)
+		}
+	}
+	// 健康检查。
+	if err := d.(*remoteDownloader).Ping(); err != nil {
+		t.Fatalf("An error occurs when pinging: %s", err)
+	}
+	server.Close()
+	if err := d.(*remoteDownloader).Ping(); err == nil {
+		t.Fatal("No error when pinging a closed server!")
+	}
+	if d.(*remoteDownloader).Healthy() {
+		t.Fatal("The remote downloader is healthy after its server has been closed!")
+	}
+	if _, err := d.Download(module.NewRequest(httpReq, 0)); err == nil {
+		t.Fatal("No error when downloading with a closed server!")
+	}
+}
+
+func TestRemoteAnalyzer(t *testing.T) {
+	parse := func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		body, _ := ioutil.ReadAll(httpResp.Body)
+		link, _ := httpResp.Request.URL.Parse(string(body))
+		httpReq, _ := http.NewRequest("GET", link.String(), nil)
+		return []module.Data{
+			module.NewRequest(httpReq, respDepth+1),
+			module.Item{"url": httpResp.Request.URL.String(), "length": len(body)},
+		}, []error{stderrors.New("parse error")}
+	}
+	local, err := analyzer.New("A1", []module.ParseResponse{parse}, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s", err)
+	}
+	server, mid := startServer(local, t)
+	defer server.Close()
+	a, err := NewAnalyzer(mid, nil, WithTimeout(time.Second), WithHealthCheckInterval(0))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a remote analyzer: %s", err)
+	}
+	defer a.(io.Closer).Close()
+	if a.RespParsers() != nil {
+		t.Fatal("Non-nil response parsers of remote analyzer!")
+	}
+	httpReq, _ := http.NewRequest("GET", "http://example.com/dir/page", nil)
+	httpResp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("next")),
+		Request:    httpReq,
+	}
+	dataList, errs := a.Analyze(module.NewResponse(httpResp, 1))
+	if len(errs) != 1 || errs[0].Error() != "parse error" {
+		t.Fatalf("Inconsistent errors: %v", errs)
+	}
+	if len(dataList) != 2 {
+		t.Fatalf("Inconsistent data list length: %d", len(dataList))
+	}
+	req, ok := dataList[0].(*module.Request)
+	if !ok || req.HTTPReq().URL.String() != "http://example.com/dir/next" || req.Depth() != 2 {
+		t.Fatalf("Inconsistent request: %#v", dataList[0])
+	}
+	item, ok := dataList[1].(module.Item)
+	if !ok || item["url"] != "http://example.com/dir/page" || item["length"] != float64(4) {
+		t.Fatalf("Inconsistent item: %#v", dataList[1])
+	}
+	if counts := a.Counts(); counts != local.Counts() {
+		t.Fatalf("Inconsistent counts: expected: %+v, actual: %+v", local.Counts(), counts)
+	}
+}
+
+func TestRemotePipeline(t *testing.T) {
+	var processed []module.Item
+	process := func(item module.Item) (module.Item, error) {
+		if item["fail"] == true {
+			return nil, stderrors.New("process error")
+		}
+		processed = append(processed, item)
+		return item, nil
+	}
+	local, err := pipeline.New("P1", []module.ProcessItem{process}, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a pipeline: %s", err)
+	}
+	server, mid := startServer(local, t)
+	defer server.Close()
+	p, err := NewPipeline(mid, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a remote pipeline: %s", err)
+	}
+	defer p.(io.Closer).Close()
+	if errs := p.Send(module.Item{"name": "a"}); len(errs) != 0 {
+		t.Fatalf("An error occurs when sending an item: %v", errs)
+	}
+	if len(processed) != 1 || processed[0]["name"] != "a" {
+		t.Fatalf("Inconsistent processed items: %v", processed)
+	}
+	if errs := p.Send(module.Item{"fail": true}); len(errs) != 1 {
+		t.Fatalf("Inconsistent errors: %v", errs)
+	}
+	p.SetFailFast(true)
+	if !local.FailFast() || !p.FailFast() {
+		t.Fatal("The fail fast of remote pipeline has not been set!")
+	}
+	if counts := p.Counts(); counts != local.Counts() || counts.CalledCount != 2 {
+		t.Fatalf("Inconsistent counts: expected: %+v, actual: %+v", local.Counts(), counts)
+	}
+}
+
+func TestRemoteIllegalParameters(t *testing.T) {
+	if _, err := NewServer(nil); err == nil {
+		t.Fatal("No error when creating a server with nil module!")
+	}
+	local, _ := pipeline.New("P1", []module.ProcessItem{
+		func(item module.Item) (module.Item, error) { return item, nil },
+	}, nil)
+	server, mid := startServer(local, t)
+	defer server.Close()
+	if _, err := NewPipeline("P1", nil); err == nil {
+		t.Fatal("No error when creating a remote pipeline without address!")
+	}
+	if _, err := NewDownloader(module.MID("P"+string(mid[1:])), nil); err == nil {
+		t.Fatal("No error when creating a remote downloader with pipeline MID!")
+	}
+	if _, err := NewDownloader(module.MID("D"+string(mid[1:])), nil); err == nil {
+		t.Fatal("No error when creating a remote downloader for a remote pipeline!")
+	}
+	if _, err := NewPipeline(mid, nil, WithTimeout(0)); err == nil {
+		t.Fatal("No error when creating a remote pipeline with illegal timeout!")
+	}
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr()
+	listener.Close()
+	mid, _ = module.GenMID(module.TYPE_PIPELINE, 1, addr)
+	if _, err := NewPipeline(mid, nil, WithTimeout(time.Second)); err == nil {
+		t.Fatal("No error when creating a remote pipeline for an unreachable server!")
+	}
+}
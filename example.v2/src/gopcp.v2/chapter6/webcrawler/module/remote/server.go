@@ -0,0 +1,241 @@
+package remote
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/helper/log"
+)
+
+// logger 代表日志记录器。
+var logger = log.DLogger()
+
+// Server 代表远程组件的服务端的类型。
+// 它会把一个本地的下载器、分析器或条目处理管道以JSON-RPC的形式提供给客户端。
+type Server struct {
+	// module 代表被提供的本地组件。
+	module module.Module
+	// mtype 代表组件的类型。
+	mtype module.Type
+	// rpcServer 代表RPC服务器。
+	rpcServer *rpc.Server
+	// listeners 代表正在使用的监听器的集合。
+	listeners map[net.Listener]bool
+	// conns 代表正在服务的连接的集合。
+	conns map[net.Conn]bool
+	// closed 代表服务端是否已被关闭。
+	closed bool
+	lock   sync.Mutex
+}
+
+// NewServer 用于创建一个远程组件的服务端。
+// 参数m代表被提供的本地组件，其类型由组件ID决定。
+func NewServer(m module.Module) (*Server, error) {
+	if m == nil {
+		return nil, genParameterError("", "nil module")
+	}
+	ok, mtype := module.GetType(m.ID())
+	if !ok || !module.CheckType(mtype, m) {
+		return nil, genParameterError("",
+			fmt.Sprintf("incorrect module type: %T (MID: %s)", m, m.ID()))
+	}
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName(serviceModule, &moduleService{m, mtype}); err != nil {
+		return nil, genErrorByError(mtype, err)
+	}
+	var service interface{}
+	var name string
+	switch mtype {
+	case module.TYPE_DOWNLOADER:
+		name, service = serviceDownloader, &downloaderService{m.(module.Downloader)}
+	case module.TYPE_ANALYZER:
+		name, service = serviceAnalyzer, &analyzerService{m.(module.Analyzer)}
+	case module.TYPE_PIPELINE:
+		name, service = servicePipeline, &pipelineService{m.(module.Pipeline)}
+	}
+	if err := rpcServer.RegisterName(name, service); err != nil {
+		return nil, genErrorByError(mtype, err)
+	}
+	return &Server{
+		module:    m,
+		mtype:     mtype,
+		rpcServer: rpcServer,
+		listeners: map[net.Listener]bool{},
+		conns:     map[net.Conn]bool{},
+	}, nil
+}
+
+// Module 用于获取被提供的本地组件。
+func (server *Server) Module() module.Module {
+	return server.module
+}
+
+// Serve 用于在给定的监听器上接受连接并提供服务。
+// 本方法会一直阻塞，直至监听器被关闭。服务端被关闭时，结果值为nil。
+func (server *Server) Serve(listener net.Listener) error {
+	server.lock.Lock()
+	if server.closed {
+		server.lock.Unlock()
+		listener.Close()
+		return genError(server.mtype, "closed server")
+	}
+	server.listeners[listener] = true
+	server.lock.Unlock()
+	defer func() {
+		server.lock.Lock()
+		delete(server.listeners, listener)
+		server.lock.Unlock()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			server.lock.Lock()
+			closed := server.closed
+			server.lock.Unlock()
+			if closed {
+				return nil
+			}
+			return genErrorByError(server.mtype, err)
+		}
+		if !server.track(conn) {
+			conn.Close()
+			return nil
+		}
+		go func(conn net.Conn) {
+			defer server.untrack(conn)
+			server.rpcServer.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}(conn)
+	}
+}
+
+// ListenAndServe 用于监听给定的网络地址并提供服务。
+func (server *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return genErrorByError(server.mtype, err)
+	}
+	return server.Serve(listener)
+}
+
+// track 用于记录正在服务的连接。若服务端已被关闭，则结果值为false。
+func (server *Server) track(conn net.Conn) bool {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if server.closed {
+		return false
+	}
+	server.conns[conn] = true
+	return true
+}
+
+// untrack 用于移除已结束服务的连接。
+func (server *Server) untrack(conn net.Conn) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	delete(server.conns, conn)
+}
+
+// Close 用于关闭服务端，包括所有的监听器和连接。
+// 被提供的本地组件不会被关闭。
+func (server *Server) Close() error {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if server.closed {
+		return nil
+	}
+	server.closed = true
+	for listener := range server.listeners {
+		listener.Close()
+	}
+	for conn := range server.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+// moduleService 代表所有远程组件都会提供的基础服务。
+type moduleService struct {
+	module module.Module
+	mtype  module.Type
+}
+
+// Ping 用于检查远程组件的健康状况并获取其计数。
+func (service *moduleService) Ping(args *Empty, reply *PingReply) error {
+	reply.MID = service.module.ID()
+	reply.Type = service.mtype
+	reply.Counts = service.module.Counts()
+	return nil
+}
+
+// downloaderService 代表下载器服务。
+type downloaderService struct {
+	downloader module.Downloader
+}
+
+// Download 用于根据请求下载内容。
+func (service *downloaderService) Download(args *Request, reply *DownloadReply) error {
+	defer func() { reply.Counts = service.downloader.Counts() }()
+	req, err := fromWireRequest(args)
+	if err != nil {
+		return err
+	}
+	resp, err := service.downloader.Download(req)
+	if err != nil {
+		wireErr := toWireError(err)
+		reply.Error = &wireErr
+	}
+	if resp != nil {
+		if reply.Response, err = toWireResponse(resp); err != nil && reply.Error == nil {
+			wireErr := toWireError(err)
+			reply.Error = &wireErr
+		}
+	}
+	return nil
+}
+
+// analyzerService 代表分析器服务。
+type analyzerService struct {
+	analyzer module.Analyzer
+}
+
+// Analyze 用于分析响应。
+func (service *analyzerService) Analyze(args *Response, reply *AnalyzeReply) error {
+	defer func() { reply.Counts = service.analyzer.Counts() }()
+	resp, err := fromWireResponse(args, nil)
+	if err != nil {
+		return err
+	}
+	dataList, errs := service.analyzer.Analyze(resp)
+	wireDataList, convErrs := toWireDataList(dataList)
+	reply.DataList = wireDataList
+	reply.Errors = toWireErrors(append(errs, convErrs...))
+	return nil
+}
+
+// pipelineService 代表条目处理管道服务。
+type pipelineService struct {
+	pipeline module.Pipeline
+}
+
+// Send 用于向条目处理管道发送条目。
+func (service *pipelineService) Send(args *SendArgs, reply *SendReply) error {
+	defer func() { reply.Counts = service.pipeline.Counts() }()
+	reply.Errors = toWireErrors(service.pipeline.Send(args.Item))
+	return nil
+}
+
+// FailFast 用于获取条目处理管道是否是快速失败的。
+func (service *pipelineService) FailFast(args *Empty, reply *bool) error {
+	*reply = service.pipeline.FailFast()
+	return nil
+}
+
+// SetFailFast 用于设置条目处理管道是否快速失败。
+func (service *pipelineService) SetFailFast(args *bool, reply *Empty) error {
+	service.pipeline.SetFailFast(*args)
+	return nil
+}
@@ -1,5 +1,7 @@
 package module
 
+import "time"
+
 // CalculateScore 代表用于计算组件评分的函数类型。
 type CalculateScore func(counts Counts) uint64
 
@@ -11,6 +13,15 @@ func CalculateScoreSimple(counts Counts) uint64 {
 		counts.HandlingNumber<<4
 }
 
+// CalculateScoreWithLatency 代表兼顾处理耗时和错误计数的组件评分计算函数。
+// 它在简易评分的基础上，为耗时较长或出错较多的组件加分，
+// 以使基于评分的选择器更少地选择这些组件。
+func CalculateScoreWithLatency(counts Counts) uint64 {
+	return CalculateScoreSimple(counts) +
+		uint64(counts.Latency.P90/time.Millisecond)<<4 +
+		counts.ErrorCounts.Total()<<4
+}
+
 // SetScore 用于设置给定组件的评分。
 // 结果值代表是否更新了评分。
 func SetScore(module Module) bool {
@@ -1,6 +1,9 @@
 package module
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestCalculateScoreSimple(t *testing.T) {
 	counts := Counts{
@@ -21,6 +24,30 @@ func TestCalculateScoreSimple(t *testing.T) {
 	t.Logf("The score is %d.", score)
 }
 
+func TestCalculateScoreWithLatency(t *testing.T) {
+	counts := Counts{
+		CalledCount:    100,
+		AcceptedCount:  99,
+		CompletedCount: 95,
+		HandlingNumber: 2,
+	}
+	baseScore := CalculateScoreWithLatency(counts)
+	if expectedScore := CalculateScoreSimple(counts); baseScore != expectedScore {
+		t.Fatalf("Inconsistent score: expected: %d, actual: %d",
+			expectedScore, baseScore)
+	}
+	slowCounts := counts
+	slowCounts.Latency.P90 = 200 * time.Millisecond
+	if score := CalculateScoreWithLatency(slowCounts); score <= baseScore {
+		t.Fatalf("The score of slow module is not greater: %d <= %d", score, baseScore)
+	}
+	failingCounts := counts
+	failingCounts.ErrorCounts.Downloader = 3
+	if score := CalculateScoreWithLatency(failingCounts); score <= baseScore {
+		t.Fatalf("The score of failing module is not greater: %d <= %d", score, baseScore)
+	}
+}
+
 func TestSetScore(t *testing.T) {
 	fakeModule := NewFakeDownloader(MID("D0"), nil)
 	ok := SetScore(fakeModule)
@@ -0,0 +1,234 @@
+package module
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+
+	"gopcp.v2/chapter6/webcrawler/errors"
+)
+
+// Selector 代表组件选择器的接口类型，即组件注册器使用的负载均衡策略。
+// 该接口的实现类型必须是并发安全的！
+type Selector interface {
+	// Type 用于获取选择器的类型。
+	Type() SelectorType
+	// Select 用于从给定的组件列表中选择一个组件。
+	// 参数modules不会为空，且不应被修改。
+	Select(modules []Module) Module
+}
+
+// SelectorType 代表组件选择器的类型。
+type SelectorType string
+
+// 当前认可的组件选择器类型的常量。
+const (
+	// SELECTOR_TYPE_SCORE 代表基于评分的选择器，它会选择评分最低的组件。
+	// 它也是默认的选择器。
+	SELECTOR_TYPE_SCORE SelectorType = "score"
+	// SELECTOR_TYPE_ROUND_ROBIN 代表轮询的选择器。
+	SELECTOR_TYPE_ROUND_ROBIN SelectorType = "round_robin"
+	// SELECTOR_TYPE_WEIGHTED_RANDOM 代表加权随机的选择器。
+	SELECTOR_TYPE_WEIGHTED_RANDOM SelectorType = "weighted_random"
+	// SELECTOR_TYPE_LEAST_HANDLING 代表选择实时处理数最少的组件的选择器。
+	SELECTOR_TYPE_LEAST_HANDLING SelectorType = "least_handling"
+	// SELECTOR_TYPE_P2C 代表“两次随机选择”的选择器。
+	// 它会随机选取两个组件，再从中选择实时处理数较少的那个。
+	SELECTOR_TYPE_P2C SelectorType = "p2c"
+)
+
+// legalSelectorTypeMap 代表合法的组件选择器类型的字典。
+var legalSelectorTypeMap = map[SelectorType]bool{
+	SELECTOR_TYPE_SCORE:           true,
+	SELECTOR_TYPE_ROUND_ROBIN:     true,
+	SELECTOR_TYPE_WEIGHTED_RANDOM: true,
+	SELECTOR_TYPE_LEAST_HANDLING:  true,
+	SELECTOR_TYPE_P2C:             true,
+}
+
+// LegalSelectorType 用于判断给定的组件选择器类型是否合法。
+// 空的类型代表默认的选择器，也是合法的。
+func LegalSelectorType(selectorType SelectorType) bool {
+	return selectorType == "" || legalSelectorTypeMap[selectorType]
+}
+
+// NewSelector 用于根据类型创建一个组件选择器。
+// 若参数selectorType为空，则创建基于评分的选择器。
+// 加权随机的选择器会使用默认的权重函数DefaultWeight。
+func NewSelector(selectorType SelectorType) (Selector, error) {
+	switch selectorType {
+	case "", SELECTOR_TYPE_SCORE:
+		return NewScoreSelector(), nil
+	case SELECTOR_TYPE_ROUND_ROBIN:
+		return NewRoundRobinSelector(), nil
+	case SELECTOR_TYPE_WEIGHTED_RANDOM:
+		return NewWeightedRandomSelector(DefaultWeight), nil
+	case SELECTOR_TYPE_LEAST_HANDLING:
+		return NewLeastHandlingSelector(), nil
+	case SELECTOR_TYPE_P2C:
+		return NewP2CSelector(), nil
+	}
+	errMsg := fmt.Sprintf("illegal selector type: %q", selectorType)
+	return nil, errors.NewIllegalParameterError(errMsg)
+}
+
+// NewScoreSelector 用于创建一个基于评分的选择器。
+// 每次选择时，它都会重新计算所有组件的评分并选择评分最低的组件。
+func NewScoreSelector() Selector {
+	return scoreSelector{}
+}
+
+// scoreSelector 代表基于评分的选择器的实现类型。
+type scoreSelector struct{}
+
+func (scoreSelector) Type() SelectorType {
+	return SELECTOR_TYPE_SCORE
+}
+
+func (scoreSelector) Select(modules []Module) Module {
+	minScore := uint64(0)
+	var selectedModule Module
+	for _, module := range modules {
+		SetScore(module)
+		score := module.Score()
+		if minScore == 0 || score < minScore {
+			selectedModule = module
+			minScore = score
+		}
+	}
+	return selectedModule
+}
+
+// NewRoundRobinSelector 用于创建一个轮询的选择器。
+func NewRoundRobinSelector() Selector {
+	return &roundRobinSelector{}
+}
+
+// roundRobinSelector 代表轮询的选择器的实现类型。
+type roundRobinSelector struct {
+	// next 代表下一次选择的序号。
+	next uint64
+}
+
+func (selector *roundRobinSelector) Type() SelectorType {
+	return SELECTOR_TYPE_ROUND_ROBIN
+}
+
+func (selector *roundRobinSelector) Select(modules []Module) Module {
+	n := atomic.AddUint64(&selector.next, 1) - 1
+	return modules[n%uint64(len(modules))]
+}
+
+// WeightFunc 代表用于计算组件权重的函数类型。
+type WeightFunc func(module Module) uint64
+
+// defaultWeightBase 代表默认的权重函数所用的基准权重。
+const defaultWeightBase = 1 << 10
+
+// DefaultWeight 代表默认的权重函数。
+// 组件的权重与其实时处理数加1成反比，因此越空闲的组件越容易被选中。
+func DefaultWeight(module Module) uint64 {
+	return defaultWeightBase / (module.HandlingNumber() + 1)
+}
+
+// NewWeightedRandomSelector 用于创建一个加权随机的选择器。
+// 组件被选中的概率与其权重成正比，权重为0的组件只会在所有组件的权重都为0时被随机选中。
+// 若参数weight为nil，则所有组件的权重都为1。
+func NewWeightedRandomSelector(weight WeightFunc) Selector {
+	return &weightedRandomSelector{weight: weight}
+}
+
+// weightedRandomSelector 代表加权随机的选择器的实现类型。
+type weightedRandomSelector struct {
+	// weight 代表权重函数。
+	weight WeightFunc
+}
+
+func (selector *weightedRandomSelector) Type() SelectorType {
+	return SELECTOR_TYPE_WEIGHTED_RANDOM
+}
+
+func (selector *weightedRandomSelector) Select(modules []Module) Module {
+	if selector.weight == nil {
+		return modules[rand.Intn(len(modules))]
+	}
+	var weights [16]uint64
+	weightList := weights[:0]
+	var total uint64
+	for _, module := range modules {
+		w := selector.weight(module)
+		weightList = append(weightList, w)
+		total += w
+	}
+	if total == 0 {
+		return modules[rand.Intn(len(modules))]
+	}
+	r := rand.Uint64() % total
+	for i, w := range weightList {
+		if r < w {
+			return modules[i]
+		}
+		r -= w
+	}
+	return modules[len(modules)-1]
+}
+
+// NewLeastHandlingSelector 用于创建一个选择实时处理数最少的组件的选择器。
+// 实时处理数相同的组件会被轮流选中。
+func NewLeastHandlingSelector() Selector {
+	return &leastHandlingSelector{}
+}
+
+// leastHandlingSelector 代表选择实时处理数最少的组件的选择器的实现类型。
+type leastHandlingSelector struct {
+	// next 代表下一次选择时开始比较的序号。
+	next uint64
+}
+
+func (selector *leastHandlingSelector) Type() SelectorType {
+	return SELECTOR_TYPE_LEAST_HANDLING
+}
+
+func (selector *leastHandlingSelector) Select(modules []Module) Module {
+	length := uint64(len(modules))
+	start := atomic.AddUint64(&selector.next, 1) - 1
+	var selectedModule Module
+	var minHandling uint64
+	for i := uint64(0); i < length; i++ {
+		module := modules[(start+i)%length]
+		handling := module.HandlingNumber()
+		if selectedModule == nil || handling < minHandling {
+			selectedModule = module
+			minHandling = handling
+		}
+	}
+	return selectedModule
+}
+
+// NewP2CSelector 用于创建一个“两次随机选择”的选择器。
+func NewP2CSelector() Selector {
+	return p2cSelector{}
+}
+
+// p2cSelector 代表“两次随机选择”的选择器的实现类型。
+type p2cSelector struct{}
+
+func (p2cSelector) Type() SelectorType {
+	return SELECTOR_TYPE_P2C
+}
+
+func (p2cSelector) Select(modules []Module) Module {
+	length := len(modules)
+	if length == 1 {
+		return modules[0]
+	}
+	i := rand.Intn(length)
+	j := rand.Intn(length - 1)
+	if j >= i {
+		j++
+	}
+	if modules[j].HandlingNumber() < modules[i].HandlingNumber() {
+		return modules[j]
+	}
+	return modules[i]
+}
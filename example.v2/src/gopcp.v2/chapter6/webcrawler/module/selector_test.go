@@ -0,0 +1,178 @@
+package module
+
+import (
+	"fmt"
+	"testing"
+)
+
+// genCountedDownloaders 用于生成计数各不相同的仿造下载器。
+// 下载器的实时处理数与给定的计数正相关。
+func genCountedDownloaders(counts ...uint64) []Module {
+	modules := make([]Module, len(counts))
+	for i, count := range counts {
+		modules[i] = &fakeDownloader{
+			fakeModule: fakeModule{
+				mid:             MID(fmt.Sprintf("D%d", i)),
+				count:           count,
+				scoreCalculator: CalculateScoreSimple,
+			},
+		}
+	}
+	return modules
+}
+
+func TestSelectorNew(t *testing.T) {
+	for selectorType := range legalSelectorTypeMap {
+		selector, err := NewSelector(selectorType)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a selector: %s (type: %s)",
+				err, selectorType)
+		}
+		if selector.Type() != selectorType {
+			t.Fatalf("Inconsistent selector type: expected: %s, actual: %s",
+				selectorType, selector.Type())
+		}
+	}
+	if selector, _ := NewSelector(""); selector.Type() != SELECTOR_TYPE_SCORE {
+		t.Fatalf("Inconsistent default selector type: %s", selector.Type())
+	}
+	if !LegalSelectorType("") || LegalSelectorType("random") {
+		t.Fatal("Inconsistent legality of selector types!")
+	}
+	if _, err := NewSelector("random"); err == nil {
+		t.Fatal("No error when creating a selector with illegal type!")
+	}
+}
+
+func TestSelectorSelect(t *testing.T) {
+	modules := genCountedDownloaders(5, 1, 3)
+	if m := NewScoreSelector().Select(modules); m != modules[1] {
+		t.Fatalf("Inconsistent module selected by score: %s", m.ID())
+	}
+	if m := NewLeastHandlingSelector().Select(modules); m != modules[1] {
+		t.Fatalf("Inconsistent module selected by least handling: %s", m.ID())
+	}
+	roundRobin := NewRoundRobinSelector()
+	for i := 0; i < 6; i++ {
+		if m := roundRobin.Select(modules); m != modules[i%3] {
+			t.Fatalf("Inconsistent module selected by round robin: expected: %s, actual: %s",
+				modules[i%3].ID(), m.ID())
+		}
+	}
+	p2c := NewP2CSelector()
+	for i := 0; i < 100; i++ {
+		if m := p2c.Select(modules); m == modules[0] {
+			t.Fatal("The busiest module has been selected by p2c!")
+		}
+	}
+	if m := p2c.Select(modules[:1]); m != modules[0] {
+		t.Fatalf("Inconsistent module selected by p2c: %s", m.ID())
+	}
+	weights := map[MID]uint64{"D0": 0, "D1": 1, "D2": 3}
+	weighted := NewWeightedRandomSelector(func(module Module) uint64 {
+		return weights[module.ID()]
+	})
+	selected := map[MID]int{}
+	for i := 0; i < 4000; i++ {
+		selected[weighted.Select(modules).ID()]++
+	}
+	if selected["D0"] != 0 {
+		t.Fatal("The module with zero weight has been selected!")
+	}
+	if selected["D2"] < 2*selected["D1"] {
+		t.Fatalf("Inconsistent weighted random selection: %v", selected)
+	}
+	// 默认的权重函数会使越空闲的组件越容易被选中。
+	weighted, _ = NewSelector(SELECTOR_TYPE_WEIGHTED_RANDOM)
+	selected = map[MID]int{}
+	for i := 0; i < 4000; i++ {
+		selected[weighted.Select(modules).ID()]++
+	}
+	if selected["D1"] <= selected["D2"] || selected["D2"] <= selected["D0"] {
+		t.Fatalf("Inconsistent weighted random selection with default weight: %v", selected)
+	}
+	uniform := NewWeightedRandomSelector(nil)
+	selected = map[MID]int{}
+	for i := 0; i < 300; i++ {
+		selected[uniform.Select(modules).ID()]++
+	}
+	if len(selected) != 3 {
+		t.Fatalf("Inconsistent uniform random selection: %v", selected)
+	}
+}
+
+func TestRegSetSelector(t *testing.T) {
+	registrar := NewRegistrar()
+	if err := registrar.SetSelector(illegalTypes[0], NewRoundRobinSelector()); err == nil {
+		t.Fatal("No error when setting selector with illegal type!")
+	}
+	if err := registrar.SetSelector(TYPE_DOWNLOADER, NewRoundRobinSelector()); err != nil {
+		t.Fatalf("An error occurs when setting selector: %s", err)
+	}
+	modules := genCountedDownloaders(1, 2, 3)
+	for i := len(modules) - 1; i >= 0; i-- {
+		registrar.Register(modules[i])
+	}
+	for i := 0; i < 6; i++ {
+		m, err := registrar.Get(TYPE_DOWNLOADER)
+		if err != nil {
+			t.Fatalf("An error occurs when getting module instance: %s", err)
+		}
+		if m != modules[i%3] {
+			t.Fatalf("Inconsistent module: expected: %s, actual: %s",
+				modules[i%3].ID(), m.ID())
+		}
+	}
+	registrar.Unregister("D1")
+	selected := map[MID]bool{}
+	for i := 0; i < 4; i++ {
+		m, _ := registrar.Get(TYPE_DOWNLOADER)
+		selected[m.ID()] = true
+	}
+	if len(selected) != 2 || selected["D1"] {
+		t.Fatalf("Inconsistent selected modules after unregistering: %v", selected)
+	}
+	// 恢复默认的选择器。
+	registrar.SetSelector(TYPE_DOWNLOADER, nil)
+	for i := 0; i < 3; i++ {
+		if m, _ := registrar.Get(TYPE_DOWNLOADER); m != modules[0] {
+			t.Fatalf("Inconsistent module selected by default selector: %s", m.ID())
+		}
+	}
+	registrar.Clear()
+	if _, err := registrar.Get(TYPE_DOWNLOADER); err != ErrNotFoundModuleInstance {
+		t.Fatalf("Inconsistent error after clearing: %v", err)
+	}
+}
+
+func BenchmarkRegistrarGet(b *testing.B) {
+	counts := make([]uint64, 16)
+	for i := range counts {
+		counts[i] = uint64(i)
+	}
+	modules := genCountedDownloaders(counts...)
+	for _, selectorType := range []SelectorType{
+		SELECTOR_TYPE_SCORE,
+		SELECTOR_TYPE_ROUND_ROBIN,
+		SELECTOR_TYPE_WEIGHTED_RANDOM,
+		SELECTOR_TYPE_LEAST_HANDLING,
+		SELECTOR_TYPE_P2C,
+	} {
+		registrar := NewRegistrar()
+		for _, m := range modules {
+			registrar.Register(m)
+		}
+		selector, _ := NewSelector(selectorType)
+		registrar.SetSelector(TYPE_DOWNLOADER, selector)
+		b.Run(string(selectorType), func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := registrar.Get(TYPE_DOWNLOADER); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package stub
 
-import "gopcp.v2/chapter6/webcrawler/module"
+import (
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
 
 // ModuleInternal 代表组件的内部基础接口类型。
 type ModuleInternal interface {
@@ -15,6 +19,13 @@ type ModuleInternal interface {
 	IncrHandlingNumber()
 	// DecrHandlingNumber 会把实时处理数减1。
 	DecrHandlingNumber()
+	// RecordLatency 会记录一次处理的耗时。
+	RecordLatency(latency time.Duration)
+	// AddBytes 会把已处理的字节数增加n。
+	AddBytes(n uint64)
+	// RecordError 会根据错误的类型把相应的错误计数增1。
+	// 若错误不是爬虫错误，则视其为与组件同类型的错误。
+	RecordError(err error)
 	// Clear 用于清空所有计数。
 	Clear()
 }
@@ -0,0 +1,91 @@
+package stub
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// latencyBucketNumber 代表耗时直方图的桶的数量。
+// 第i个桶（i>0）容纳的耗时的范围为[2^(i-1), 2^i)微秒，第0个桶只容纳0微秒。
+const latencyBucketNumber = 64
+
+// latencyHistogram 代表耗时直方图的类型。
+// 它以2的幂为边界划分耗时，因此得到的分位数的误差不会超过一倍。
+// 它的所有方法都是并发安全的。
+type latencyHistogram struct {
+	// buckets 代表各个桶中的耗时的数量。
+	buckets [latencyBucketNumber]uint64
+	// count 代表耗时的总数量。
+	count uint64
+	// max 代表最大的耗时，单位是纳秒。
+	max int64
+}
+
+// record 用于记录一次耗时。
+func (hist *latencyHistogram) record(latency time.Duration) {
+	if latency < 0 {
+		latency = 0
+	}
+	index := bits.Len64(uint64(latency / time.Microsecond))
+	if index >= latencyBucketNumber {
+		index = latencyBucketNumber - 1
+	}
+	atomic.AddUint64(&hist.buckets[index], 1)
+	atomic.AddUint64(&hist.count, 1)
+	for {
+		max := atomic.LoadInt64(&hist.max)
+		if int64(latency) <= max ||
+			atomic.CompareAndSwapInt64(&hist.max, max, int64(latency)) {
+			break
+		}
+	}
+}
+
+// quantile 用于获取给定的分位数的近似值。
+// 参数q的取值范围为(0, 1]。结果值是相应的桶的上界，但不会大于最大的耗时。
+func (hist *latencyHistogram) quantile(q float64, count uint64, max time.Duration) time.Duration {
+	if count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(count) + 0.5)
+	if rank == 0 {
+		rank = 1
+	}
+	var cumulative uint64
+	for i := range hist.buckets {
+		cumulative += atomic.LoadUint64(&hist.buckets[i])
+		if cumulative < rank {
+			continue
+		}
+		upper := time.Duration(uint64(1)<<uint(i)) * time.Microsecond
+		if upper > max {
+			upper = max
+		}
+		return upper
+	}
+	return max
+}
+
+// summary 用于获取耗时的分布。
+func (hist *latencyHistogram) summary() module.LatencyStruct {
+	count := atomic.LoadUint64(&hist.count)
+	max := time.Duration(atomic.LoadInt64(&hist.max))
+	return module.LatencyStruct{
+		P50: hist.quantile(0.5, count, max),
+		P90: hist.quantile(0.9, count, max),
+		P99: hist.quantile(0.99, count, max),
+		Max: max,
+	}
+}
+
+// clear 用于清空直方图。
+func (hist *latencyHistogram) clear() {
+	for i := range hist.buckets {
+		atomic.StoreUint64(&hist.buckets[i], 0)
+	}
+	atomic.StoreUint64(&hist.count, 0)
+	atomic.StoreInt64(&hist.max, 0)
+}
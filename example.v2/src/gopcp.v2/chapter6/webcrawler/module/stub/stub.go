@@ -3,6 +3,7 @@ package stub
 import (
 	"fmt"
 	"sync/atomic"
+	"time"
 
 	"gopcp.v2/chapter6/webcrawler/errors"
 	"gopcp.v2/chapter6/webcrawler/module"
@@ -30,6 +31,29 @@ type myModule struct {
 	completedCount uint64
 	// handlingNumber 代表实时处理数。
 	handlingNumber uint64
+	// latency 代表处理耗时的直方图。
+	latency latencyHistogram
+	// bytesProcessed 代表已处理的字节数。
+	bytesProcessed uint64
+	// errorType 代表与组件同类型的错误类型。
+	errorType errors.ErrorType
+	// errorCounts 代表各类错误的计数，其中的元素与errorTypes一一对应。
+	errorCounts [4]uint64
+}
+
+// errorTypes 代表参与计数的错误类型的列表。
+var errorTypes = [4]errors.ErrorType{
+	errors.ERROR_TYPE_DOWNLOADER,
+	errors.ERROR_TYPE_ANALYZER,
+	errors.ERROR_TYPE_PIPELINE,
+	errors.ERROR_TYPE_SCHEDULER,
+}
+
+// moduleErrorTypeMap 代表组件类型与错误类型的映射。
+var moduleErrorTypeMap = map[module.Type]errors.ErrorType{
+	module.TYPE_DOWNLOADER: errors.ERROR_TYPE_DOWNLOADER,
+	module.TYPE_ANALYZER:   errors.ERROR_TYPE_ANALYZER,
+	module.TYPE_PIPELINE:   errors.ERROR_TYPE_PIPELINE,
 }
 
 // NewModuleInternal 用于创建一个组件内部基础类型的实例。
@@ -41,10 +65,12 @@ func NewModuleInternal(
 		return nil, errors.NewIllegalParameterError(
 			fmt.Sprintf("illegal ID %q: %s", mid, err))
 	}
+	_, moduleType := module.GetType(mid)
 	return &myModule{
 		mid:             mid,
 		addr:            parts[2],
 		scoreCalculator: scoreCalculator,
+		errorType:       moduleErrorTypeMap[moduleType],
 	}, nil
 }
 
@@ -91,6 +117,14 @@ func (m *myModule) Counts() module.Counts {
 		AcceptedCount:  atomic.LoadUint64(&m.acceptedCount),
 		CompletedCount: atomic.LoadUint64(&m.completedCount),
 		HandlingNumber: atomic.LoadUint64(&m.handlingNumber),
+		Latency:        m.latency.summary(),
+		BytesProcessed: atomic.LoadUint64(&m.bytesProcessed),
+		ErrorCounts: module.ErrorCounts{
+			Downloader: atomic.LoadUint64(&m.errorCounts[0]),
+			Analyzer:   atomic.LoadUint64(&m.errorCounts[1]),
+			Pipeline:   atomic.LoadUint64(&m.errorCounts[2]),
+			Scheduler:  atomic.LoadUint64(&m.errorCounts[3]),
+		},
 	}
 }
 
@@ -102,6 +136,9 @@ func (m *myModule) Summary() module.SummaryStruct {
 		Accepted:  counts.AcceptedCount,
 		Completed: counts.CompletedCount,
 		Handling:  counts.HandlingNumber,
+		Latency:   counts.Latency,
+		Bytes:     counts.BytesProcessed,
+		Errors:    counts.ErrorCounts,
 		Extra:     nil,
 	}
 }
@@ -126,9 +163,38 @@ func (m *myModule) DecrHandlingNumber() {
 	atomic.AddUint64(&m.handlingNumber, ^uint64(0))
 }
 
+func (m *myModule) RecordLatency(latency time.Duration) {
+	m.latency.record(latency)
+}
+
+func (m *myModule) AddBytes(n uint64) {
+	atomic.AddUint64(&m.bytesProcessed, n)
+}
+
+func (m *myModule) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	errorType := m.errorType
+	if crawlerError, ok := err.(errors.CrawlerError); ok {
+		errorType = crawlerError.Type()
+	}
+	for i, t := range errorTypes {
+		if t == errorType {
+			atomic.AddUint64(&m.errorCounts[i], 1)
+			return
+		}
+	}
+}
+
 func (m *myModule) Clear() {
 	atomic.StoreUint64(&m.calledCount, 0)
 	atomic.StoreUint64(&m.acceptedCount, 0)
 	atomic.StoreUint64(&m.completedCount, 0)
 	atomic.StoreUint64(&m.handlingNumber, 0)
+	m.latency.clear()
+	atomic.StoreUint64(&m.bytesProcessed, 0)
+	for i := range m.errorCounts {
+		atomic.StoreUint64(&m.errorCounts[i], 0)
+	}
 }
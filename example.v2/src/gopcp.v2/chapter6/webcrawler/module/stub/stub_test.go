@@ -1,8 +1,11 @@
 package stub
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	"gopcp.v2/chapter6/webcrawler/errors"
 	"gopcp.v2/chapter6/webcrawler/module"
 )
 
@@ -206,6 +209,57 @@ func TestSummary(t *testing.T) {
 	}
 }
 
+func TestMetrics(t *testing.T) {
+	mi, _ := NewModuleInternal(mid, nil)
+	for i := 1; i <= 100; i++ {
+		mi.RecordLatency(time.Duration(i) * time.Millisecond)
+	}
+	mi.AddBytes(100)
+	mi.AddBytes(28)
+	mi.RecordError(nil)
+	mi.RecordError(fmt.Errorf("plain error"))
+	mi.RecordError(errors.NewCrawlerError(errors.ERROR_TYPE_PIPELINE, "pipeline error"))
+	counts := mi.Counts()
+	latency := counts.Latency
+	if latency.Max != 100*time.Millisecond {
+		t.Fatalf("Inconsistent max latency: expected: %s, actual: %s",
+			100*time.Millisecond, latency.Max)
+	}
+	// 分位数的误差不会超过一倍。
+	for _, q := range []struct {
+		name     string
+		value    time.Duration
+		expected time.Duration
+	}{
+		{"p50", latency.P50, 50 * time.Millisecond},
+		{"p90", latency.P90, 90 * time.Millisecond},
+		{"p99", latency.P99, 99 * time.Millisecond},
+	} {
+		if q.value < q.expected || q.value > latency.Max {
+			t.Fatalf("Inconsistent %s latency: expected: [%s, %s], actual: %s",
+				q.name, q.expected, latency.Max, q.value)
+		}
+	}
+	if counts.BytesProcessed != 128 {
+		t.Fatalf("Inconsistent bytes processed: expected: %d, actual: %d",
+			128, counts.BytesProcessed)
+	}
+	expectedErrorCounts := module.ErrorCounts{Downloader: 1, Pipeline: 1}
+	if counts.ErrorCounts != expectedErrorCounts {
+		t.Fatalf("Inconsistent error counts: expected: %+v, actual: %+v",
+			expectedErrorCounts, counts.ErrorCounts)
+	}
+	summary := mi.Summary()
+	if summary.Latency != latency || summary.Bytes != 128 ||
+		summary.Errors != expectedErrorCounts {
+		t.Fatalf("Inconsistent summary for internal module: %#v", summary)
+	}
+	mi.Clear()
+	if counts := mi.Counts(); counts != (module.Counts{}) {
+		t.Fatalf("The metrics have not been cleared! (counts: %+v)", counts)
+	}
+}
+
 func TestAllInParallel(t *testing.T) {
 	number := uint64(100000)
 	mi, _ := NewModuleInternal(mid, nil)
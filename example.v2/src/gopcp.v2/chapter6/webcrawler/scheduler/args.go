@@ -1,6 +1,12 @@
 package scheduler
 
-import "gopcp.v2/chapter6/webcrawler/module"
+import (
+	"fmt"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/canonical"
+)
 
 // Args 代表参数容器的接口类型。
 type Args interface {
@@ -17,12 +23,50 @@ type RequestArgs struct {
 	// maxDepth 代表了需要被爬取的最大深度。
 	// 实际深度大于此值的请求都会被忽略。
 	MaxDepth uint32 `json:"max_depth"`
+	// Politeness 代表针对单个主机的礼貌性爬取参数。
+	Politeness PolitenessArgs `json:"politeness"`
+	// Robots 代表robots.txt相关的参数。
+	Robots RobotsArgs `json:"robots"`
+	// Canonical 代表URL规范化的规则。
+	// URL会先被规范化，然后再被用于判断是否重复。
+	Canonical canonical.Rules `json:"canonical"`
+	// SeenSet 代表已处理URL集合的参数。
+	SeenSet SeenSetArgs `json:"seen_set"`
+	// Scope 代表爬取范围的参数。
+	Scope ScopeArgs `json:"scope"`
+	// Retry 代表下载失败时的重试参数。
+	Retry RetryArgs `json:"retry"`
+	// Sitemap 代表站点地图相关的参数。
+	Sitemap SitemapArgs `json:"sitemap"`
+	// Dedup 代表基于内容的重复页面检测的参数。
+	Dedup DedupArgs `json:"dedup"`
 }
 
 func (args *RequestArgs) Check() error {
 	if args.AcceptedDomains == nil {
 		return genError("nil accepted primary domain list")
 	}
+	if err := args.Politeness.Check(); err != nil {
+		return err
+	}
+	if err := args.Robots.Check(); err != nil {
+		return err
+	}
+	if err := args.Canonical.Check(); err != nil {
+		return err
+	}
+	if err := args.SeenSet.Check(); err != nil {
+		return err
+	}
+	if err := args.Scope.Check(); err != nil {
+		return err
+	}
+	if err := args.Retry.Check(); err != nil {
+		return err
+	}
+	if err := args.Dedup.Check(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -34,6 +78,30 @@ func (args *RequestArgs) Same(another *RequestArgs) bool {
 	if another.MaxDepth != args.MaxDepth {
 		return false
 	}
+	if another.Politeness != args.Politeness {
+		return false
+	}
+	if another.Robots != args.Robots {
+		return false
+	}
+	if !another.Canonical.Same(&args.Canonical) {
+		return false
+	}
+	if another.SeenSet != args.SeenSet {
+		return false
+	}
+	if !another.Scope.Same(&args.Scope) {
+		return false
+	}
+	if another.Retry != args.Retry {
+		return false
+	}
+	if !another.Sitemap.Same(&args.Sitemap) {
+		return false
+	}
+	if another.Dedup != args.Dedup {
+		return false
+	}
 	anotherDomains := another.AcceptedDomains
 	anotherDomainsLen := len(anotherDomains)
 	if anotherDomainsLen != len(args.AcceptedDomains) {
@@ -49,12 +117,73 @@ func (args *RequestArgs) Same(another *RequestArgs) bool {
 	return true
 }
 
+// PolitenessArgs 代表礼貌性爬取相关的参数容器的类型。
+// 其中的约束都是针对单个主机的。
+type PolitenessArgs struct {
+	// MinDelay 代表相邻两次请求开始下载的最小时间间隔。
+	MinDelay time.Duration `json:"min_delay"`
+	// MaxConnsPerHost 代表同时在下载的请求的最大数量。
+	// 若为0，则不做限制。
+	MaxConnsPerHost uint32 `json:"max_conns_per_host"`
+	// BackoffBase 代表收到状态码为429或503的响应之后的初始退避时间。
+	// 之后每次连续收到此类响应都会使退避时间加倍。
+	// 若为0，则使用默认值。
+	BackoffBase time.Duration `json:"backoff_base"`
+	// BackoffMax 代表退避时间的上限。
+	// 若为0，则使用默认值。
+	BackoffMax time.Duration `json:"backoff_max"`
+}
+
+func (args *PolitenessArgs) Check() error {
+	if args.MinDelay < 0 {
+		return genError("negative min delay")
+	}
+	if args.BackoffBase < 0 {
+		return genError("negative backoff base")
+	}
+	if args.BackoffMax < 0 {
+		return genError("negative max backoff")
+	}
+	if args.BackoffBase > 0 && args.BackoffMax > 0 &&
+		args.BackoffBase > args.BackoffMax {
+		return genError("backoff base is greater than max backoff")
+	}
+	return nil
+}
+
+// RobotsArgs 代表robots.txt相关的参数容器的类型。
+type RobotsArgs struct {
+	// Obey 代表是否遵守robots.txt。
+	// 若为true，则调度器会按站点获取并缓存robots.txt，
+	// 被其禁止访问的请求都会被忽略。
+	Obey bool `json:"obey"`
+	// UserAgent 代表用于匹配robots.txt中规则组的用户代理，
+	// 同时也会被用作获取robots.txt时的User-Agent。
+	UserAgent string `json:"user_agent"`
+	// RetryInterval 代表robots.txt暂时无法获取时重新获取的时间间隔。
+	// 无法连接站点时，在此期间该站点的所有路径都被允许访问；
+	// 状态码为5xx时，在此期间该站点的所有路径都暂时被禁止访问。
+	// 若为0，则使用默认值。
+	RetryInterval time.Duration `json:"retry_interval"`
+}
+
+func (args *RobotsArgs) Check() error {
+	if args.RetryInterval < 0 {
+		return genError("negative robots.txt retry interval")
+	}
+	return nil
+}
+
 // DataArgs 代表数据相关的参数容器的类型。
 type DataArgs struct {
 	// ReqBufferCap 代表请求缓冲器的容量。
+	// 它与ReqMaxBufferNumber的乘积即为URL边界的容量。
 	ReqBufferCap uint32 `json:"req_buffer_cap"`
 	// ReqMaxBufferNumber 代表请求缓冲器的最大数量。
 	ReqMaxBufferNumber uint32 `json:"req_max_buffer_number"`
+	// Frontier 代表URL边界的类型，它决定了请求被下载的顺序。
+	// 若为空，则使用广度优先的URL边界。
+	Frontier FrontierType `json:"frontier"`
 	// RespBufferCap 代表响应缓冲器的容量。
 	RespBufferCap uint32 `json:"resp_buffer_cap"`
 	// RespMaxBufferNumber 代表响应缓冲器的最大数量。
@@ -67,6 +196,14 @@ type DataArgs struct {
 	ErrorBufferCap uint32 `json:"error_buffer_cap"`
 	// ErrorMaxBufferNumber 代表错误缓冲器的最大数量。
 	ErrorMaxBufferNumber uint32 `json:"error_max_buffer_number"`
+	// Concurrency 代表下载、分析和条目处理各阶段的工作协程的数量。
+	Concurrency ConcurrencyArgs `json:"concurrency"`
+	// SnapshotPath 代表快照文件的路径。
+	// 若不为空，则调度器在停止时也会把当前状态保存到该文件。
+	SnapshotPath string `json:"snapshot_path"`
+	// SnapshotInterval 代表周期性地生成快照的时间间隔。
+	// 若为0，则不会周期性地生成快照。
+	SnapshotInterval time.Duration `json:"snapshot_interval"`
 }
 
 func (args *DataArgs) Check() error {
@@ -76,6 +213,9 @@ func (args *DataArgs) Check() error {
 	if args.ReqMaxBufferNumber == 0 {
 		return genError("zero max request buffer number")
 	}
+	if args.Frontier != "" && !legalFrontierTypeMap[args.Frontier] {
+		return genError(fmt.Sprintf("illegal frontier type: %q", args.Frontier))
+	}
 	if args.RespBufferCap == 0 {
 		return genError("zero response buffer capacity")
 	}
@@ -94,6 +234,12 @@ func (args *DataArgs) Check() error {
 	if args.ErrorMaxBufferNumber == 0 {
 		return genError("zero max error buffer number")
 	}
+	if err := args.Concurrency.Check(); err != nil {
+		return err
+	}
+	if args.SnapshotInterval > 0 && args.SnapshotPath == "" {
+		return genError("empty snapshot path")
+	}
 	return nil
 }
 
@@ -102,6 +248,40 @@ type ModuleArgsSummary struct {
 	DownloaderListSize int `json:"downloader_list_size"`
 	AnalyzerListSize   int `json:"analyzer_list_size"`
 	PipelineListSize   int `json:"pipeline_list_size"`
+	// Selectors 代表各类组件使用的选择器的类型。
+	Selectors SelectorArgs `json:"selectors"`
+	// Health 代表组件的健康策略。
+	Health module.HealthPolicy `json:"health"`
+}
+
+// SelectorArgs 代表各类组件使用的选择器（即负载均衡策略）的参数容器的类型。
+// 其中的类型为空时使用默认的基于评分的选择器。
+type SelectorArgs struct {
+	// Downloader 代表下载器使用的选择器的类型。
+	Downloader module.SelectorType `json:"downloader"`
+	// Analyzer 代表分析器使用的选择器的类型。
+	Analyzer module.SelectorType `json:"analyzer"`
+	// Pipeline 代表条目处理管道使用的选择器的类型。
+	Pipeline module.SelectorType `json:"pipeline"`
+}
+
+func (args *SelectorArgs) Check() error {
+	for _, selectorType := range []module.SelectorType{
+		args.Downloader, args.Analyzer, args.Pipeline} {
+		if !module.LegalSelectorType(selectorType) {
+			return genError(fmt.Sprintf("illegal selector type: %q", selectorType))
+		}
+	}
+	return nil
+}
+
+// typeMap 用于获取组件类型与选择器类型的映射。
+func (args *SelectorArgs) typeMap() map[module.Type]module.SelectorType {
+	return map[module.Type]module.SelectorType{
+		module.TYPE_DOWNLOADER: args.Downloader,
+		module.TYPE_ANALYZER:   args.Analyzer,
+		module.TYPE_PIPELINE:   args.Pipeline,
+	}
 }
 
 // ModuleArgs 代表组件相关的参数容器的类型。
@@ -112,6 +292,15 @@ type ModuleArgs struct {
 	Analyzers []module.Analyzer
 	// Pipelines 代表条目处理管道管道列表。
 	Pipelines []module.Pipeline
+	// ScoreFunc 代表请求分数的计算函数。
+	// 仅在URL边界的类型为FRONTIER_TYPE_PRIORITY时有效，可以为nil。
+	// 它只用于自身未带有分数的请求，分析器可以通过请求的WithScore方法为其附加分数。
+	ScoreFunc ScoreFunc
+	// Selectors 代表各类组件使用的选择器的类型。
+	Selectors SelectorArgs
+	// Health 代表组件的健康策略，即熔断的判定条件。
+	// 其中的零值字段代表使用默认值，默认不启用熔断。
+	Health module.HealthPolicy
 }
 
 // Check 用于当前参数容器的有效性。
@@ -125,6 +314,12 @@ func (args *ModuleArgs) Check() error {
 	if len(args.Pipelines) == 0 {
 		return genError("empty pipeline list")
 	}
+	if err := args.Selectors.Check(); err != nil {
+		return err
+	}
+	if err := args.Health.Check(); err != nil {
+		return genErrorByError(err)
+	}
 	return nil
 }
 
@@ -133,5 +328,7 @@ func (args *ModuleArgs) Summary() ModuleArgsSummary {
 		DownloaderListSize: len(args.Downloaders),
 		AnalyzerListSize:   len(args.Analyzers),
 		PipelineListSize:   len(args.Pipelines),
+		Selectors:          args.Selectors,
+		Health:             args.Health,
 	}
 }
@@ -91,6 +91,9 @@ func TestArgsData(t *testing.T) {
 		dataArgsList = append(
 			dataArgsList, genDataArgsByDetail(values))
 	}
+	invalidDataArgs := genDataArgs(10, 2, 1)
+	invalidDataArgs.SnapshotInterval = time.Second
+	dataArgsList = append(dataArgsList, invalidDataArgs)
 	for _, dataArgs := range dataArgsList {
 		if err := dataArgs.Check(); err == nil {
 			t.Fatalf("No error when check data arguments! (dataArgs: %#v)",
@@ -155,11 +158,17 @@ func TestArgsModule(t *testing.T) {
 		t.Fatalf("Inconsistent module args summary: expected: %#v, actual: %#v",
 			expectedSummary, summary)
 	}
+	illegalSelectorArgs := genSimpleModuleArgs(3, 2, 1, t)
+	illegalSelectorArgs.Selectors.Analyzer = "random"
+	illegalHealthArgs := genSimpleModuleArgs(3, 2, 1, t)
+	illegalHealthArgs.Health.FailureRate = 1.5
 	moduleArgsList := []ModuleArgs{
 		genSimpleModuleArgs(0, 2, 1, t),
 		genSimpleModuleArgs(3, 0, 1, t),
 		genSimpleModuleArgs(3, 2, 0, t),
 		ModuleArgs{},
+		illegalSelectorArgs,
+		illegalHealthArgs,
 	}
 	for _, moduleArgs := range moduleArgsList {
 		if err := moduleArgs.Check(); err == nil {
@@ -278,9 +287,8 @@ func genSimplePipelines(number int8, reuseMID bool, snGen module.SNGenertor, t *
 func parseATag(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
 	//TODO: 支持更多的HTTP响应状态。
 	if httpResp.StatusCode != 200 {
-		err := fmt.Errorf(
-			fmt.Sprintf("Unsupported status code %d! (httpResponse: %v)",
-				httpResp.StatusCode, httpResp))
+		err := fmt.Errorf("Unsupported status code %d! (httpResponse: %v)",
+			httpResp.StatusCode, httpResp)
 		return nil, []error{err}
 	}
 	reqURL := httpResp.Request.URL
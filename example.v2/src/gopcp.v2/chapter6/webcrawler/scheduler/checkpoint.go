@@ -0,0 +1,323 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// Snapshot 代表调度器快照的类型。
+type Snapshot struct {
+	// Time 代表生成快照的时间。
+	Time time.Time `json:"time"`
+	// RequestArgs 代表请求相关的参数。
+	RequestArgs RequestArgs `json:"request_args"`
+	// DataArgs 代表数据相关的参数。
+	DataArgs DataArgs `json:"data_args"`
+	// AcceptedDomains 代表可以接受的URL的主域名的列表，
+	// 其中包含了根据首次请求添加的主域名。
+	// 在其他的爬取范围判定模式下，其中是主机、域名或URL前缀。
+	AcceptedDomains []string `json:"accepted_domains"`
+	// SeenURLs 代表已处理的URL的列表。
+	// 仅在已处理URL集合为精确的集合时有效。
+	SeenURLs []string `json:"seen_urls"`
+	// SeenFilter 代表编码后的布隆过滤器。
+	// 仅在已处理URL集合基于布隆过滤器时有效。
+	SeenFilter []byte `json:"seen_filter,omitempty"`
+	// Requests 代表已被接受但尚未处理完毕的请求的列表。
+	Requests []RequestSnapshot `json:"requests"`
+}
+
+// RequestSnapshot 代表请求快照的类型。
+type RequestSnapshot struct {
+	// Method 代表HTTP请求的方法。
+	Method string `json:"method"`
+	// URL 代表HTTP请求的URL。
+	URL string `json:"url"`
+	// Header 代表HTTP请求的头部。
+	Header http.Header `json:"header,omitempty"`
+	// Depth 代表请求的深度。
+	Depth uint32 `json:"depth"`
+	// Score 代表请求的分数。若为nil，则请求不带有分数。
+	Score *float64 `json:"score,omitempty"`
+}
+
+// newRequestSnapshot 用于根据给定的请求生成请求快照。
+func newRequestSnapshot(req *module.Request) (RequestSnapshot, bool) {
+	if req == nil || !req.Valid() {
+		return RequestSnapshot{}, false
+	}
+	httpReq := req.HTTPReq()
+	rs := RequestSnapshot{
+		Method: httpReq.Method,
+		URL:    httpReq.URL.String(),
+		Header: httpReq.Header,
+		Depth:  req.Depth(),
+	}
+	if score, ok := req.Score(); ok {
+		rs.Score = &score
+	}
+	return rs, true
+}
+
+// Request 用于根据请求快照还原出请求。
+func (rs RequestSnapshot) Request() (*module.Request, error) {
+	method := rs.Method
+	if method == "" {
+		method = "GET"
+	}
+	httpReq, err := http.NewRequest(method, rs.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range rs.Header {
+		httpReq.Header[k] = v
+	}
+	req := module.NewRequest(httpReq, rs.Depth)
+	if rs.Score != nil {
+		req = req.WithScore(*rs.Score)
+	}
+	return req, nil
+}
+
+func (sched *myScheduler) Checkpoint(snapshotPath string) (err error) {
+	if sched.Status() == SCHED_STATUS_UNINITIALIZED {
+		return genError("the scheduler has not yet been initialized!")
+	}
+	if snapshotPath == "" {
+		return genParameterError("empty snapshot path")
+	}
+	logger.Infof("Save snapshot to %s...", snapshotPath)
+	return saveSnapshot(sched.snapshot(), snapshotPath)
+}
+
+// snapshot 用于生成调度器当前状态的快照。
+func (sched *myScheduler) snapshot() *Snapshot {
+	snapshot := &Snapshot{
+		Time:            time.Now(),
+		RequestArgs:     sched.requestArgs,
+		DataArgs:        sched.dataArgs,
+		AcceptedDomains: []string{},
+		SeenURLs:        []string{},
+		Requests:        []RequestSnapshot{},
+	}
+	sched.scope.acceptedMap.Range(func(key string, element interface{}) bool {
+		snapshot.AcceptedDomains = append(snapshot.AcceptedDomains, key)
+		return true
+	})
+	sort.Strings(snapshot.AcceptedDomains)
+	switch urlSet := sched.urlSet.(type) {
+	case *exactSeenSet:
+		urlSet.Range(func(url string) bool {
+			snapshot.SeenURLs = append(snapshot.SeenURLs, url)
+			return true
+		})
+		sort.Strings(snapshot.SeenURLs)
+	case *bloomSeenSet:
+		b, err := urlSet.filter.MarshalBinary()
+		if err != nil {
+			logger.Errorf("An error occurs when encoding the bloom filter: %s", err)
+			break
+		}
+		snapshot.SeenFilter = b
+	}
+	sched.pendingReqMap.Range(func(key string, element interface{}) bool {
+		req, ok := element.(*module.Request)
+		if !ok {
+			return true
+		}
+		if rs, ok := newRequestSnapshot(req); ok {
+			snapshot.Requests = append(snapshot.Requests, rs)
+		}
+		return true
+	})
+	sort.Slice(snapshot.Requests, func(i, j int) bool {
+		if snapshot.Requests[i].Depth != snapshot.Requests[j].Depth {
+			return snapshot.Requests[i].Depth < snapshot.Requests[j].Depth
+		}
+		return snapshot.Requests[i].URL < snapshot.Requests[j].URL
+	})
+	return snapshot
+}
+
+// checkpointPeriodically 会按照数据相关参数中的时间间隔周期性地生成快照。
+func (sched *myScheduler) checkpointPeriodically() {
+	interval := sched.dataArgs.SnapshotInterval
+	path := sched.dataArgs.SnapshotPath
+	if interval <= 0 || path == "" {
+		return
+	}
+	logger.Infof("-- Snapshot: path: %s, interval: %s", path, interval)
+	go func(ctxDone <-chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctxDone:
+				return
+			case <-ticker.C:
+				if err := saveSnapshot(sched.snapshot(), path); err != nil {
+					logger.Errorf("An error occurs when saving snapshot: %s (path: %s)",
+						err, path)
+				}
+			}
+		}
+	}(sched.ctx.Done())
+}
+
+// saveSnapshot 用于把快照保存到指定的文件。
+// 快照会先被写入同一目录下的临时文件，然后再替换目标文件，
+// 以免在写入过程中崩溃而损坏已有的快照。
+func saveSnapshot(snapshot *Snapshot, path string) error {
+	if snapshot == nil {
+		return genParameterError("nil snapshot")
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return genError(fmt.Sprintf("couldn't encode snapshot: %s", err))
+	}
+	dir := filepath.Dir(path)
+	tmpFile, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return genError(fmt.Sprintf("couldn't create snapshot file: %s", err))
+	}
+	tmpPath := tmpFile.Name()
+	if _, err = tmpFile.Write(b); err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return genError(fmt.Sprintf("couldn't write snapshot file: %s", err))
+	}
+	return nil
+}
+
+// LoadSnapshot 用于从指定的文件加载快照。
+func LoadSnapshot(snapshotPath string) (*Snapshot, error) {
+	b, err := ioutil.ReadFile(snapshotPath)
+	if err != nil {
+		return nil, genError(fmt.Sprintf("couldn't read snapshot file: %s", err))
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(b, snapshot); err != nil {
+		return nil, genError(fmt.Sprintf("couldn't decode snapshot: %s", err))
+	}
+	return snapshot, nil
+}
+
+// Resume 会根据快照文件创建调度器并恢复之前的爬取流程。
+// 快照中的参数会被用于初始化调度器，已处理过的URL不会被再次爬取。
+// 参数snapshotPath代表快照文件的路径。
+// 参数moduleArgs代表组件相关的参数。
+func Resume(snapshotPath string, moduleArgs ModuleArgs) (Scheduler, error) {
+	snapshot, err := LoadSnapshot(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	sched := &myScheduler{}
+	if err := sched.Init(
+		snapshot.RequestArgs, snapshot.DataArgs, moduleArgs); err != nil {
+		return nil, err
+	}
+	if err := sched.resume(snapshot); err != nil {
+		return nil, err
+	}
+	return sched, nil
+}
+
+// RestoreFromSnapshot 是Resume函数的别名。
+func RestoreFromSnapshot(snapshotPath string, moduleArgs ModuleArgs) (Scheduler, error) {
+	return Resume(snapshotPath, moduleArgs)
+}
+
+// resume 用于根据快照恢复调度器的状态并启动调度器。
+func (sched *myScheduler) resume(snapshot *Snapshot) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			errMsg := fmt.Sprintf("Fatal scheduler error: %s", p)
+			logger.Fatal(errMsg)
+			err = genError(errMsg)
+		}
+	}()
+	logger.Info("Restore scheduler from snapshot...")
+	var oldStatus Status
+	oldStatus, err =
+		sched.checkAndSetStatus(SCHED_STATUS_STARTING)
+	defer func() {
+		sched.statusLock.Lock()
+		if err != nil {
+			sched.status = oldStatus
+		} else {
+			sched.status = SCHED_STATUS_STARTED
+		}
+		sched.statusLock.Unlock()
+	}()
+	if err != nil {
+		return
+	}
+	if snapshot == nil {
+		err = genParameterError("nil snapshot")
+		return
+	}
+	var reqs []*module.Request
+	for _, rs := range snapshot.Requests {
+		req, reqErr := rs.Request()
+		if reqErr != nil {
+			logger.Warnf("Ignore the request in snapshot! %s (URL: %s)", reqErr, rs.URL)
+			continue
+		}
+		reqs = append(reqs, req)
+	}
+	for _, domain := range snapshot.AcceptedDomains {
+		sched.scope.accept(domain)
+	}
+	if len(snapshot.SeenFilter) > 0 {
+		urlSet, ok := sched.urlSet.(*bloomSeenSet)
+		if !ok {
+			err = genError(fmt.Sprintf("couldn't restore the bloom filter to a seen set of type %q",
+				sched.urlSet.Type()))
+			return
+		}
+		if err = urlSet.filter.UnmarshalBinary(snapshot.SeenFilter); err != nil {
+			err = genError(fmt.Sprintf("couldn't decode the bloom filter: %s", err))
+			return
+		}
+	}
+	for _, u := range snapshot.SeenURLs {
+		sched.urlSet.Put(u)
+	}
+	logger.Infof("-- Restored %d seen URL(s) and %d pending request(s) from the snapshot taken at %s.",
+		sched.urlSet.Len(), len(reqs), snapshot.Time)
+	if err = sched.checkBufferPoolForStart(); err != nil {
+		return
+	}
+	sched.download()
+	sched.analyze()
+	sched.pick()
+	sched.checkpointPeriodically()
+	logger.Info("Scheduler has been resumed.")
+	for _, req := range reqs {
+		urlKey := sched.urlKey(req.HTTPReq().URL)
+		sched.urlSet.Put(urlKey)
+		sched.pendingReqMap.Put(urlKey, req)
+		go func(req *module.Request) {
+			if err := sched.frontier.Put(req); err != nil {
+				logger.Warnln("The frontier was closed. Ignore request sending.")
+			}
+		}(req)
+	}
+	return nil
+}
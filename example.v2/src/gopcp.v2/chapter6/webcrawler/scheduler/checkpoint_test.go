@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// pageServer 代表测试用的网页服务器。
+type pageServer struct {
+	*httptest.Server
+	// pages 代表路径与其中链接的路径列表的映射。
+	pages map[string][]string
+	// hits 代表各路径被访问的次数。
+	hits map[string]int
+	// robots 代表robots.txt的内容。若为空，则robots.txt不存在。
+	robots string
+	lock   sync.Mutex
+}
+
+// newPageServer 用于创建一个测试用的网页服务器。
+func newPageServer(pages map[string][]string) *pageServer {
+	ps := &pageServer{
+		pages: pages,
+		hits:  map[string]int{},
+	}
+	ps.Server = httptest.NewServer(http.HandlerFunc(ps.serve))
+	return ps
+}
+
+func (ps *pageServer) serve(w http.ResponseWriter, r *http.Request) {
+	ps.lock.Lock()
+	ps.hits[r.URL.Path]++
+	ps.lock.Unlock()
+	if r.URL.Path == "/robots.txt" && ps.robots != "" {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, ps.robots)
+		return
+	}
+	links, ok := ps.pages[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, "<html><body>")
+	for _, link := range links {
+		fmt.Fprintf(w, `<a href="%s">%s</a>`, link, link)
+	}
+	fmt.Fprint(w, "</body></html>")
+}
+
+// Hits 用于获取指定路径被访问的次数。
+func (ps *pageServer) Hits(path string) int {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	return ps.hits[path]
+}
+
+// Host 用于获取服务器的主机名（包含端口）。
+func (ps *pageServer) Host() string {
+	u, _ := url.Parse(ps.URL)
+	return u.Host
+}
+
+// waitFor 会等待条件满足，直至超时。
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestCheckpointSnapshot(t *testing.T) {
+	requestArgs := genRequestArgs([]string{"bing.com"}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := sched.Checkpoint(path); err == nil {
+		t.Fatal("No error when checkpoint an uninitialized scheduler!")
+	}
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+	urls := []string{
+		"http://cn.bing.com/search?q=golang",
+		"http://cn.bing.com/images/search?q=golang",
+	}
+	for i, u := range urls {
+		httpReq, _ := http.NewRequest("GET", u, nil)
+		httpReq.Header.Set("User-Agent", "test-agent")
+		if !mySched.sendReq(module.NewRequest(httpReq, uint32(i))) {
+			t.Fatalf("Couldn't send request! (URL: %s)", u)
+		}
+	}
+	if err := sched.Checkpoint(""); err == nil {
+		t.Fatal("No error when checkpoint with empty path!")
+	}
+	if err := sched.Checkpoint(path); err != nil {
+		t.Fatalf("An error occurs when saving snapshot: %s", err)
+	}
+	snapshot, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("An error occurs when loading snapshot: %s", err)
+	}
+	if !snapshot.RequestArgs.Same(&requestArgs) {
+		t.Fatalf("Inconsistent request arguments: expected: %#v, actual: %#v",
+			requestArgs, snapshot.RequestArgs)
+	}
+	if snapshot.DataArgs != dataArgs {
+		t.Fatalf("Inconsistent data arguments: expected: %#v, actual: %#v",
+			dataArgs, snapshot.DataArgs)
+	}
+	if len(snapshot.SeenURLs) != len(urls) {
+		t.Fatalf("Inconsistent seen URL number: expected: %d, actual: %d",
+			len(urls), len(snapshot.SeenURLs))
+	}
+	if len(snapshot.Requests) != len(urls) {
+		t.Fatalf("Inconsistent request number: expected: %d, actual: %d",
+			len(urls), len(snapshot.Requests))
+	}
+	for i, rs := range snapshot.Requests {
+		if rs.URL != urls[i] || rs.Depth != uint32(i) {
+			t.Fatalf("Inconsistent request snapshot: expected: %s (depth: %d), actual: %s (depth: %d)",
+				urls[i], i, rs.URL, rs.Depth)
+		}
+		req, err := rs.Request()
+		if err != nil {
+			t.Fatalf("An error occurs when restoring request: %s", err)
+		}
+		if ua := req.HTTPReq().Header.Get("User-Agent"); ua != "test-agent" {
+			t.Fatalf("Inconsistent request header: expected: %s, actual: %s",
+				"test-agent", ua)
+		}
+	}
+	if _, err := LoadSnapshot(path + ".missing"); err == nil {
+		t.Fatal("No error when loading a missing snapshot!")
+	}
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	server := newPageServer(map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/"},
+		"/b": {"/a", "/c"},
+		"/c": {},
+	})
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	snapshot := &Snapshot{
+		Time:            time.Now(),
+		RequestArgs:     genRequestArgs([]string{server.Host()}, 3),
+		DataArgs:        genDataArgs(10, 2, 1),
+		AcceptedDomains: []string{server.Host()},
+		SeenURLs: []string{
+			server.URL + "/",
+			server.URL + "/a",
+			server.URL + "/b",
+		},
+		Requests: []RequestSnapshot{
+			{Method: "GET", URL: server.URL + "/b", Depth: 1},
+		},
+	}
+	if err := saveSnapshot(snapshot, path); err != nil {
+		t.Fatalf("An error occurs when saving snapshot: %s", err)
+	}
+	sched, err := Resume(path, genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+	defer sched.Stop()
+	if sched.Status() != SCHED_STATUS_STARTED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STARTED),
+			GetStatusDescription(sched.Status()))
+	}
+	if !waitFor(5*time.Second, func() bool { return server.Hits("/c") > 0 }) {
+		t.Fatalf("The pending request in snapshot has not been resumed!")
+	}
+	waitFor(5*time.Second, sched.Idle)
+	for _, p := range []string{"/", "/a"} {
+		if hits := server.Hits(p); hits != 0 {
+			t.Fatalf("The seen URL has been fetched again! (path: %s, hits: %d)",
+				p, hits)
+		}
+	}
+	if hits := server.Hits("/b"); hits != 1 {
+		t.Fatalf("Inconsistent hits: expected: %d, actual: %d (path: %s)",
+			1, hits, "/b")
+	}
+	if _, err := RestoreFromSnapshot(path+".missing", genSimpleModuleArgs(1, 1, 1, t)); err == nil {
+		t.Fatal("No error when resuming with a missing snapshot!")
+	}
+}
@@ -0,0 +1,328 @@
+package scheduler
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/simhash"
+)
+
+// 内容去重的默认参数和限制。
+const (
+	// defaultDedupMaxBodyBytes 代表默认的参与去重的响应体的最大字节数。
+	defaultDedupMaxBodyBytes = 4 << 20
+	// defaultDedupMaxEntries 代表默认的索引中最多保留的页面的数量。
+	defaultDedupMaxEntries = 100000
+	// maxDedupDistance 代表近似重复判定所允许的最大海明距离的上限。
+	maxDedupDistance = 15
+)
+
+// DedupArgs 代表基于内容的重复页面检测相关的参数容器的类型。
+type DedupArgs struct {
+	// Enabled 代表是否启用重复页面检测。
+	// 若为true，则分析器会在读取响应体之后计算状态码为2xx的HTML响应中
+	// 规范化文本的精确哈希值和SimHash指纹。
+	// 内容重复的响应仍会被分析并产出条目，但其中的链接不会被跟进。
+	Enabled bool `json:"enabled"`
+	// MaxDistance 代表判定为近似重复时两个SimHash指纹之间的最大海明距离。
+	// 若为0，则只检测内容完全相同的页面。
+	MaxDistance uint8 `json:"max_distance"`
+	// MaxBodyBytes 代表参与检测的响应体的最大字节数。
+	// 更大的响应体不会参与检测。若为0，则使用默认值。
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+	// MaxEntries 代表索引中最多保留的规范页面的数量。
+	// 超出时会淘汰最久未被匹配的页面。若为0，则使用默认值。
+	MaxEntries uint32 `json:"max_entries"`
+}
+
+func (args *DedupArgs) Check() error {
+	if args.MaxDistance > maxDedupDistance {
+		return genError(fmt.Sprintf("too large dedup distance: %d (max: %d)",
+			args.MaxDistance, maxDedupDistance))
+	}
+	if args.MaxBodyBytes < 0 {
+		return genError(fmt.Sprintf("illegal dedup max body bytes: %d",
+			args.MaxBodyBytes))
+	}
+	return nil
+}
+
+// DuplicateClusterStruct 代表重复页面的簇的类型。
+type DuplicateClusterStruct struct {
+	// Canonical 代表簇中首个被处理的页面的URL，即规范URL。
+	Canonical string `json:"canonical"`
+	// Aliases 代表与规范URL的内容重复的页面的URL的列表。
+	Aliases []string `json:"aliases"`
+}
+
+// DuplicateIndex 代表重复页面索引的接口类型。
+type DuplicateIndex interface {
+	// Canonical 用于获取给定URL所属的簇的规范URL。
+	// 若该URL是重复页面，则第二个结果值为true。
+	Canonical(url string) (string, bool)
+	// Aliases 用于获取与给定的规范URL的内容重复的页面的URL的列表。
+	Aliases(canonical string) []string
+	// Clusters 用于获取所有包含重复页面的簇，簇的顺序与其规范URL的处理顺序一致。
+	Clusters() []DuplicateClusterStruct
+	// Len 用于获取已发现的重复页面的数量。
+	Len() uint64
+}
+
+// simEntry 代表索引中的规范页面的条目。
+type simEntry struct {
+	// hash 代表页面的精确哈希值。
+	hash string
+	// fingerprint 代表页面的SimHash指纹。
+	fingerprint uint64
+	// canonical 代表页面的URL。
+	canonical string
+}
+
+// myDuplicateIndex 代表重复页面索引的实现类型。
+type myDuplicateIndex struct {
+	// maxDistance 代表判定为近似重复时的最大海明距离。
+	maxDistance int
+	// maxEntries 代表最多保留的规范页面的数量。
+	maxEntries int
+	// entries 代表按最近被匹配的顺序排列的规范页面的条目的列表，
+	// 其中元素的值的类型为*simEntry。
+	entries *list.List
+	// exact 代表从精确哈希值到条目的映射。
+	exact map[string]*list.Element
+	// bands 代表按指纹的分段建立的SimHash索引。
+	// 根据抽屉原理，海明距离不超过maxDistance的两个指纹
+	// 在maxDistance+1个分段中至少有一段是完全相同的。
+	bands []map[uint64][]*list.Element
+	// canonicalMap 代表从重复页面的URL到规范URL的映射。
+	canonicalMap map[string]string
+	// aliasMap 代表从规范URL到重复页面的URL的列表的映射。
+	aliasMap map[string][]string
+	// canonicals 代表包含重复页面的簇的规范URL的列表。
+	canonicals []string
+	// number 代表重复页面的数量。
+	number uint64
+	lock   sync.RWMutex
+}
+
+// newDuplicateIndex 用于创建一个重复页面索引。
+// 参数maxEntries代表最多保留的规范页面的数量，为0时使用默认值。
+func newDuplicateIndex(maxDistance uint8, maxEntries uint32) *myDuplicateIndex {
+	if maxEntries == 0 {
+		maxEntries = defaultDedupMaxEntries
+	}
+	bands := make([]map[uint64][]*list.Element, int(maxDistance)+1)
+	for i := range bands {
+		bands[i] = map[uint64][]*list.Element{}
+	}
+	return &myDuplicateIndex{
+		maxDistance:  int(maxDistance),
+		maxEntries:   int(maxEntries),
+		entries:      list.New(),
+		exact:        map[string]*list.Element{},
+		bands:        bands,
+		canonicalMap: map[string]string{},
+		aliasMap:     map[string][]string{},
+	}
+}
+
+// band 用于获取指纹的第i个分段的值。
+func (index *myDuplicateIndex) band(fingerprint uint64, i int) uint64 {
+	n := len(index.bands)
+	start := 64 * i / n
+	end := 64 * (i + 1) / n
+	return (fingerprint >> uint(start)) & (1<<uint(end-start) - 1)
+}
+
+// check 用于检查给定页面的内容是否与已处理的页面重复。
+// 若重复，则会把该页面记录为相应簇的成员，并返回规范URL和true。
+// 否则会把该页面记录为新的规范页面，超出数量限制时会淘汰最久未被匹配的规范页面。
+func (index *myDuplicateIndex) check(
+	url string, text string) (canonical string, dup bool) {
+	hash := simhash.ExactHash(text)
+	fingerprint := simhash.Fingerprint(text)
+	index.lock.Lock()
+	defer index.lock.Unlock()
+	if canonical, ok := index.canonicalMap[url]; ok {
+		return canonical, true
+	}
+	elem, ok := index.exact[hash]
+	if !ok && index.maxDistance > 0 {
+		elem, ok = index.near(fingerprint)
+	}
+	if ok {
+		index.entries.MoveToFront(elem)
+		canonical := elem.Value.(*simEntry).canonical
+		if canonical == url {
+			return "", false
+		}
+		index.addAlias(canonical, url)
+		return canonical, true
+	}
+	elem = index.entries.PushFront(&simEntry{
+		hash:        hash,
+		fingerprint: fingerprint,
+		canonical:   url,
+	})
+	index.exact[hash] = elem
+	if index.maxDistance > 0 {
+		for i, band := range index.bands {
+			key := index.band(fingerprint, i)
+			band[key] = append(band[key], elem)
+		}
+	}
+	if index.entries.Len() > index.maxEntries {
+		index.evict(index.entries.Back())
+	}
+	return "", false
+}
+
+// near 用于查找与给定指纹近似的规范页面的条目。
+func (index *myDuplicateIndex) near(fingerprint uint64) (*list.Element, bool) {
+	for i, band := range index.bands {
+		for _, elem := range band[index.band(fingerprint, i)] {
+			entry := elem.Value.(*simEntry)
+			if simhash.Distance(entry.fingerprint, fingerprint) <= index.maxDistance {
+				return elem, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// evict 用于从索引中淘汰给定的规范页面的条目。
+// 已记录的重复页面的簇不受影响。
+func (index *myDuplicateIndex) evict(elem *list.Element) {
+	entry := index.entries.Remove(elem).(*simEntry)
+	if index.exact[entry.hash] == elem {
+		delete(index.exact, entry.hash)
+	}
+	if index.maxDistance == 0 {
+		return
+	}
+	for i, band := range index.bands {
+		key := index.band(entry.fingerprint, i)
+		elems := band[key]
+		for j, e := range elems {
+			if e == elem {
+				elems = append(elems[:j], elems[j+1:]...)
+				break
+			}
+		}
+		if len(elems) == 0 {
+			delete(band, key)
+		} else {
+			band[key] = elems
+		}
+	}
+}
+
+// addAlias 用于把重复页面的URL加入规范URL所在的簇。
+func (index *myDuplicateIndex) addAlias(canonical string, url string) {
+	if _, ok := index.aliasMap[canonical]; !ok {
+		index.canonicals = append(index.canonicals, canonical)
+	}
+	index.aliasMap[canonical] = append(index.aliasMap[canonical], url)
+	index.canonicalMap[url] = canonical
+	index.number++
+}
+
+func (index *myDuplicateIndex) Canonical(url string) (string, bool) {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+	canonical, ok := index.canonicalMap[url]
+	return canonical, ok
+}
+
+func (index *myDuplicateIndex) Aliases(canonical string) []string {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+	aliases := index.aliasMap[canonical]
+	if len(aliases) == 0 {
+		return nil
+	}
+	return append([]string(nil), aliases...)
+}
+
+func (index *myDuplicateIndex) Clusters() []DuplicateClusterStruct {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+	clusters := make([]DuplicateClusterStruct, 0, len(index.canonicals))
+	for _, canonical := range index.canonicals {
+		clusters = append(clusters, DuplicateClusterStruct{
+			Canonical: canonical,
+			Aliases:   append([]string(nil), index.aliasMap[canonical]...),
+		})
+	}
+	return clusters
+}
+
+func (index *myDuplicateIndex) Len() uint64 {
+	index.lock.RLock()
+	defer index.lock.RUnlock()
+	return index.number
+}
+
+func (sched *myScheduler) Duplicates() DuplicateIndex {
+	if sched.duplicates == nil {
+		return newDuplicateIndex(0, 0)
+	}
+	return sched.duplicates
+}
+
+// duplicateCheck 用于生成检查给定响应的内容是否重复的函数，
+// 它会由分析器在读取响应体之后调用。
+// 若未启用重复页面检测，则结果值为nil。
+// 参数dup会在检查之后被设置为该响应是否重复。
+func (sched *myScheduler) duplicateCheck(dup *bool) module.CheckDuplicate {
+	if !sched.requestArgs.Dedup.Enabled {
+		return nil
+	}
+	return func(httpResp *http.Response) bool {
+		*dup = sched.checkDuplicate(httpResp)
+		return *dup
+	}
+}
+
+// checkDuplicate 用于检查响应的内容是否与已分析的响应重复。
+// 只有状态码为2xx的HTML响应会参与检查。
+// 响应体会被读取，但不会被关闭。
+func (sched *myScheduler) checkDuplicate(httpResp *http.Response) bool {
+	if httpResp == nil || httpResp.Body == nil || httpResp.Request == nil {
+		return false
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return false
+	}
+	if !isHTMLMediaType(httpResp.Header.Get("Content-Type")) {
+		return false
+	}
+	maxBodyBytes := sched.requestArgs.Dedup.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = defaultDedupMaxBodyBytes
+	}
+	body := &io.LimitedReader{R: httpResp.Body, N: maxBodyBytes + 1}
+	text, err := simhash.NormalizeHTML(body)
+	if err != nil || body.N == 0 || text == "" {
+		return false
+	}
+	url := httpResp.Request.URL.String()
+	canonical, dup := sched.duplicates.check(url, text)
+	if dup {
+		logger.Infof("Ignore the links in duplicate page %s (canonical: %s).", url, canonical)
+	}
+	return dup
+}
+
+// isHTMLMediaType 用于判断给定的内容类型是否为HTML。
+func isHTMLMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
@@ -0,0 +1,246 @@
+package scheduler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testingArticle 代表测试用的页面正文。
+const testingArticle = `Go is an open source programming language that makes it
+simple to build secure, scalable systems. It was designed at Google to improve
+programming productivity in an era of multicore, networked machines and large
+codebases. The designers wanted to address criticism of other languages in use
+while keeping their useful characteristics: static typing and run-time
+efficiency, readability and usability, and high-performance networking and
+multiprocessing.`
+
+func TestDedupArgs(t *testing.T) {
+	illegalArgs := []DedupArgs{
+		{Enabled: true, MaxDistance: maxDedupDistance + 1},
+		{Enabled: true, MaxBodyBytes: -1},
+	}
+	for _, args := range illegalArgs {
+		if err := args.Check(); err == nil {
+			t.Fatalf("No error when checking illegal dedup args: %+v", args)
+		}
+	}
+	args := DedupArgs{Enabled: true, MaxDistance: 3}
+	if err := args.Check(); err != nil {
+		t.Fatalf("An error occurs when checking dedup args: %s (args: %+v)", err, args)
+	}
+}
+
+func TestDuplicateIndex(t *testing.T) {
+	article := strings.Join(strings.Fields(strings.ToLower(testingArticle)), " ")
+	similar := strings.Replace(article, "large codebases", "huge codebases", 1)
+	index := newDuplicateIndex(8, 0)
+	if _, dup := index.check("http://a.com/1", article); dup {
+		t.Fatal("The first page is regarded as a duplicate!")
+	}
+	if _, dup := index.check("http://a.com/1", article); dup {
+		t.Fatal("The canonical page is regarded as a duplicate of itself!")
+	}
+	if _, dup := index.check("http://a.com/other", "something completely different"); dup {
+		t.Fatal("A different page is regarded as a duplicate!")
+	}
+	for _, url := range []string{"http://a.com/2", "http://a.com/3"} {
+		canonical, dup := index.check(url, article)
+		if !dup || canonical != "http://a.com/1" {
+			t.Fatalf("Inconsistent exact duplicate check result: %q, %v (url: %s)",
+				canonical, dup, url)
+		}
+	}
+	canonical, dup := index.check("http://a.com/4", similar)
+	if !dup || canonical != "http://a.com/1" {
+		t.Fatalf("Inconsistent near duplicate check result: %q, %v", canonical, dup)
+	}
+	exactIndex := newDuplicateIndex(0, 0)
+	exactIndex.check("http://a.com/1", article)
+	if _, dup := exactIndex.check("http://a.com/4", similar); dup {
+		t.Fatal("A near duplicate is regarded as an exact duplicate!")
+	}
+	if index.Len() != 3 {
+		t.Fatalf("Inconsistent duplicate number: expected: %d, actual: %d", 3, index.Len())
+	}
+	if canonical, ok := index.Canonical("http://a.com/3"); !ok || canonical != "http://a.com/1" {
+		t.Fatalf("Inconsistent canonical URL: %q, %v", canonical, ok)
+	}
+	if _, ok := index.Canonical("http://a.com/1"); ok {
+		t.Fatal("The canonical page is regarded as a duplicate!")
+	}
+	expectedAliases := []string{"http://a.com/2", "http://a.com/3", "http://a.com/4"}
+	aliases := index.Aliases("http://a.com/1")
+	if fmt.Sprint(aliases) != fmt.Sprint(expectedAliases) {
+		t.Fatalf("Inconsistent aliases: expected: %v, actual: %v", expectedAliases, aliases)
+	}
+	if aliases := index.Aliases("http://a.com/other"); aliases != nil {
+		t.Fatalf("Non-nil aliases of a page without duplicates: %v", aliases)
+	}
+	clusters := index.Clusters()
+	if len(clusters) != 1 || clusters[0].Canonical != "http://a.com/1" ||
+		fmt.Sprint(clusters[0].Aliases) != fmt.Sprint(expectedAliases) {
+		t.Fatalf("Inconsistent duplicate clusters: %+v", clusters)
+	}
+}
+
+func TestDuplicateIndexEviction(t *testing.T) {
+	article := strings.Join(strings.Fields(strings.ToLower(testingArticle)), " ")
+	index := newDuplicateIndex(3, 2)
+	index.check("http://a.com/1", article)
+	index.check("http://a.com/2", "something completely different")
+	// 匹配会使规范页面成为最近被使用的页面。
+	index.check("http://a.com/1-alias", article)
+	index.check("http://a.com/3", "yet another page with other words")
+	if index.entries.Len() != 2 {
+		t.Fatalf("Inconsistent entry number: expected: %d, actual: %d",
+			2, index.entries.Len())
+	}
+	if canonical, dup := index.check("http://a.com/1-alias2", article); !dup ||
+		canonical != "http://a.com/1" {
+		t.Fatalf("The recently matched page has been evicted! (%q, %v)", canonical, dup)
+	}
+	if _, dup := index.check("http://a.com/2-alias", "something completely different"); dup {
+		t.Fatal("The evicted page is still in the index!")
+	}
+	for i, band := range index.bands {
+		for key, elems := range band {
+			for _, elem := range elems {
+				if elem.Value.(*simEntry).canonical == "http://a.com/3" {
+					t.Fatalf("The evicted page is still in band %d (key: %d)!", i, key)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckDuplicate(t *testing.T) {
+	sched := &myScheduler{
+		requestArgs: RequestArgs{Dedup: DedupArgs{Enabled: true}},
+		duplicates:  newDuplicateIndex(0, 0),
+	}
+	body := "<html><body><p>" + testingArticle + "</p></body></html>"
+	newResp := func(path string, statusCode int, contentType string) *http.Response {
+		httpReq, _ := http.NewRequest("GET", "http://a.com"+path, nil)
+		return &http.Response{
+			StatusCode: statusCode,
+			Header:     http.Header{"Content-Type": []string{contentType}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    httpReq,
+		}
+	}
+	for _, resp := range []*http.Response{
+		newResp("/missing", http.StatusNotFound, "text/html"),
+		newResp("/plain", http.StatusOK, "text/plain"),
+		newResp("/unknown", http.StatusOK, ""),
+	} {
+		if sched.checkDuplicate(resp) {
+			t.Fatalf("The response has been regarded as a duplicate! (URL: %s)",
+				resp.Request.URL)
+		}
+	}
+	if sched.duplicates.entries.Len() != 0 {
+		t.Fatalf("Non-HTML or non-2xx responses have been fingerprinted! (number: %d)",
+			sched.duplicates.entries.Len())
+	}
+	if sched.checkDuplicate(newResp("/1", http.StatusOK, "text/html; charset=utf-8")) {
+		t.Fatal("The first page is regarded as a duplicate!")
+	}
+	if !sched.checkDuplicate(newResp("/2", http.StatusOK, "application/xhtml+xml")) {
+		t.Fatal("The duplicate page has not been detected!")
+	}
+	sched.requestArgs.Dedup.MaxBodyBytes = 10
+	if sched.checkDuplicate(newResp("/3", http.StatusOK, "text/html")) {
+		t.Fatal("The too large page has been checked!")
+	}
+}
+
+func TestSchedDedup(t *testing.T) {
+	var lock sync.Mutex
+	hits := map[string]int{}
+	pages := map[string]string{
+		"/":  `<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a><a href="/d">d</a>`,
+		"/a": "<p>" + testingArticle + `</p><a href="/a1">next</a>`,
+		"/b": "<div>" + strings.ToUpper(testingArticle) + `</div><a href="/b1">next</a>`,
+		"/c": "<p>" + strings.Replace(testingArticle, "large\ncodebases", "huge codebases", 1) +
+			`</p><a href="/c1">next</a>`,
+		"/d":  `<p>The quick brown fox jumps over the lazy dog.</p><a href="/d1">next</a>`,
+		"/a1": "a1", "/b1": "b1", "/c1": "c1", "/d1": "d1",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		hits[r.URL.Path]++
+		lock.Unlock()
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><body>%s</body></html>", page)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	requestArgs := genRequestArgs([]string{host}, 2)
+	requestArgs.Dedup = DedupArgs{Enabled: true, MaxDistance: 8}
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1),
+		genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	hitsOf := func(path string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return hits[path]
+	}
+	ok := waitFor(10*time.Second, func() bool {
+		return hitsOf("/d1") > 0 && sched.Idle()
+	})
+	if !ok {
+		t.Fatal("The pages have not been crawled!")
+	}
+	// 重复页面中的条目仍会被处理：首页中有4个链接，其余4个页面中各有1个。
+	var items uint64
+	for _, pipeline := range sched.Summary().Struct().Pipelines {
+		items += pipeline.Called
+	}
+	if items != 8 {
+		t.Fatalf("Inconsistent item number: expected: %d, actual: %d", 8, items)
+	}
+	// 只有规范页面中的链接会被跟进。
+	followed := hitsOf("/a1") + hitsOf("/b1") + hitsOf("/c1")
+	if followed != 1 {
+		t.Fatalf("Inconsistent number of followed links in duplicate pages: expected: %d, actual: %d",
+			1, followed)
+	}
+	clusters := sched.Duplicates().Clusters()
+	if len(clusters) != 1 || len(clusters[0].Aliases) != 2 {
+		t.Fatalf("Inconsistent duplicate clusters: %+v", clusters)
+	}
+	cluster := clusters[0]
+	followedPath := strings.TrimPrefix(cluster.Canonical, server.URL) + "1"
+	if hitsOf(followedPath) != 1 {
+		t.Fatalf("The links in canonical page %s have not been followed!", cluster.Canonical)
+	}
+	for _, alias := range cluster.Aliases {
+		if canonical, ok := sched.Duplicates().Canonical(alias); !ok || canonical != cluster.Canonical {
+			t.Fatalf("Inconsistent canonical URL of %s: %q", alias, canonical)
+		}
+	}
+	if _, ok := sched.Duplicates().Canonical(server.URL + "/d"); ok {
+		t.Fatal("A different page is regarded as a duplicate!")
+	}
+	if number := sched.Summary().Struct().NumDuplicates; number != 2 {
+		t.Fatalf("Inconsistent duplicate number: expected: %d, actual: %d", 2, number)
+	}
+}
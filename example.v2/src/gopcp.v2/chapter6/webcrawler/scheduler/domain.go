@@ -1,34 +1,20 @@
 package scheduler
 
 import (
+	"fmt"
+	"net"
 	"regexp"
 	"strings"
+
+	"golang.org/x/net/publicsuffix"
 )
 
 var regexpForIP = regexp.MustCompile(`((?:(?:25[0-5]|2[0-4]\d|[01]?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|[01]?\d?\d))`)
 
-var regexpForDomains = []*regexp.Regexp{
-	// *.xx or *.xxx.xx
-	regexp.MustCompile(`\.(com|com\.\w{2})$`),
-	regexp.MustCompile(`\.(gov|gov\.\w{2})$`),
-	regexp.MustCompile(`\.(net|net\.\w{2})$`),
-	regexp.MustCompile(`\.(org|org\.\w{2})$`),
-	// *.xx
-	regexp.MustCompile(`\.me$`),
-	regexp.MustCompile(`\.biz$`),
-	regexp.MustCompile(`\.info$`),
-	regexp.MustCompile(`\.name$`),
-	regexp.MustCompile(`\.mobi$`),
-	regexp.MustCompile(`\.so$`),
-	regexp.MustCompile(`\.asia$`),
-	regexp.MustCompile(`\.tel$`),
-	regexp.MustCompile(`\.tv$`),
-	regexp.MustCompile(`\.cc$`),
-	regexp.MustCompile(`\.co$`),
-	regexp.MustCompile(`\.\w{2}$`),
-}
-
 // getPrimaryDomain 用于获取给定主机名的主域名。
+// 主域名即可注册域名，它由公共后缀列表中的后缀加上其左侧的一级组成，
+// 例如“www.example.co.uk”的主域名为“example.co.uk”。
+// 若主机名为IP地址，则原样返回。
 func getPrimaryDomain(host string) (string, error) {
 	host = strings.TrimSpace(host)
 	if host == "" {
@@ -37,24 +23,22 @@ func getPrimaryDomain(host string) (string, error) {
 	if regexpForIP.MatchString(host) {
 		return host, nil
 	}
-	var suffixIndex int
-	for _, re := range regexpForDomains {
-		pos := re.FindStringIndex(host)
-		if pos != nil {
-			suffixIndex = pos[0]
-			break
-		}
+	hostname := hostnameOf(host)
+	suffix, icann := publicsuffix.PublicSuffix(hostname)
+	if !icann && !strings.Contains(suffix, ".") {
+		return "", genError(fmt.Sprintf("unrecognized host %q", host))
 	}
-	if suffixIndex > 0 {
-		var pdIndex int
-		firstPart := host[:suffixIndex]
-		index := strings.LastIndex(firstPart, ".")
-		if index < 0 {
-			pdIndex = 0
-		} else {
-			pdIndex = index + 1
-		}
-		return host[pdIndex:], nil
+	pd, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		return "", genError(fmt.Sprintf("unrecognized host %q: %s", host, err))
+	}
+	return pd, nil
+}
+
+// hostnameOf 用于获取去掉端口和末尾的“.”之后的小写主机名。
+func hostnameOf(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
 	}
-	return "", genError("unrecognized host")
+	return strings.TrimSuffix(strings.ToLower(host), ".")
 }
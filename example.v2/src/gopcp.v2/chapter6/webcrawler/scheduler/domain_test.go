@@ -24,13 +24,37 @@ func TestGetPrimaryDomain(t *testing.T) {
 		t.Fatalf("Inconsistent primary domain: expected: %s, actual: %s",
 			expectedPD, pd)
 	}
+	hostPDs := map[string]string{
+		"www.example.co.uk":  "example.co.uk",
+		"a.b.example.com.cn": "example.com.cn",
+		"news.example.app":   "example.app",
+		"CN.Bing.com:8080":   "bing.com",
+		"www.bing.net":       "bing.net",
+		"example.com.":       "example.com",
+	}
+	for host, expectedPD := range hostPDs {
+		pd, err = getPrimaryDomain(host)
+		if err != nil {
+			t.Fatalf("An error occurs when getting primary domain: %s (host: %s)",
+				err, host)
+		}
+		if pd != expectedPD {
+			t.Fatalf("Inconsistent primary domain: expected: %s, actual: %s (host: %s)",
+				expectedPD, pd, host)
+		}
+	}
 	_, err = getPrimaryDomain("")
 	if err == nil {
 		t.Fatal("It still can get primary domain for a empty host!")
 	}
-	host = "123.abc"
+	host = "123.notatld"
 	_, err = getPrimaryDomain(host)
 	if err == nil {
 		t.Fatalf("It still can get primary domain for a unrecognized host %q!", host)
 	}
+	for _, host := range []string{"co.uk", "localhost"} {
+		if _, err = getPrimaryDomain(host); err == nil {
+			t.Fatalf("It still can get primary domain for host %q!", host)
+		}
+	}
 }
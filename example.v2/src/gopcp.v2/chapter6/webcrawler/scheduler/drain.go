@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// drainCheckInterval 代表排空过程中检查各缓冲池状态的时间间隔。
+const drainCheckInterval = 10 * time.Millisecond
+
+// DrainReport 代表优雅停止时的排空报告的类型。
+type DrainReport struct {
+	// FlushedResponses 代表排空过程中被分析的响应的数量。
+	FlushedResponses uint64 `json:"flushed_responses"`
+	// FlushedItems 代表排空过程中被条目处理管道处理的条目的数量。
+	FlushedItems uint64 `json:"flushed_items"`
+	// DiscardedRequests 代表排空开始后被丢弃的请求的数量，
+	// 其中包括新产生的请求和尚未开始下载的请求。
+	DiscardedRequests uint64 `json:"discarded_requests"`
+	// DiscardedResponses 代表排空结束时仍未被分析的响应的数量。
+	DiscardedResponses uint64 `json:"discarded_responses"`
+	// DiscardedItems 代表排空结束时仍未被处理的条目的数量。
+	DiscardedItems uint64 `json:"discarded_items"`
+	// TimedOut 代表排空是否因超时或被取消而提前结束。
+	TimedOut bool `json:"timed_out"`
+}
+
+// drainCounts 代表排空过程中的计数。
+type drainCounts struct {
+	// flushedResponses 代表排空过程中被分析的响应的数量。
+	flushedResponses uint64
+	// flushedItems 代表排空过程中被处理的条目的数量。
+	flushedItems uint64
+	// discardedRequests 代表排空过程中被丢弃的请求的数量。
+	discardedRequests uint64
+}
+
+func (sched *myScheduler) StopGracefully(ctx context.Context) (report DrainReport, err error) {
+	logger.Info("Stop scheduler gracefully...")
+	// 检查状态。
+	logger.Info("Check status for stop...")
+	var oldStatus Status
+	oldStatus, err =
+		sched.checkAndSetStatus(SCHED_STATUS_STOPPING)
+	defer func() {
+		sched.statusLock.Lock()
+		if err != nil {
+			sched.status = oldStatus
+		} else {
+			sched.status = SCHED_STATUS_STOPPED
+		}
+		sched.statusLock.Unlock()
+	}()
+	if err != nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// 若调度器已被暂停，则需要先恢复各个流程才能排空。
+	sched.pauseGate.open()
+	report = sched.drain(ctx)
+	logger.Infof("-- Drain report: %+v", report)
+	sched.shutdown()
+	return report, nil
+}
+
+// drain 会停止接受新的请求，并等待响应和条目缓冲池被排空，
+// 直至排空完毕或ctx被取消。
+func (sched *myScheduler) drain(ctx context.Context) DrainReport {
+	// 各个计数可能正在被其他协程原子地增加，因此也需要原子地重置。
+	atomic.StoreUint64(&sched.drainCounts.flushedResponses, 0)
+	atomic.StoreUint64(&sched.drainCounts.flushedItems, 0)
+	atomic.StoreUint64(&sched.drainCounts.discardedRequests, 0)
+	atomic.StoreUint32(&sched.draining, 1)
+	logger.Info("Drain response and item buffer pools...")
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	var timedOut bool
+	// 需要连续两次检查都为空闲才认为已排空，
+	// 以免遗漏正在被放入缓冲池的响应或条目。
+	var idleCount int
+	for idleCount < 2 && !timedOut {
+		select {
+		case <-ctx.Done():
+			timedOut = true
+		case <-ticker.C:
+			if sched.drained() {
+				idleCount++
+			} else {
+				idleCount = 0
+			}
+		}
+	}
+	return DrainReport{
+		FlushedResponses: atomic.LoadUint64(&sched.drainCounts.flushedResponses),
+		FlushedItems:     atomic.LoadUint64(&sched.drainCounts.flushedItems),
+		DiscardedRequests: atomic.LoadUint64(&sched.drainCounts.discardedRequests) +
+			sched.frontier.Len() + sched.hostDispatcher.Total() +
+			uint64(atomic.LoadInt64(&sched.pendingRetries)),
+		DiscardedResponses: sched.respBufferPool.Total(),
+		DiscardedItems:     sched.itemBufferPool.Total(),
+		TimedOut:           timedOut,
+	}
+}
+
+// drained 用于判断响应和条目是否都已被处理完毕。
+func (sched *myScheduler) drained() bool {
+	return !sched.handling() &&
+		sched.respBufferPool.Total() == 0 &&
+		sched.itemBufferPool.Total() == 0
+}
+
+// isDraining 用于判断调度器是否正在排空。
+func (sched *myScheduler) isDraining() bool {
+	return atomic.LoadUint32(&sched.draining) == 1
+}
+
+// discardIfDraining 会在调度器正在排空时丢弃给定的请求。
+// 若请求被丢弃，则返回true。
+func (sched *myScheduler) discardIfDraining(req *module.Request) bool {
+	if !sched.isDraining() {
+		return false
+	}
+	atomic.AddUint64(&sched.drainCounts.discardedRequests, 1)
+	logger.Warnf("Ignore the request! The scheduler is draining. (URL: %s)\n",
+		req.HTTPReq().URL)
+	return true
+}
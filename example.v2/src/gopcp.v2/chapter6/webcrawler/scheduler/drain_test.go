@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// startDrainTestingScheduler 用于启动一个测试排空用的调度器，
+// 并放入给定数量的条目。
+func startDrainTestingScheduler(
+	server *pageServer, itemNumber int, t *testing.T) Scheduler {
+	requestArgs := genRequestArgs([]string{server.Host()}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	dataArgs.ItemBufferCap = uint32(itemNumber)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+	for i := 0; i < itemNumber; i++ {
+		item := module.Item(map[string]interface{}{"number": i})
+		if err := mySched.itemBufferPool.Put(item); err != nil {
+			t.Fatalf("Couldn't put item: %s (number: %d)", err, i)
+		}
+	}
+	return sched
+}
+
+func TestDrainStopGracefully(t *testing.T) {
+	server := newPageServer(map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/"},
+		"/b": {},
+	})
+	defer server.Close()
+	sched := NewScheduler()
+	if _, err := sched.StopGracefully(context.Background()); err == nil {
+		t.Fatal("No error when stop an unstarted scheduler gracefully!")
+	}
+	itemNumber := 20
+	sched = startDrainTestingScheduler(server, itemNumber, t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	report, err := sched.StopGracefully(ctx)
+	if err != nil {
+		t.Fatalf("An error occurs when stopping scheduler gracefully: %s", err)
+	}
+	if report.TimedOut {
+		t.Fatalf("The drain has timed out! (report: %+v)", report)
+	}
+	if report.FlushedItems == 0 {
+		t.Fatalf("No item has been flushed! (report: %+v)", report)
+	}
+	if report.DiscardedResponses != 0 || report.DiscardedItems != 0 {
+		t.Fatalf("Some responses or items have been discarded! (report: %+v)",
+			report)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STOPPED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STOPPED),
+			GetStatusDescription(status))
+	}
+	mySched := sched.(*myScheduler)
+	if mySched.isDraining() {
+		t.Fatal("The scheduler is still draining after stopped!")
+	}
+	if _, err := sched.StopGracefully(ctx); err == nil {
+		t.Fatal("No error when stop a stopped scheduler gracefully!")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	server := newPageServer(map[string][]string{"/": {}})
+	defer server.Close()
+	itemNumber := 100
+	sched := startDrainTestingScheduler(server, itemNumber, t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := sched.StopGracefully(ctx)
+	if err != nil {
+		t.Fatalf("An error occurs when stopping scheduler gracefully: %s", err)
+	}
+	if !report.TimedOut {
+		t.Fatalf("The drain should have timed out! (report: %+v)", report)
+	}
+	if report.DiscardedItems == 0 {
+		t.Fatalf("No item has been discarded! (report: %+v)", report)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STOPPED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STOPPED),
+			GetStatusDescription(status))
+	}
+}
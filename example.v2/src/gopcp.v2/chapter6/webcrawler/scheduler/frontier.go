@@ -0,0 +1,268 @@
+package scheduler
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// FrontierType 代表URL边界（待爬取请求的队列）的类型。
+type FrontierType string
+
+// 当前认可的URL边界类型的常量。
+const (
+	// FRONTIER_TYPE_BFS 代表广度优先的URL边界。
+	// 深度较小的请求会先被取出，深度相同的请求按放入的顺序取出。
+	FRONTIER_TYPE_BFS FrontierType = "bfs"
+	// FRONTIER_TYPE_DFS 代表深度优先的URL边界。
+	// 深度较大的请求会先被取出，深度相同的请求中后放入的先取出。
+	FRONTIER_TYPE_DFS FrontierType = "dfs"
+	// FRONTIER_TYPE_PRIORITY 代表按分数排序的URL边界。
+	// 分数较高的请求会先被取出，分数相同的请求按放入的顺序取出。
+	FRONTIER_TYPE_PRIORITY FrontierType = "priority"
+)
+
+// legalFrontierTypeMap 代表合法的URL边界类型的字典。
+var legalFrontierTypeMap = map[FrontierType]bool{
+	FRONTIER_TYPE_BFS:      true,
+	FRONTIER_TYPE_DFS:      true,
+	FRONTIER_TYPE_PRIORITY: true,
+}
+
+// errClosedFrontier 代表URL边界已关闭的错误。
+var errClosedFrontier = errors.New("closed frontier")
+
+// ScoreFunc 代表用于计算请求分数的函数类型。
+// 分数会在请求被放入按分数排序的URL边界时计算，分数越高越先被爬取。
+// 自身带有分数的请求（参见module.Request的WithScore方法）会直接使用其分数。
+type ScoreFunc func(req *module.Request) float64
+
+// defaultScore 代表默认的请求分数计算函数，深度越小分数越高。
+func defaultScore(req *module.Request) float64 {
+	return -float64(req.Depth())
+}
+
+// Frontier 代表URL边界的接口类型。
+// 调度器会通过它来决定请求被下载的顺序。
+// 该接口的实现类型必须是并发安全的。
+type Frontier interface {
+	// Type 用于获取URL边界的类型。
+	Type() FrontierType
+	// Cap 用于获取URL边界的容量。
+	Cap() uint64
+	// Len 用于获取URL边界中请求的数量。
+	Len() uint64
+	// Put 用于放入请求。
+	// 本方法是阻塞的。若URL边界已满，则会等待直至有空位。
+	// 若URL边界已关闭，则会直接返回非nil的错误值。
+	Put(req *module.Request) error
+	// Get 用于按顺序取出下一个请求。
+	// 本方法是阻塞的。若URL边界为空，则会等待直至有请求可取。
+	// 若URL边界已关闭，则会直接返回非nil的错误值。
+	Get() (*module.Request, error)
+	// Close 用于关闭URL边界。
+	// 若URL边界之前已关闭，则返回false，否则返回true。
+	Close() bool
+	// Closed 用于判断URL边界是否已关闭。
+	Closed() bool
+}
+
+// NewFrontier 用于创建一个URL边界。
+// 参数frontierType代表类型，为空时使用广度优先。
+// 参数capacity代表容量，不能为0。
+// 参数scoreFunc仅对按分数排序的URL边界有效，为nil时深度越小分数越高。
+func NewFrontier(
+	frontierType FrontierType,
+	capacity uint64,
+	scoreFunc ScoreFunc) (Frontier, error) {
+	if frontierType == "" {
+		frontierType = FRONTIER_TYPE_BFS
+	}
+	if !legalFrontierTypeMap[frontierType] {
+		errMsg := fmt.Sprintf("illegal frontier type: %q", frontierType)
+		return nil, genParameterError(errMsg)
+	}
+	if capacity == 0 {
+		return nil, genParameterError("zero frontier capacity")
+	}
+	f := &myFrontier{
+		frontierType: frontierType,
+		capacity:     capacity,
+	}
+	f.entries.less, f.scoreFunc = frontierOrder(frontierType, scoreFunc)
+	f.cond = sync.NewCond(&f.lock)
+	return f, nil
+}
+
+// frontierOrder 用于获取给定类型的URL边界判断条目先后的函数，
+// 以及计算请求分数的函数。后者仅对按分数排序的类型有效，否则为nil。
+// 参数frontierType为空时视为广度优先。
+func frontierOrder(
+	frontierType FrontierType,
+	scoreFunc ScoreFunc) (func(a, b *frontierEntry) bool, ScoreFunc) {
+	switch frontierType {
+	case FRONTIER_TYPE_DFS:
+		return func(a, b *frontierEntry) bool {
+			if a.req.Depth() != b.req.Depth() {
+				return a.req.Depth() > b.req.Depth()
+			}
+			return a.seq > b.seq
+		}, nil
+	case FRONTIER_TYPE_PRIORITY:
+		if scoreFunc == nil {
+			scoreFunc = defaultScore
+		}
+		less := func(a, b *frontierEntry) bool {
+			if a.score != b.score {
+				return a.score > b.score
+			}
+			return a.seq < b.seq
+		}
+		return less, requestScore(scoreFunc)
+	default:
+		return func(a, b *frontierEntry) bool {
+			if a.req.Depth() != b.req.Depth() {
+				return a.req.Depth() < b.req.Depth()
+			}
+			return a.seq < b.seq
+		}, nil
+	}
+}
+
+// requestScore 用于生成优先使用请求自身所带分数的分数计算函数。
+// 只有请求不带有分数时，才会使用参数scoreFunc计算。
+func requestScore(scoreFunc ScoreFunc) ScoreFunc {
+	return func(req *module.Request) float64 {
+		if score, ok := req.Score(); ok {
+			return score
+		}
+		return scoreFunc(req)
+	}
+}
+
+// frontierEntry 代表URL边界中的条目。
+type frontierEntry struct {
+	// req 代表请求。
+	req *module.Request
+	// score 代表请求的分数。
+	score float64
+	// seq 代表请求被放入的序号。
+	seq uint64
+}
+
+// frontierHeap 代表URL边界所用的堆。
+type frontierHeap struct {
+	// items 代表条目列表。
+	items []*frontierEntry
+	// less 代表判断条目先后的函数。
+	less func(a, b *frontierEntry) bool
+}
+
+func (h *frontierHeap) Len() int { return len(h.items) }
+
+func (h *frontierHeap) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+
+func (h *frontierHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *frontierHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(*frontierEntry))
+}
+
+func (h *frontierHeap) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	return item
+}
+
+// myFrontier 代表URL边界的实现类型。
+type myFrontier struct {
+	// frontierType 代表类型。
+	frontierType FrontierType
+	// capacity 代表容量。
+	capacity uint64
+	// scoreFunc 代表请求分数的计算函数。
+	scoreFunc ScoreFunc
+	// entries 代表存放条目的堆。
+	entries frontierHeap
+	// seq 代表下一个条目的序号。
+	seq uint64
+	// closed 代表关闭状态。
+	closed bool
+	// lock 代表保护以上字段的互斥锁。
+	lock sync.Mutex
+	// cond 代表用于等待状态变化的条件变量。
+	cond *sync.Cond
+}
+
+func (f *myFrontier) Type() FrontierType {
+	return f.frontierType
+}
+
+func (f *myFrontier) Cap() uint64 {
+	return f.capacity
+}
+
+func (f *myFrontier) Len() uint64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return uint64(f.entries.Len())
+}
+
+func (f *myFrontier) Put(req *module.Request) error {
+	if req == nil {
+		return genParameterError("nil request")
+	}
+	entry := &frontierEntry{req: req}
+	if f.scoreFunc != nil {
+		entry.score = f.scoreFunc(req)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for !f.closed && uint64(f.entries.Len()) >= f.capacity {
+		f.cond.Wait()
+	}
+	if f.closed {
+		return errClosedFrontier
+	}
+	entry.seq = f.seq
+	f.seq++
+	heap.Push(&f.entries, entry)
+	f.cond.Broadcast()
+	return nil
+}
+
+func (f *myFrontier) Get() (*module.Request, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for !f.closed && f.entries.Len() == 0 {
+		f.cond.Wait()
+	}
+	if f.closed {
+		return nil, errClosedFrontier
+	}
+	entry := heap.Pop(&f.entries).(*frontierEntry)
+	f.cond.Broadcast()
+	return entry.req, nil
+}
+
+func (f *myFrontier) Close() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.closed {
+		return false
+	}
+	f.closed = true
+	f.cond.Broadcast()
+	return true
+}
+
+func (f *myFrontier) Closed() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.closed
+}
@@ -0,0 +1,218 @@
+package scheduler
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// genTestingRequests 用于按照给定的URL和深度生成测试用的请求列表。
+func genTestingRequests(urls []string, depths []uint32, t *testing.T) []*module.Request {
+	reqs := make([]*module.Request, len(urls))
+	for i, u := range urls {
+		httpReq, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)",
+				err, u)
+		}
+		reqs[i] = module.NewRequest(httpReq, depths[i])
+	}
+	return reqs
+}
+
+func TestFrontierNew(t *testing.T) {
+	frontier, err := NewFrontier("", 10, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a frontier: %s", err)
+	}
+	if frontier.Type() != FRONTIER_TYPE_BFS {
+		t.Fatalf("Inconsistent frontier type: expected: %s, actual: %s",
+			FRONTIER_TYPE_BFS, frontier.Type())
+	}
+	if frontier.Cap() != 10 {
+		t.Fatalf("Inconsistent frontier capacity: expected: %d, actual: %d",
+			10, frontier.Cap())
+	}
+	if _, err := NewFrontier("random", 10, nil); err == nil {
+		t.Fatal("No error when creating a frontier with illegal type!")
+	}
+	if _, err := NewFrontier(FRONTIER_TYPE_DFS, 0, nil); err == nil {
+		t.Fatal("No error when creating a frontier with zero capacity!")
+	}
+	dataArgs := genDataArgs(10, 2, 1)
+	dataArgs.Frontier = "random"
+	if err := dataArgs.Check(); err == nil {
+		t.Fatalf("No error when check data arguments with illegal frontier type! (args: %#v)",
+			dataArgs)
+	}
+}
+
+func TestFrontierOrder(t *testing.T) {
+	urls := []string{
+		"http://a.com/d1-1",
+		"http://a.com/d0",
+		"http://a.com/d2-very-important",
+		"http://a.com/d1-2",
+	}
+	depths := []uint32{1, 0, 2, 1}
+	scoreFunc := func(req *module.Request) float64 {
+		if strings.Contains(req.HTTPReq().URL.Path, "important") {
+			return 100
+		}
+		return -float64(req.Depth())
+	}
+	cases := []struct {
+		frontierType FrontierType
+		scoreFunc    ScoreFunc
+		expected     []string
+	}{
+		{FRONTIER_TYPE_BFS, nil, []string{"/d0", "/d1-1", "/d1-2", "/d2-very-important"}},
+		{FRONTIER_TYPE_DFS, nil, []string{"/d2-very-important", "/d1-2", "/d1-1", "/d0"}},
+		{FRONTIER_TYPE_PRIORITY, nil, []string{"/d0", "/d1-1", "/d1-2", "/d2-very-important"}},
+		{FRONTIER_TYPE_PRIORITY, scoreFunc, []string{"/d2-very-important", "/d0", "/d1-1", "/d1-2"}},
+	}
+	for _, c := range cases {
+		frontier, err := NewFrontier(c.frontierType, 10, c.scoreFunc)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a frontier: %s", err)
+		}
+		for _, req := range genTestingRequests(urls, depths, t) {
+			if err := frontier.Put(req); err != nil {
+				t.Fatalf("An error occurs when putting request: %s", err)
+			}
+		}
+		if frontier.Len() != uint64(len(urls)) {
+			t.Fatalf("Inconsistent frontier length: expected: %d, actual: %d",
+				len(urls), frontier.Len())
+		}
+		for i, path := range c.expected {
+			req, err := frontier.Get()
+			if err != nil {
+				t.Fatalf("An error occurs when getting request: %s", err)
+			}
+			if actual := req.HTTPReq().URL.Path; actual != path {
+				t.Fatalf("Inconsistent request order: expected: %s, actual: %s (type: %s, index: %d)",
+					path, actual, c.frontierType, i)
+			}
+		}
+	}
+}
+
+func TestFrontierRequestScore(t *testing.T) {
+	urls := []string{"http://a.com/d0", "http://a.com/d1", "http://a.com/d2"}
+	reqs := genTestingRequests(urls, []uint32{0, 1, 2}, t)
+	reqs[2] = reqs[2].WithScore(1)
+	frontier, _ := NewFrontier(FRONTIER_TYPE_PRIORITY, 10, nil)
+	for _, req := range reqs {
+		frontier.Put(req)
+	}
+	for i, path := range []string{"/d2", "/d0", "/d1"} {
+		req, err := frontier.Get()
+		if err != nil {
+			t.Fatalf("An error occurs when getting request: %s", err)
+		}
+		if actual := req.HTTPReq().URL.Path; actual != path {
+			t.Fatalf("Inconsistent request order: expected: %s, actual: %s (index: %d)",
+				path, actual, i)
+		}
+	}
+}
+
+func TestFrontierBlockAndClose(t *testing.T) {
+	frontier, _ := NewFrontier(FRONTIER_TYPE_BFS, 1, nil)
+	reqs := genTestingRequests(
+		[]string{"http://a.com/1", "http://a.com/2"}, []uint32{0, 0}, t)
+	if err := frontier.Put(reqs[0]); err != nil {
+		t.Fatalf("An error occurs when putting request: %s", err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- frontier.Put(reqs[1])
+	}()
+	select {
+	case err := <-errCh:
+		t.Fatalf("The capacity is not honored! (err: %v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := frontier.Get(); err != nil {
+		t.Fatalf("An error occurs when getting request: %s", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("An error occurs when putting request: %s", err)
+	}
+	frontier.Get()
+	reqCh := make(chan error, 1)
+	go func() {
+		_, err := frontier.Get()
+		reqCh <- err
+	}()
+	select {
+	case err := <-reqCh:
+		t.Fatalf("It still can get request from an empty frontier! (err: %v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !frontier.Close() {
+		t.Fatal("Couldn't close the frontier!")
+	}
+	if frontier.Close() {
+		t.Fatal("It still can close a closed frontier!")
+	}
+	if err := <-reqCh; err == nil {
+		t.Fatal("No error when getting request from a closed frontier!")
+	}
+	if !frontier.Closed() {
+		t.Fatal("Inconsistent closed status: expected: true, actual: false")
+	}
+	if err := frontier.Put(reqs[0]); err == nil {
+		t.Fatal("No error when putting request to a closed frontier!")
+	}
+}
+
+func TestFrontierWithPoliteness(t *testing.T) {
+	server := newPageServer(map[string][]string{
+		"/":   {"/p1", "/p4", "/p2", "/p5", "/p3"},
+		"/p1": {}, "/p2": {}, "/p3": {}, "/p4": {}, "/p5": {},
+	})
+	defer server.Close()
+	var order []string
+	var lock sync.Mutex
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		order = append(order, r.URL.Path)
+		lock.Unlock()
+		server.serve(w, r)
+	})
+	requestArgs := genRequestArgs([]string{server.Host()}, 1)
+	// 使各个链接在主机可以再次被访问之前都已进入主机分发器。
+	requestArgs.Politeness = PolitenessArgs{MinDelay: 500 * time.Millisecond}
+	dataArgs := genDataArgs(10, 2, 1)
+	dataArgs.Frontier = FRONTIER_TYPE_PRIORITY
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	moduleArgs.ScoreFunc = func(req *module.Request) float64 {
+		path := req.HTTPReq().URL.Path
+		return float64(path[len(path)-1])
+	}
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	if !waitFor(5*time.Second, func() bool { return server.Hits("/p1") > 0 }) {
+		t.Fatal("The pages have not been fetched!")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	expected := "/ /p5 /p4 /p3 /p2 /p1"
+	if actual := strings.Join(order, " "); actual != expected {
+		t.Fatalf("Inconsistent download order: expected: %s, actual: %s",
+			expected, actual)
+	}
+}
@@ -0,0 +1,229 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// RequestHook 代表请求钩子的类型。
+// 它会在请求被放入URL边界之前被调用，可以修改或替换请求。
+// 第二个结果值为false或第一个结果值为nil时，请求会被丢弃。
+type RequestHook func(req *module.Request) (*module.Request, bool)
+
+// ResponseHook 代表响应钩子的类型。
+// 它会在响应被下载之后、放入响应缓冲池之前被调用，可以修改或替换响应。
+// 第二个结果值为false或第一个结果值为nil时，响应会被丢弃。
+// 丢弃响应的钩子不必关闭响应体，调度器会负责关闭原有的响应体。
+type ResponseHook func(resp *module.Response) (*module.Response, bool)
+
+// ItemHook 代表条目钩子的类型。
+// 它会在条目被交给条目处理管道之前被调用，可以修改或替换条目。
+// 第二个结果值为false或第一个结果值为nil时，条目会被丢弃。
+type ItemHook func(item module.Item) (module.Item, bool)
+
+// ErrorHook 代表错误钩子的类型。
+// 它会在错误被发送到错误通道之前被调用，可以修改或替换错误。
+// 第二个结果值为false或第一个结果值为nil时，错误会被丢弃。
+type ErrorHook func(err error) (error, bool)
+
+// hookChain 代表钩子链的类型。
+// 同类的钩子会按照注册的顺序依次被调用，
+// 前一个钩子的结果会作为后一个钩子的参数，
+// 任何一个钩子丢弃了数据都会使后续的钩子不再被调用。
+// 钩子引发的运行时恐慌会被恢复，相应的数据会被丢弃。
+type hookChain struct {
+	// requestHooks 代表请求钩子的列表。
+	requestHooks []RequestHook
+	// responseHooks 代表响应钩子的列表。
+	responseHooks []ResponseHook
+	// itemHooks 代表条目钩子的列表。
+	itemHooks []ItemHook
+	// errorHooks 代表错误钩子的列表。
+	errorHooks []ErrorHook
+	lock       sync.RWMutex
+}
+
+// addRequestHook 用于添加请求钩子。
+func (chain *hookChain) addRequestHook(hook RequestHook) {
+	chain.lock.Lock()
+	defer chain.lock.Unlock()
+	chain.requestHooks = append(chain.requestHooks, hook)
+}
+
+// addResponseHook 用于添加响应钩子。
+func (chain *hookChain) addResponseHook(hook ResponseHook) {
+	chain.lock.Lock()
+	defer chain.lock.Unlock()
+	chain.responseHooks = append(chain.responseHooks, hook)
+}
+
+// addItemHook 用于添加条目钩子。
+func (chain *hookChain) addItemHook(hook ItemHook) {
+	chain.lock.Lock()
+	defer chain.lock.Unlock()
+	chain.itemHooks = append(chain.itemHooks, hook)
+}
+
+// addErrorHook 用于添加错误钩子。
+func (chain *hookChain) addErrorHook(hook ErrorHook) {
+	chain.lock.Lock()
+	defer chain.lock.Unlock()
+	chain.errorHooks = append(chain.errorHooks, hook)
+}
+
+// onRequest 用于依次调用请求钩子。
+// 若第三个结果值不为nil，则说明有钩子引发了运行时恐慌。
+func (chain *hookChain) onRequest(
+	req *module.Request) (result *module.Request, ok bool, err error) {
+	chain.lock.RLock()
+	hooks := chain.requestHooks
+	chain.lock.RUnlock()
+	defer recoverHook("request", &ok, &err)
+	result = req
+	for _, hook := range hooks {
+		if result, ok = hook(result); !ok || result == nil {
+			return nil, false, nil
+		}
+	}
+	return result, true, nil
+}
+
+// onResponse 用于依次调用响应钩子。
+// 若第三个结果值不为nil，则说明有钩子引发了运行时恐慌。
+func (chain *hookChain) onResponse(
+	resp *module.Response) (result *module.Response, ok bool, err error) {
+	chain.lock.RLock()
+	hooks := chain.responseHooks
+	chain.lock.RUnlock()
+	defer recoverHook("response", &ok, &err)
+	result = resp
+	for _, hook := range hooks {
+		if result, ok = hook(result); !ok || result == nil {
+			return nil, false, nil
+		}
+	}
+	return result, true, nil
+}
+
+// onItem 用于依次调用条目钩子。
+// 若第三个结果值不为nil，则说明有钩子引发了运行时恐慌。
+func (chain *hookChain) onItem(
+	item module.Item) (result module.Item, ok bool, err error) {
+	chain.lock.RLock()
+	hooks := chain.itemHooks
+	chain.lock.RUnlock()
+	defer recoverHook("item", &ok, &err)
+	result = item
+	for _, hook := range hooks {
+		if result, ok = hook(result); !ok || result == nil {
+			return nil, false, nil
+		}
+	}
+	return result, true, nil
+}
+
+// onError 用于依次调用错误钩子。
+// 若第三个结果值不为nil，则说明有钩子引发了运行时恐慌。
+func (chain *hookChain) onError(
+	e error) (result error, ok bool, err error) {
+	chain.lock.RLock()
+	hooks := chain.errorHooks
+	chain.lock.RUnlock()
+	defer recoverHook("error", &ok, &err)
+	result = e
+	for _, hook := range hooks {
+		if result, ok = hook(result); !ok || result == nil {
+			return nil, false, nil
+		}
+	}
+	return result, true, nil
+}
+
+// recoverHook 用于恢复钩子引发的运行时恐慌，并把它转换为错误值。
+// 本函数只应在延迟调用中直接使用。
+func recoverHook(kind string, ok *bool, err *error) {
+	if p := recover(); p != nil {
+		*ok = false
+		*err = genError(fmt.Sprintf("%s hook panic: %v", kind, p))
+	}
+}
+
+func (sched *myScheduler) OnRequest(hook RequestHook) {
+	if hook == nil {
+		return
+	}
+	sched.hooks.addRequestHook(hook)
+}
+
+func (sched *myScheduler) OnResponse(hook ResponseHook) {
+	if hook == nil {
+		return
+	}
+	sched.hooks.addResponseHook(hook)
+}
+
+func (sched *myScheduler) OnItem(hook ItemHook) {
+	if hook == nil {
+		return
+	}
+	sched.hooks.addItemHook(hook)
+}
+
+func (sched *myScheduler) OnError(hook ErrorHook) {
+	if hook == nil {
+		return
+	}
+	sched.hooks.addErrorHook(hook)
+}
+
+// hookRequest 用于对请求调用请求钩子。
+// 若第二个结果值为false，则说明请求已被丢弃。
+func (sched *myScheduler) hookRequest(req *module.Request) (*module.Request, bool) {
+	result, ok, err := sched.hooks.onRequest(req)
+	if err != nil {
+		sendError(err, "", sched.errorBufferPool)
+	}
+	if !ok {
+		logger.Warnln("Ignore the request! It is dropped by a request hook.")
+	}
+	return result, ok
+}
+
+// hookResponse 用于对响应调用响应钩子。
+// 若第二个结果值为false，则说明响应已被丢弃，其响应体也已被关闭。
+func (sched *myScheduler) hookResponse(resp *module.Response) (*module.Response, bool) {
+	result, ok, err := sched.hooks.onResponse(resp)
+	if err != nil {
+		sendError(err, "", sched.errorBufferPool)
+	}
+	if !ok {
+		if httpResp := resp.HTTPResp(); httpResp != nil && httpResp.Body != nil {
+			httpResp.Body.Close()
+		}
+	}
+	return result, ok
+}
+
+// hookItem 用于对条目调用条目钩子。
+// 若第二个结果值为false，则说明条目已被丢弃。
+func (sched *myScheduler) hookItem(item module.Item) (module.Item, bool) {
+	result, ok, err := sched.hooks.onItem(item)
+	if err != nil {
+		sendError(err, "", sched.errorBufferPool)
+	}
+	return result, ok
+}
+
+// hookError 用于对错误调用错误钩子。
+// 若第二个结果值为false，则说明错误已被丢弃。
+// 错误钩子引发运行时恐慌时，原有的错误会被保留。
+func (sched *myScheduler) hookError(e error) (error, bool) {
+	result, ok, err := sched.hooks.onError(e)
+	if err != nil {
+		logger.Errorf("An error occurs when calling error hooks: %s", err)
+		return e, true
+	}
+	return result, ok
+}
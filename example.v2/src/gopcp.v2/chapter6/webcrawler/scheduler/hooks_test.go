@@ -0,0 +1,197 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/module/local/analyzer"
+	"gopcp.v2/chapter6/webcrawler/module/local/pipeline"
+)
+
+func TestHookChain(t *testing.T) {
+	var chain hookChain
+	req := genTestingRequest("http://example.com/", t)
+	result, ok, err := chain.onRequest(req)
+	if !ok || err != nil || result != req {
+		t.Fatalf("Inconsistent result of empty hook chain: %v, %v, %v", result, ok, err)
+	}
+	var calls []string
+	chain.addItemHook(func(item module.Item) (module.Item, bool) {
+		calls = append(calls, "first")
+		item["first"] = true
+		return item, true
+	})
+	chain.addItemHook(func(item module.Item) (module.Item, bool) {
+		calls = append(calls, "second")
+		if item["drop"] == true {
+			return nil, false
+		}
+		return module.Item{"replaced": item["first"]}, true
+	})
+	item, ok, err := chain.onItem(module.Item{})
+	if !ok || err != nil || item["replaced"] != true {
+		t.Fatalf("Inconsistent result of item hooks: %v, %v, %v", item, ok, err)
+	}
+	if fmt.Sprint(calls) != "[first second]" {
+		t.Fatalf("Inconsistent hook calls: %v", calls)
+	}
+	if item, ok, _ := chain.onItem(module.Item{"drop": true}); ok || item != nil {
+		t.Fatalf("The item has not been dropped: %v", item)
+	}
+	chain.addResponseHook(func(resp *module.Response) (*module.Response, bool) {
+		return nil, true
+	})
+	if _, ok, _ := chain.onResponse(&module.Response{}); ok {
+		t.Fatal("The response has not been dropped by a hook returning nil!")
+	}
+	chain.addErrorHook(func(err error) (error, bool) {
+		panic("boom")
+	})
+	_, ok, err = chain.onError(errors.New("error"))
+	if ok || err == nil || !strings.Contains(err.Error(), "error hook panic: boom") {
+		t.Fatalf("Inconsistent result of panicking hook: %v, %v", ok, err)
+	}
+}
+
+func TestSchedHooks(t *testing.T) {
+	var lock sync.Mutex
+	hits := map[string]int{}
+	headers := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		hits[r.URL.Path]++
+		headers[r.URL.Path] = r.Header.Get("X-Crawler")
+		lock.Unlock()
+		links := map[string]string{
+			"/":  `<a href="/a">a</a><a href="/b">b</a><a href="/secret">s</a><a href="/missing">m</a>`,
+			"/a": "", "/b": `<a href="/b1">b1</a>`, "/b1": "", "/secret": "",
+		}
+		page, ok := links[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><body>%s</body></html>", page)
+	}))
+	defer server.Close()
+	// 准备组件。
+	snGen := module.NewSNGenertor(1, 0)
+	parseURLItem := func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		if httpResp.StatusCode != 200 {
+			return nil, nil
+		}
+		return []module.Data{module.Item{"path": httpResp.Request.URL.Path}}, nil
+	}
+	a, err := analyzer.New(module.MID(fmt.Sprintf("A%d", snGen.Get())),
+		[]module.ParseResponse{parseATag, parseURLItem}, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s", err)
+	}
+	items := map[string]module.Item{}
+	p, err := pipeline.New(module.MID(fmt.Sprintf("P%d", snGen.Get())),
+		[]module.ProcessItem{func(item module.Item) (module.Item, error) {
+			if path, ok := item["path"].(string); ok {
+				lock.Lock()
+				items[path] = item
+				lock.Unlock()
+			}
+			return item, nil
+		}}, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a pipeline: %s", err)
+	}
+	moduleArgs := ModuleArgs{
+		Downloaders: genSimpleDownloaders(1, false, snGen, t),
+		Analyzers:   []module.Analyzer{a},
+		Pipelines:   []module.Pipeline{p},
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	sched := NewScheduler()
+	if err := sched.Init(genRequestArgs([]string{host}, 2),
+		genDataArgs(10, 2, 1), moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	// 注册钩子。
+	sched.OnRequest(nil)
+	sched.OnRequest(func(req *module.Request) (*module.Request, bool) {
+		if req.HTTPReq().URL.Path == "/secret" {
+			return nil, false
+		}
+		req.HTTPReq().Header.Set("X-Crawler", "hooked")
+		return req, true
+	})
+	var numResponses int
+	sched.OnResponse(func(resp *module.Response) (*module.Response, bool) {
+		lock.Lock()
+		numResponses++
+		lock.Unlock()
+		return resp, resp.HTTPResp().Request.URL.Path != "/b"
+	})
+	sched.OnItem(func(item module.Item) (module.Item, bool) {
+		item["tag"] = "hooked"
+		return item, item["path"] != "/a"
+	})
+	sched.OnError(func(err error) (error, bool) {
+		return fmt.Errorf("hooked: %s", err), true
+	})
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	var hookedErr error
+	errChan := sched.ErrorChan()
+	go func() {
+		for err := range errChan {
+			lock.Lock()
+			hookedErr = err
+			lock.Unlock()
+		}
+	}()
+	ok := waitFor(10*time.Second, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return items["/"] != nil && hits["/missing"] > 0 && hookedErr != nil
+	})
+	if !ok || !waitFor(5*time.Second, sched.Idle) {
+		t.Fatal("The pages have not been crawled!")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	// 请求钩子。
+	if hits["/secret"] != 0 {
+		t.Fatal("The request dropped by hook has been downloaded!")
+	}
+	for _, path := range []string{"/", "/a", "/b"} {
+		if headers[path] != "hooked" {
+			t.Fatalf("Inconsistent header: expected: %q, actual: %q (path: %s)",
+				"hooked", headers[path], path)
+		}
+	}
+	// 响应钩子。
+	if numResponses != 4 {
+		t.Fatalf("Inconsistent response number: expected: %d, actual: %d", 4, numResponses)
+	}
+	if hits["/b1"] != 0 {
+		t.Fatal("The links in the response dropped by hook have been followed!")
+	}
+	// 条目钩子。
+	if items["/"]["tag"] != "hooked" {
+		t.Fatalf("The item has not been tagged: %v", items["/"])
+	}
+	if items["/a"] != nil || items["/b"] != nil {
+		t.Fatalf("The items dropped by hook have been processed: %v", items)
+	}
+	// 错误钩子。
+	if !strings.HasPrefix(hookedErr.Error(), "hooked: ") {
+		t.Fatalf("The error has not been hooked: %s", hookedErr)
+	}
+}
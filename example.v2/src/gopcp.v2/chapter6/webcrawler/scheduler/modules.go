@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// moduleDrainCheckInterval 代表移除组件时检查组件实时处理数的时间间隔。
+const moduleDrainCheckInterval = 10 * time.Millisecond
+
+// moduleUsage 代表组件的使用计数，即组件已被获取但尚未被归还的次数。
+type moduleUsage struct {
+	// counts 代表组件ID与使用计数的映射。
+	counts map[module.MID]int
+	lock   sync.Mutex
+}
+
+// acquireModule 用于获取一个给定类型的组件，并增加它的使用计数。
+// 获取成功之后，调用方必须在用完组件时调用releaseModule方法。
+func (sched *myScheduler) acquireModule(moduleType module.Type) (module.Module, error) {
+	usage := &sched.moduleUsage
+	usage.lock.Lock()
+	defer usage.lock.Unlock()
+	m, err := sched.registrar.Get(moduleType)
+	if err != nil || m == nil {
+		return m, err
+	}
+	if usage.counts == nil {
+		usage.counts = map[module.MID]int{}
+	}
+	usage.counts[m.ID()]++
+	return m, nil
+}
+
+// releaseModule 用于减少给定组件的使用计数。
+func (sched *myScheduler) releaseModule(m module.Module) {
+	usage := &sched.moduleUsage
+	usage.lock.Lock()
+	defer usage.lock.Unlock()
+	if usage.counts[m.ID()] <= 1 {
+		delete(usage.counts, m.ID())
+		return
+	}
+	usage.counts[m.ID()]--
+}
+
+// moduleInUse 用于判断给定的组件是否正在被使用。
+func (sched *myScheduler) moduleInUse(mid module.MID) bool {
+	usage := &sched.moduleUsage
+	usage.lock.Lock()
+	defer usage.lock.Unlock()
+	return usage.counts[mid] > 0
+}
+
+// unregisterModule 用于注销给定的组件。
+// 注销与获取组件互斥，因此注销之后该组件的使用计数只会减少。
+func (sched *myScheduler) unregisterModule(mid module.MID) error {
+	usage := &sched.moduleUsage
+	usage.lock.Lock()
+	defer usage.lock.Unlock()
+	_, err := sched.registrar.Unregister(mid)
+	return err
+}
+
+// checkModuleChangeable 用于检查调度器的当前状态是否允许添加或移除组件。
+func (sched *myScheduler) checkModuleChangeable() error {
+	switch status := sched.Status(); status {
+	case SCHED_STATUS_INITIALIZED, SCHED_STATUS_STARTED, SCHED_STATUS_PAUSED:
+		return nil
+	default:
+		errMsg := fmt.Sprintf("couldn't change modules when the scheduler is %s!",
+			GetStatusDescription(status))
+		return genError(errMsg)
+	}
+}
+
+func (sched *myScheduler) AddModule(m module.Module) (err error) {
+	if m == nil {
+		return genParameterError("nil module")
+	}
+	logger.Infof("Add module %s...", m.ID())
+	if err = sched.checkModuleChangeable(); err != nil {
+		return
+	}
+	sched.moduleLock.Lock()
+	defer sched.moduleLock.Unlock()
+	ok, err := sched.registrar.Register(m)
+	if err != nil {
+		return genErrorByError(err)
+	}
+	if !ok {
+		errMsg := fmt.Sprintf("the module with MID %q has been registered!", m.ID())
+		return genError(errMsg)
+	}
+	logger.Infof("Module %s has been added.", m.ID())
+	return nil
+}
+
+func (sched *myScheduler) RemoveModule(mid module.MID, drain bool) (err error) {
+	logger.Infof("Remove module %s... (drain: %v)", mid, drain)
+	if err = sched.checkModuleChangeable(); err != nil {
+		return
+	}
+	ok, moduleType := module.GetType(mid)
+	if !ok {
+		return genParameterError(fmt.Sprintf("illegal MID: %q", mid))
+	}
+	sched.moduleLock.Lock()
+	defer sched.moduleLock.Unlock()
+	modules, err := sched.registrar.GetAllByType(moduleType)
+	if err != nil {
+		return genErrorByError(err)
+	}
+	m, ok := modules[mid]
+	if !ok {
+		return genError(fmt.Sprintf("not found the module with MID %q!", mid))
+	}
+	if len(modules) <= 1 {
+		errMsg := fmt.Sprintf("couldn't remove the last module of type %q!", moduleType)
+		return genError(errMsg)
+	}
+	// 先注销组件，以使它不会再被选中。
+	if err = sched.unregisterModule(mid); err != nil {
+		return genErrorByError(err)
+	}
+	if !drain {
+		// 组件不再被使用之后，会在后台被关闭。
+		go func() {
+			sched.waitForModule(m, false)
+			if err := closeModule(m); err != nil {
+				logger.Errorf("An error occurs when closing module %s: %s", mid, err)
+			}
+		}()
+		logger.Infof("Module %s has been removed.", mid)
+		return nil
+	}
+	// 等待组件处理完正在处理的数据。
+	logger.Infof("Wait for the handling data of module %s...", mid)
+	sched.waitForModule(m, true)
+	if err = closeModule(m); err != nil {
+		return genErrorByError(err)
+	}
+	logger.Infof("Module %s has been removed.", mid)
+	return nil
+}
+
+// waitForModule 用于等待给定的组件不再被使用，或者调度器被停止。
+// 若参数drain为true，则还会等待该组件的实时处理数变为0。
+func (sched *myScheduler) waitForModule(m module.Module, drain bool) {
+	for sched.moduleInUse(m.ID()) || (drain && m.HandlingNumber() > 0) {
+		if sched.canceled() {
+			return
+		}
+		time.Sleep(moduleDrainCheckInterval)
+	}
+}
+
+// closeModule 用于关闭实现了io.Closer接口的组件。
+func closeModule(m module.Module) error {
+	if closer, ok := m.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
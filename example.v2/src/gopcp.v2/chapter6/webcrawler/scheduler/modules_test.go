@@ -0,0 +1,263 @@
+package scheduler
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// drainingPipeline 代表可以控制实时处理数的条目处理管道。
+type drainingPipeline struct {
+	module.Pipeline
+	// handling 代表实时处理数。
+	handling uint64
+	// closed 代表是否已被关闭。1代表是，0代表否。
+	closed uint32
+}
+
+func (pipeline *drainingPipeline) HandlingNumber() uint64 {
+	return atomic.LoadUint64(&pipeline.handling)
+}
+
+func (pipeline *drainingPipeline) Close() error {
+	atomic.StoreUint32(&pipeline.closed, 1)
+	return nil
+}
+
+// midSelector 代表优先选择给定ID的组件的组件选择器。
+type midSelector struct {
+	mid *module.MID
+}
+
+func (selector midSelector) Type() module.SelectorType {
+	return module.SELECTOR_TYPE_ROUND_ROBIN
+}
+
+func (selector midSelector) Select(modules []module.Module) module.Module {
+	for _, m := range modules {
+		if m.ID() == *selector.mid {
+			return m
+		}
+	}
+	return modules[0]
+}
+
+func TestModuleAddAndRemove(t *testing.T) {
+	server := newPageServer(map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/c"},
+		"/b": {"/c"},
+		"/c": {},
+	})
+	defer server.Close()
+	snGen := module.NewSNGenertor(100, 0)
+	newDownloader := genSimpleDownloaders(1, false, snGen, t)[0]
+	sched := NewScheduler()
+	if err := sched.AddModule(newDownloader); err == nil {
+		t.Fatal("No error when add module to an uninitialized scheduler!")
+	}
+	requestArgs := genRequestArgs([]string{server.Host()}, 3)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if err := sched.AddModule(nil); err == nil {
+		t.Fatal("No error when add nil module!")
+	}
+	if err := sched.AddModule(newDownloader); err != nil {
+		t.Fatalf("An error occurs when adding module: %s", err)
+	}
+	if err := sched.AddModule(newDownloader); err == nil {
+		t.Fatal("No error when add a module repeatedly!")
+	}
+	oldDownloader := moduleArgs.Downloaders[0]
+	if err := sched.RemoveModule(oldDownloader.ID(), true); err != nil {
+		t.Fatalf("An error occurs when removing module: %s", err)
+	}
+	if err := sched.RemoveModule(newDownloader.ID(), true); err == nil {
+		t.Fatal("No error when remove the last downloader!")
+	}
+	if err := sched.RemoveModule(oldDownloader.ID(), false); err == nil {
+		t.Fatal("No error when remove a nonexistent module!")
+	}
+	if err := sched.RemoveModule("X1", false); err == nil {
+		t.Fatal("No error when remove a module with illegal MID!")
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	if !waitFor(5*time.Second, func() bool { return server.Hits("/c") > 0 }) {
+		t.Fatal("The scheduler has not finished crawling!")
+	}
+	summary := sched.Summary().Struct()
+	if len(summary.Downloaders) != 1 ||
+		summary.Downloaders[0].ID != newDownloader.ID() {
+		t.Fatalf("Inconsistent downloaders: %#v", summary.Downloaders)
+	}
+	if summary.Downloaders[0].Called == 0 {
+		t.Fatal("The added downloader has not been called!")
+	}
+	if oldDownloader.CalledCount() != 0 {
+		t.Fatalf("The removed downloader has been called! (count: %d)",
+			oldDownloader.CalledCount())
+	}
+	if err := sched.Stop(); err != nil {
+		t.Fatalf("An error occurs when stopping scheduler: %s", err)
+	}
+	if err := sched.AddModule(oldDownloader); err == nil {
+		t.Fatal("No error when add module to a stopped scheduler!")
+	}
+}
+
+func TestModuleRemoveDraining(t *testing.T) {
+	server := newPageServer(map[string][]string{"/": {}})
+	defer server.Close()
+	requestArgs := genRequestArgs([]string{server.Host()}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	snGen := module.NewSNGenertor(100, 0)
+	pipeline := &drainingPipeline{
+		Pipeline: genSimplePipelines(1, false, snGen, t)[0],
+		handling: 1,
+	}
+	if err := sched.AddModule(pipeline); err != nil {
+		t.Fatalf("An error occurs when adding module: %s", err)
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	done := make(chan error, 1)
+	go func() {
+		done <- sched.RemoveModule(pipeline.ID(), true)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("The module has been removed before it was drained! (error: %v)", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if atomic.LoadUint32(&pipeline.closed) == 1 {
+		t.Fatal("The module has been closed before it was drained!")
+	}
+	atomic.StoreUint64(&pipeline.handling, 0)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("An error occurs when removing module: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The drained module has not been removed!")
+	}
+	if atomic.LoadUint32(&pipeline.closed) != 1 {
+		t.Fatal("The removed module has not been closed!")
+	}
+	if pipelines := sched.Summary().Struct().Pipelines; len(pipelines) != 1 {
+		t.Fatalf("Inconsistent pipelines: %#v", pipelines)
+	}
+}
+
+func TestModuleRequeueWithoutDownloader(t *testing.T) {
+	server := newPageServer(map[string][]string{
+		"/":  {"/a"},
+		"/a": {},
+	})
+	defer server.Close()
+	requestArgs := genRequestArgs([]string{server.Host()}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	// 直接注销唯一的下载器，以模拟没有可用的下载器的情况。
+	mySched := sched.(*myScheduler)
+	if _, err := mySched.registrar.Unregister(moduleArgs.Downloaders[0].ID()); err != nil {
+		t.Fatalf("An error occurs when unregistering module: %s", err)
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	time.Sleep(300 * time.Millisecond)
+	if hits := server.Hits("/"); hits != 0 {
+		t.Fatalf("The request has been downloaded without downloader! (hits: %d)", hits)
+	}
+	snGen := module.NewSNGenertor(100, 0)
+	if err := sched.AddModule(genSimpleDownloaders(1, false, snGen, t)[0]); err != nil {
+		t.Fatalf("An error occurs when adding module: %s", err)
+	}
+	if !waitFor(5*time.Second, func() bool { return server.Hits("/a") > 0 }) {
+		t.Fatal("The requeued request has been lost!")
+	}
+}
+
+func TestModuleRemoveInUse(t *testing.T) {
+	requestArgs := genRequestArgs([]string{"example.com"}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+	// 使新添加的条目处理管道总是被选中。
+	var preferred module.MID
+	selector := midSelector{mid: &preferred}
+	if err := mySched.registrar.SetSelector(module.TYPE_PIPELINE, selector); err != nil {
+		t.Fatalf("An error occurs when setting selector: %s", err)
+	}
+	snGen := module.NewSNGenertor(100, 0)
+	for _, drain := range []bool{true, false} {
+		pipeline := &drainingPipeline{
+			Pipeline: genSimplePipelines(1, false, snGen, t)[0],
+		}
+		if err := sched.AddModule(pipeline); err != nil {
+			t.Fatalf("An error occurs when adding module: %s", err)
+		}
+		preferred = pipeline.ID()
+		// 获取该条目处理管道，但尚未调用它的任何方法，即其实时处理数仍为0。
+		acquired, err := mySched.acquireModule(module.TYPE_PIPELINE)
+		if err != nil || acquired.ID() != pipeline.ID() {
+			t.Fatalf("Couldn't acquire the added module! (module: %v, error: %v)",
+				acquired, err)
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- sched.RemoveModule(pipeline.ID(), drain)
+		}()
+		if drain {
+			select {
+			case err := <-done:
+				t.Fatalf("The module has been removed while it was in use! (error: %v)", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+		} else if err := <-done; err != nil {
+			t.Fatalf("An error occurs when removing module: %s", err)
+		}
+		if atomic.LoadUint32(&pipeline.closed) == 1 {
+			t.Fatalf("The module has been closed while it was in use! (drain: %v)", drain)
+		}
+		mySched.releaseModule(acquired)
+		if !waitFor(5*time.Second, func() bool {
+			return atomic.LoadUint32(&pipeline.closed) == 1
+		}) {
+			t.Fatalf("The removed module has not been closed! (drain: %v)", drain)
+		}
+		if drain {
+			if err := <-done; err != nil {
+				t.Fatalf("An error occurs when removing module: %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// pauseCheckInterval 代表暂停过程中检查各处理模块状态的时间间隔。
+const pauseCheckInterval = 10 * time.Millisecond
+
+// pauseGate 代表暂停闸门的类型。
+// 闸门关闭时，下载、分析和条目处理的流程都会在取出数据前后等待。
+type pauseGate struct {
+	// ch 代表闸门关闭时使用的通道，它会在闸门打开时被关闭。
+	// 若为nil，则说明闸门是打开的。
+	ch   chan struct{}
+	lock sync.Mutex
+}
+
+// close 用于关闭闸门。
+func (gate *pauseGate) close() {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+	if gate.ch == nil {
+		gate.ch = make(chan struct{})
+	}
+}
+
+// open 用于打开闸门，并唤醒所有正在等待的流程。
+func (gate *pauseGate) open() {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+	if gate.ch != nil {
+		close(gate.ch)
+		gate.ch = nil
+	}
+}
+
+// wait 会在闸门关闭时等待，直至闸门被打开或done被关闭。
+// 若闸门已打开，则返回true，否则返回false。
+func (gate *pauseGate) wait(done <-chan struct{}) bool {
+	gate.lock.Lock()
+	ch := gate.ch
+	gate.lock.Unlock()
+	if ch == nil {
+		return true
+	}
+	select {
+	case <-ch:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+func (sched *myScheduler) Pause() (err error) {
+	logger.Info("Pause scheduler...")
+	// 检查状态。
+	logger.Info("Check status for pause...")
+	var oldStatus Status
+	oldStatus, err =
+		sched.checkAndSetStatus(SCHED_STATUS_PAUSING)
+	defer func() {
+		sched.statusLock.Lock()
+		if err != nil {
+			sched.status = oldStatus
+		} else {
+			sched.status = SCHED_STATUS_PAUSED
+		}
+		sched.statusLock.Unlock()
+	}()
+	if err != nil {
+		return
+	}
+	sched.pauseGate.close()
+	// 等待正在处理的请求、响应和条目被处理完毕。
+	logger.Info("Wait for the handling data...")
+	for sched.handling() {
+		if sched.canceled() {
+			break
+		}
+		time.Sleep(pauseCheckInterval)
+	}
+	logger.Info("Scheduler has been paused.")
+	return nil
+}
+
+func (sched *myScheduler) Resume() (err error) {
+	logger.Info("Resume scheduler...")
+	sched.statusLock.Lock()
+	defer sched.statusLock.Unlock()
+	if sched.status != SCHED_STATUS_PAUSED {
+		return genError("the scheduler has not been paused!")
+	}
+	sched.status = SCHED_STATUS_STARTED
+	sched.pauseGate.open()
+	logger.Info("Scheduler has been resumed.")
+	return nil
+}
+
+// waitIfPaused 会在调度器被暂停时等待，直至调度器被恢复或停止。
+// 若调度器已被停止，则返回false，否则返回true。
+func (sched *myScheduler) waitIfPaused() bool {
+	return sched.pauseGate.wait(sched.ctx.Done())
+}
+
+// handling 用于判断是否有工作协程或处理模块正在处理数据。
+func (sched *myScheduler) handling() bool {
+	if sched.workers.busy() {
+		return true
+	}
+	moduleMap := sched.registrar.GetAll()
+	for _, m := range moduleMap {
+		if m.HandlingNumber() > 0 {
+			return true
+		}
+	}
+	return false
+}
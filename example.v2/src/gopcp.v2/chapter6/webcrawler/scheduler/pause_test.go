@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPauseAndResume(t *testing.T) {
+	server := newPageServer(map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/c"},
+		"/b": {"/c"},
+		"/c": {},
+	})
+	defer server.Close()
+	requestArgs := genRequestArgs([]string{server.Host()}, 3)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if err := sched.Pause(); err == nil {
+		t.Fatal("No error when pause an unstarted scheduler!")
+	}
+	if err := sched.Resume(); err == nil {
+		t.Fatal("No error when resume an unpaused scheduler!")
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	if err := sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_PAUSED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_PAUSED),
+			GetStatusDescription(status))
+	}
+	if err := sched.Pause(); err == nil {
+		t.Fatal("No error when pause a paused scheduler!")
+	}
+	if err := sched.Start(httpReq); err == nil {
+		t.Fatal("No error when start a paused scheduler!")
+	}
+	paths := []string{"/", "/a", "/b", "/c"}
+	var hits int
+	for _, p := range paths {
+		hits += server.Hits(p)
+	}
+	time.Sleep(200 * time.Millisecond)
+	var newHits int
+	for _, p := range paths {
+		newHits += server.Hits(p)
+	}
+	if newHits != hits {
+		t.Fatalf("The paused scheduler is still downloading! (hits: %d, new hits: %d)",
+			hits, newHits)
+	}
+	if err := sched.Resume(); err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STARTED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STARTED),
+			GetStatusDescription(status))
+	}
+	if !waitFor(5*time.Second, func() bool { return server.Hits("/c") > 0 }) {
+		t.Fatal("The resumed scheduler has not finished crawling!")
+	}
+	for _, p := range paths {
+		if hits := server.Hits(p); hits != 1 {
+			t.Fatalf("Inconsistent hits: expected: %d, actual: %d (path: %s)",
+				1, hits, p)
+		}
+	}
+	if err := sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+	if err := sched.Stop(); err != nil {
+		t.Fatalf("An error occurs when stopping a paused scheduler: %s", err)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STOPPED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STOPPED),
+			GetStatusDescription(status))
+	}
+}
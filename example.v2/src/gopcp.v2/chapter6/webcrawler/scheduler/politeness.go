@@ -0,0 +1,348 @@
+package scheduler
+
+import (
+	"container/heap"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// 默认的退避时间。
+const (
+	// defaultBackoffBase 代表默认的初始退避时间。
+	defaultBackoffBase = time.Second
+	// defaultBackoffMax 代表默认的退避时间上限。
+	defaultBackoffMax = time.Minute
+)
+
+// errClosedHostDispatcher 代表主机分发器已关闭的错误。
+var errClosedHostDispatcher = errors.New("closed host dispatcher")
+
+// HostQueueSummaryStruct 代表单个主机的请求队列的摘要类型。
+type HostQueueSummaryStruct struct {
+	// Host 代表主机名（可能包含端口）。
+	Host string `json:"host"`
+	// Queued 代表正在排队的请求的数量。
+	Queued uint64 `json:"queued"`
+	// Active 代表正在下载的请求的数量。
+	Active uint32 `json:"active"`
+}
+
+// hostState 代表单个主机的调度状态。
+type hostState struct {
+	// queue 代表该主机的请求队列，其中的请求与URL边界中的顺序一致。
+	queue frontierHeap
+	// active 代表正在下载的请求的数量。
+	active uint32
+	// lastStart 代表最近一次开始下载的时间。
+	lastStart time.Time
+	// crawlDelay 代表该主机额外要求的请求间隔，例如robots.txt中的Crawl-delay。
+	crawlDelay time.Duration
+	// backoff 代表当前的退避时间。
+	backoff time.Duration
+	// backoffUntil 代表退避结束的时间。
+	backoffUntil time.Time
+}
+
+// hostDispatcher 代表按主机调度请求的分发器。
+// 它位于URL边界与下载器之间，
+// 用于保证对同一主机的请求满足最小间隔、最大并发数和退避等约束。
+// 在满足约束的前提下，请求会按照与URL边界相同的顺序被取出。
+type hostDispatcher struct {
+	// args 代表礼貌性爬取的参数。
+	args PolitenessArgs
+	// less 代表判断请求先后的函数，它与URL边界所用的相同。
+	less func(a, b *frontierEntry) bool
+	// scoreFunc 代表请求分数的计算函数，可以为nil。
+	scoreFunc ScoreFunc
+	// seq 代表下一个请求的序号。
+	seq uint64
+	// capacity 代表可以排队的请求的最大总数。
+	capacity uint64
+	// total 代表正在排队的请求的总数。
+	total uint64
+	// hosts 代表主机名与其调度状态的映射。
+	hosts map[string]*hostState
+	// changed 会在状态变化时被关闭，用于唤醒所有等待者。
+	changed chan struct{}
+	// lock 代表保护以上字段的互斥锁。
+	lock sync.Mutex
+}
+
+// newHostDispatcher 用于创建一个主机分发器。
+// 参数frontierType和scoreFunc与创建URL边界时所用的相同，
+// 它们决定了请求被取出的顺序。
+func newHostDispatcher(
+	args PolitenessArgs,
+	capacity uint32,
+	frontierType FrontierType,
+	scoreFunc ScoreFunc) *hostDispatcher {
+	if capacity == 0 {
+		capacity = 1
+	}
+	hd := &hostDispatcher{
+		args:     args,
+		capacity: uint64(capacity),
+		hosts:    map[string]*hostState{},
+		changed:  make(chan struct{}),
+	}
+	hd.less, hd.scoreFunc = frontierOrder(frontierType, scoreFunc)
+	return hd
+}
+
+// hostOf 用于获取请求对应的主机名。
+func hostOf(req *module.Request) string {
+	if req == nil || !req.Valid() {
+		return ""
+	}
+	return strings.ToLower(req.HTTPReq().URL.Host)
+}
+
+// put 用于把请求放入对应主机的队列。
+// 若排队的请求总数已达上限，则会阻塞直至有空位或done被关闭。
+func (hd *hostDispatcher) put(done <-chan struct{}, req *module.Request) error {
+	host := hostOf(req)
+	entry := &frontierEntry{req: req}
+	if hd.scoreFunc != nil {
+		entry.score = hd.scoreFunc(req)
+	}
+	for {
+		hd.lock.Lock()
+		if hd.total < hd.capacity {
+			st := hd.stateLocked(host)
+			entry.seq = hd.seq
+			hd.seq++
+			heap.Push(&st.queue, entry)
+			hd.total++
+			hd.notifyLocked()
+			hd.lock.Unlock()
+			return nil
+		}
+		changed := hd.changed
+		hd.lock.Unlock()
+		select {
+		case <-done:
+			return errClosedHostDispatcher
+		case <-changed:
+		}
+	}
+}
+
+// get 用于获取下一个可以被下载的请求。
+// 若暂时没有满足约束的请求，则会阻塞直至有或done被关闭。
+// 获取到的请求在下载完成后必须调用finish方法。
+func (hd *hostDispatcher) get(done <-chan struct{}) (*module.Request, error) {
+	for {
+		hd.lock.Lock()
+		req, wait := hd.pickLocked(time.Now())
+		if req != nil {
+			hd.notifyLocked()
+			hd.lock.Unlock()
+			return req, nil
+		}
+		changed := hd.changed
+		hd.lock.Unlock()
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil, errClosedHostDispatcher
+		case <-changed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// finish 用于告知分发器某个请求已下载完毕。
+// 参数httpResp可以为nil。若其状态码为429或503，则会对该主机进行退避。
+func (hd *hostDispatcher) finish(req *module.Request, httpResp *http.Response) {
+	host := hostOf(req)
+	hd.lock.Lock()
+	defer hd.lock.Unlock()
+	st, ok := hd.hosts[host]
+	if !ok {
+		return
+	}
+	if st.active > 0 {
+		st.active--
+	}
+	if httpResp != nil {
+		switch httpResp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			st.backoff = hd.nextBackoff(st.backoff)
+			wait := st.backoff
+			if retryAfter, ok := parseRetryAfter(httpResp.Header.Get("Retry-After")); ok &&
+				retryAfter > wait {
+				wait = retryAfter
+			}
+			st.backoffUntil = time.Now().Add(wait)
+			logger.Warnf("Back off host %q for %s. (status code: %d)",
+				host, wait, httpResp.StatusCode)
+		default:
+			st.backoff = 0
+		}
+	}
+	hd.notifyLocked()
+}
+
+// setCrawlDelay 用于设置指定主机额外要求的请求间隔。
+func (hd *hostDispatcher) setCrawlDelay(host string, delay time.Duration) {
+	hd.lock.Lock()
+	defer hd.lock.Unlock()
+	hd.stateLocked(strings.ToLower(host)).crawlDelay = delay
+	hd.notifyLocked()
+}
+
+// Total 用于获取正在排队的请求的总数。
+func (hd *hostDispatcher) Total() uint64 {
+	hd.lock.Lock()
+	defer hd.lock.Unlock()
+	return hd.total
+}
+
+// summary 用于获取各主机请求队列的摘要，结果会按主机名排序。
+func (hd *hostDispatcher) summary() []HostQueueSummaryStruct {
+	hd.lock.Lock()
+	summaries := []HostQueueSummaryStruct{}
+	for host, st := range hd.hosts {
+		if st.queue.Len() == 0 && st.active == 0 {
+			continue
+		}
+		summaries = append(summaries, HostQueueSummaryStruct{
+			Host:   host,
+			Queued: uint64(st.queue.Len()),
+			Active: st.active,
+		})
+	}
+	hd.lock.Unlock()
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Host < summaries[j].Host
+	})
+	return summaries
+}
+
+// stateLocked 用于获取指定主机的调度状态，不存在时会新建。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (hd *hostDispatcher) stateLocked(host string) *hostState {
+	st, ok := hd.hosts[host]
+	if !ok {
+		st = &hostState{queue: frontierHeap{less: hd.less}}
+		hd.hosts[host] = st
+	}
+	return st
+}
+
+// pickLocked 用于挑选一个满足约束的请求。
+// 在所有满足约束的主机的队首请求中，会挑选在URL边界的顺序中最靠前的那个。
+// 若没有，则第二个结果值代表最少需要等待的时间，0代表需等待状态变化。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (hd *hostDispatcher) pickLocked(now time.Time) (*module.Request, time.Duration) {
+	var selected *hostState
+	var minWait time.Duration
+	for host, st := range hd.hosts {
+		readyAt := hd.readyAt(st)
+		if st.queue.Len() == 0 {
+			if st.active == 0 && st.crawlDelay == 0 && !now.Before(readyAt) {
+				delete(hd.hosts, host)
+			}
+			continue
+		}
+		if hd.args.MaxConnsPerHost > 0 && st.active >= hd.args.MaxConnsPerHost {
+			continue
+		}
+		if now.Before(readyAt) {
+			wait := readyAt.Sub(now)
+			if minWait == 0 || wait < minWait {
+				minWait = wait
+			}
+			continue
+		}
+		if selected == nil || hd.less(st.queue.items[0], selected.queue.items[0]) {
+			selected = st
+		}
+	}
+	if selected == nil {
+		return nil, minWait
+	}
+	req := heap.Pop(&selected.queue).(*frontierEntry).req
+	selected.active++
+	selected.lastStart = now
+	hd.total--
+	return req, 0
+}
+
+// readyAt 用于计算指定主机下一次可以开始下载的时间。
+func (hd *hostDispatcher) readyAt(st *hostState) time.Time {
+	delay := hd.args.MinDelay
+	if st.crawlDelay > delay {
+		delay = st.crawlDelay
+	}
+	readyAt := st.lastStart.Add(delay)
+	if st.backoffUntil.After(readyAt) {
+		readyAt = st.backoffUntil
+	}
+	return readyAt
+}
+
+// nextBackoff 用于根据当前的退避时间计算下一次的退避时间。
+func (hd *hostDispatcher) nextBackoff(current time.Duration) time.Duration {
+	base := hd.args.BackoffBase
+	if base <= 0 {
+		base = defaultBackoffBase
+	}
+	max := hd.args.BackoffMax
+	if max <= 0 {
+		max = defaultBackoffMax
+	}
+	next := base
+	if current > 0 {
+		next = current * 2
+	}
+	if next > max {
+		next = max
+	}
+	return next
+}
+
+// notifyLocked 用于唤醒所有等待状态变化的调用方。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (hd *hostDispatcher) notifyLocked() {
+	close(hd.changed)
+	hd.changed = make(chan struct{})
+}
+
+// parseRetryAfter 用于解析HTTP响应头中的Retry-After。
+// 它可以是秒数，也可以是HTTP日期。
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// genTestingRequest 用于生成测试用的请求。
+func genTestingRequest(rawURL string, t *testing.T) *module.Request {
+	httpReq, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)",
+			err, rawURL)
+	}
+	return module.NewRequest(httpReq, 0)
+}
+
+func TestPolitenessArgs(t *testing.T) {
+	validArgsList := []PolitenessArgs{
+		{},
+		{MinDelay: time.Second, MaxConnsPerHost: 2},
+		{BackoffBase: time.Second, BackoffMax: time.Minute},
+	}
+	for _, args := range validArgsList {
+		if err := args.Check(); err != nil {
+			t.Fatalf("An error occurs when checking politeness arguments: %s (args: %#v)",
+				err, args)
+		}
+	}
+	invalidArgsList := []PolitenessArgs{
+		{MinDelay: -1},
+		{BackoffBase: -1},
+		{BackoffMax: -1},
+		{BackoffBase: time.Minute, BackoffMax: time.Second},
+	}
+	for _, args := range invalidArgsList {
+		if err := args.Check(); err == nil {
+			t.Fatalf("No error when check politeness arguments! (args: %#v)", args)
+		}
+	}
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.Politeness = invalidArgsList[0]
+	if err := requestArgs.Check(); err == nil {
+		t.Fatalf("No error when check request arguments with invalid politeness! (args: %#v)",
+			requestArgs)
+	}
+	another := genRequestArgs([]string{}, 0)
+	if requestArgs.Same(&another) {
+		t.Fatal("Same request arguments with different politeness!")
+	}
+}
+
+func TestPolitenessMinDelay(t *testing.T) {
+	delay := 100 * time.Millisecond
+	hd := newHostDispatcher(PolitenessArgs{MinDelay: delay}, 10, FRONTIER_TYPE_BFS, nil)
+	done := make(chan struct{})
+	defer close(done)
+	urls := []string{
+		"http://a.com/1",
+		"http://a.com/2",
+		"http://b.com/1",
+	}
+	for _, u := range urls {
+		if err := hd.put(done, genTestingRequest(u, t)); err != nil {
+			t.Fatalf("An error occurs when putting request: %s", err)
+		}
+	}
+	if hd.Total() != uint64(len(urls)) {
+		t.Fatalf("Inconsistent total: expected: %d, actual: %d",
+			len(urls), hd.Total())
+	}
+	start := time.Now()
+	hosts := map[string]time.Time{}
+	var order []string
+	for range urls {
+		req, err := hd.get(done)
+		if err != nil {
+			t.Fatalf("An error occurs when getting request: %s", err)
+		}
+		host := hostOf(req)
+		if last, ok := hosts[host]; ok {
+			if elapsed := time.Since(last); elapsed < delay {
+				t.Fatalf("The min delay is not honored: expected: >= %s, actual: %s (host: %s)",
+					delay, elapsed, host)
+			}
+		}
+		hosts[host] = time.Now()
+		order = append(order, req.HTTPReq().URL.String())
+		hd.finish(req, nil)
+	}
+	if order[2] != "http://a.com/2" {
+		t.Fatalf("The request of another host is blocked by a delayed host! (order: %v)",
+			order)
+	}
+	if elapsed := time.Since(start); elapsed > 5*delay {
+		t.Fatalf("Too long to dispatch requests: %s", elapsed)
+	}
+}
+
+func TestPolitenessMaxConns(t *testing.T) {
+	hd := newHostDispatcher(PolitenessArgs{MaxConnsPerHost: 1}, 10, FRONTIER_TYPE_BFS, nil)
+	done := make(chan struct{})
+	defer close(done)
+	hd.put(done, genTestingRequest("http://a.com/1", t))
+	hd.put(done, genTestingRequest("http://a.com/2", t))
+	first, _ := hd.get(done)
+	summaries := hd.summary()
+	if len(summaries) != 1 || summaries[0].Active != 1 || summaries[0].Queued != 1 {
+		t.Fatalf("Inconsistent host queue summary: %#v", summaries)
+	}
+	got := make(chan *module.Request, 1)
+	go func() {
+		req, _ := hd.get(done)
+		got <- req
+	}()
+	select {
+	case req := <-got:
+		t.Fatalf("The max connections is not honored! (request: %s)",
+			req.HTTPReq().URL)
+	case <-time.After(100 * time.Millisecond):
+	}
+	hd.finish(first, nil)
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("Couldn't get the request after the previous one finished!")
+	}
+}
+
+func TestPolitenessBackoff(t *testing.T) {
+	args := PolitenessArgs{
+		BackoffBase: 50 * time.Millisecond,
+		BackoffMax:  time.Second,
+	}
+	hd := newHostDispatcher(args, 10, FRONTIER_TYPE_BFS, nil)
+	done := make(chan struct{})
+	defer close(done)
+	hd.put(done, genTestingRequest("http://a.com/1", t))
+	hd.put(done, genTestingRequest("http://a.com/2", t))
+	req, _ := hd.get(done)
+	httpResp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"1"}},
+	}
+	hd.finish(req, httpResp)
+	start := time.Now()
+	req, _ = hd.get(done)
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Fatalf("The Retry-After is not honored: expected: >= %s, actual: %s",
+			time.Second, elapsed)
+	}
+	hd.finish(req, &http.Response{StatusCode: http.StatusOK})
+	if backoff := hd.nextBackoff(0); backoff != args.BackoffBase {
+		t.Fatalf("Inconsistent backoff: expected: %s, actual: %s",
+			args.BackoffBase, backoff)
+	}
+	if backoff := hd.nextBackoff(800 * time.Millisecond); backoff != args.BackoffMax {
+		t.Fatalf("Inconsistent backoff: expected: %s, actual: %s",
+			args.BackoffMax, backoff)
+	}
+}
+
+func TestPolitenessClose(t *testing.T) {
+	hd := newHostDispatcher(PolitenessArgs{}, 1, FRONTIER_TYPE_BFS, nil)
+	done := make(chan struct{})
+	hd.put(done, genTestingRequest("http://a.com/1", t))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- hd.put(done, genTestingRequest("http://a.com/2", t))
+	}()
+	select {
+	case err := <-errCh:
+		t.Fatalf("The capacity is not honored! (err: %v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(done)
+	if err := <-errCh; err == nil {
+		t.Fatal("No error when putting request to a closed host dispatcher!")
+	}
+	if _, err := hd.get(done); err != nil {
+		t.Fatalf("An error occurs when getting the queued request: %s", err)
+	}
+	if _, err := hd.get(done); err == nil {
+		t.Fatal("No error when getting request from a closed host dispatcher!")
+	}
+}
+
+func TestPolitenessRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("120"); !ok || d != 2*time.Minute {
+		t.Fatalf("Inconsistent Retry-After: expected: %s, actual: %s",
+			2*time.Minute, d)
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(future); !ok || d < 59*time.Minute {
+		t.Fatalf("Inconsistent Retry-After: expected: about %s, actual: %s",
+			time.Hour, d)
+	}
+	for _, v := range []string{"", "abc"} {
+		if _, ok := parseRetryAfter(v); ok {
+			t.Fatalf("It still can parse invalid Retry-After %q!", v)
+		}
+	}
+}
+
+func TestPolitenessOrder(t *testing.T) {
+	scoreFunc := func(req *module.Request) float64 {
+		path := req.HTTPReq().URL.Path
+		return float64(path[len(path)-1] - '0')
+	}
+	args := PolitenessArgs{MinDelay: 50 * time.Millisecond}
+	hd := newHostDispatcher(args, 10, FRONTIER_TYPE_PRIORITY, scoreFunc)
+	done := make(chan struct{})
+	defer close(done)
+	urls := []string{
+		"http://a.com/1",
+		"http://a.com/3",
+		"http://b.com/2",
+		"http://a.com/2",
+	}
+	for _, u := range urls {
+		hd.put(done, genTestingRequest(u, t))
+	}
+	expectedOrder := []string{
+		"http://a.com/3",
+		"http://b.com/2",
+		"http://a.com/2",
+		"http://a.com/1",
+	}
+	for i, expected := range expectedOrder {
+		req, err := hd.get(done)
+		if err != nil {
+			t.Fatalf("An error occurs when getting request: %s", err)
+		}
+		if actual := req.HTTPReq().URL.String(); actual != expected {
+			t.Fatalf("Inconsistent request order: expected: %s, actual: %s (index: %d)",
+				expected, actual, i)
+		}
+		hd.finish(req, nil)
+	}
+}
@@ -0,0 +1,244 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// 默认的重试退避时间。
+const (
+	// defaultRetryBackoffBase 代表默认的首次重试前的退避时间。
+	defaultRetryBackoffBase = 500 * time.Millisecond
+	// defaultRetryBackoffMax 代表默认的重试退避时间的上限。
+	defaultRetryBackoffMax = 30 * time.Second
+)
+
+// requeueDelay 代表没有可用的下载器时请求被放回URL边界之前的等待时间。
+const requeueDelay = 100 * time.Millisecond
+
+// maxDeadLetterNumber 代表死信列表中最多保留的条目的数量。
+const maxDeadLetterNumber = 1000
+
+// RetryArgs 代表下载重试相关的参数容器的类型。
+type RetryArgs struct {
+	// MaxAttempts 代表每个请求最多被尝试下载的次数（包括首次下载）。
+	// 若为0或1，则不会重试。
+	MaxAttempts uint32 `json:"max_attempts"`
+	// BackoffBase 代表首次重试前的退避时间。
+	// 之后每次重试都会使退避时间加倍。若为0，则使用默认值。
+	BackoffBase time.Duration `json:"backoff_base"`
+	// BackoffMax 代表退避时间的上限。若为0，则使用默认值。
+	BackoffMax time.Duration `json:"backoff_max"`
+	// Jitter 代表退避时间的随机抖动比例，取值范围为[0, 1]。
+	// 实际的退避时间会在[(1-Jitter)*退避时间, 退避时间]之间随机选取。
+	Jitter float64 `json:"jitter"`
+}
+
+func (args *RetryArgs) Check() error {
+	if args.BackoffBase < 0 {
+		return genError("negative retry backoff base")
+	}
+	if args.BackoffMax < 0 {
+		return genError("negative max retry backoff")
+	}
+	if args.BackoffBase > 0 && args.BackoffMax > 0 &&
+		args.BackoffBase > args.BackoffMax {
+		return genError("retry backoff base is greater than max retry backoff")
+	}
+	if args.Jitter < 0 || args.Jitter > 1 {
+		return genError(fmt.Sprintf("illegal retry jitter: %f", args.Jitter))
+	}
+	return nil
+}
+
+// backoff 用于计算第attempt次重试前的退避时间，attempt从0开始。
+func (args *RetryArgs) backoff(attempt uint32) time.Duration {
+	base := args.BackoffBase
+	if base <= 0 {
+		base = defaultRetryBackoffBase
+	}
+	max := args.BackoffMax
+	if max <= 0 {
+		max = defaultRetryBackoffMax
+	}
+	backoff := base
+	for i := uint32(0); i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	if args.Jitter > 0 {
+		backoff -= time.Duration(args.Jitter * rand.Float64() * float64(backoff))
+	}
+	return backoff
+}
+
+// isRetryableError 用于判断下载时发生的错误是否值得重试。
+// 超时、连接被重置或拒绝以及连接意外断开都被视为可重试的错误。
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, target := range []error{
+		syscall.ECONNRESET,
+		syscall.ECONNABORTED,
+		syscall.ECONNREFUSED,
+		syscall.EPIPE,
+		io.ErrUnexpectedEOF,
+		io.EOF,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryableStatus 用于判断HTTP响应的状态码是否值得重试。
+// 状态码为429或5xx的响应都被视为可重试的。
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// DeadLetterStruct 代表死信（即最终下载失败的请求）的类型。
+type DeadLetterStruct struct {
+	// URL 代表请求的URL。
+	URL string `json:"url"`
+	// Depth 代表请求的深度。
+	Depth uint32 `json:"depth"`
+	// Attempts 代表请求被尝试下载的次数。
+	Attempts uint32 `json:"attempts"`
+	// Reason 代表最后一次下载失败的原因。
+	Reason string `json:"reason"`
+}
+
+// deadLetterList 代表死信列表的类型。
+type deadLetterList struct {
+	// letters 代表死信的列表，最多保留maxDeadLetterNumber个。
+	letters []DeadLetterStruct
+	lock    sync.Mutex
+}
+
+// add 用于添加死信。若列表已满，则会丢弃最早的死信。
+func (list *deadLetterList) add(letter DeadLetterStruct) {
+	list.lock.Lock()
+	defer list.lock.Unlock()
+	if len(list.letters) >= maxDeadLetterNumber {
+		list.letters = list.letters[1:]
+	}
+	list.letters = append(list.letters, letter)
+}
+
+// summary 用于获取死信列表的副本。
+func (list *deadLetterList) summary() []DeadLetterStruct {
+	list.lock.Lock()
+	defer list.lock.Unlock()
+	letters := make([]DeadLetterStruct, len(list.letters))
+	copy(letters, list.letters)
+	return letters
+}
+
+// retryIfNeeded 会在下载因可重试的原因失败且还可以重试时安排重试，
+// 而在下载最终失败时把请求加入死信列表。
+// 最终失败包括重试次数用尽、未启用重试以及因不可重试的原因
+// （例如DNS错误、内容类型不被允许或状态码为4xx）而失败。
+// 参数resp和err代表下载的结果。
+// 结果值retrying代表是否已安排重试。
+// 结果值exhausted代表请求是否已用尽重试次数，
+// 此时响应体已被关闭，响应不应再被分析。
+// 其他最终失败的响应仍然会被分析。
+func (sched *myScheduler) retryIfNeeded(
+	req *module.Request, resp *module.Response, err error) (retrying bool, exhausted bool) {
+	var reason string
+	var retryable bool
+	var wait time.Duration
+	switch {
+	case err != nil:
+		reason = err.Error()
+		retryable = isRetryableError(err)
+	case resp != nil && resp.HTTPResp() != nil &&
+		resp.HTTPResp().StatusCode >= 400:
+		httpResp := resp.HTTPResp()
+		reason = fmt.Sprintf("unexpected status code %d", httpResp.StatusCode)
+		retryable = isRetryableStatus(httpResp.StatusCode)
+		if retryAfter, ok := parseRetryAfter(httpResp.Header.Get("Retry-After")); ok {
+			wait = retryAfter
+		}
+	default:
+		return false, false
+	}
+	args := sched.requestArgs.Retry
+	if !retryable || args.MaxAttempts <= 1 {
+		sched.addDeadLetter(req, reason)
+		return false, false
+	}
+	if resp != nil && resp.HTTPResp().Body != nil {
+		resp.HTTPResp().Body.Close()
+	}
+	if req.Attempt()+1 >= args.MaxAttempts {
+		sched.addDeadLetter(req, reason)
+		return false, true
+	}
+	if backoff := args.backoff(req.Attempt()); backoff > wait {
+		wait = backoff
+	}
+	logger.Warnf("Retry the request after %s! (URL: %s, attempt: %d, reason: %s)",
+		wait, req.HTTPReq().URL, req.Attempt()+1, reason)
+	sched.scheduleRetry(req.NextAttempt(), wait)
+	return true, false
+}
+
+// scheduleRetry 会在等待给定的时间之后把请求重新放入URL边界。
+// 在此期间请求仍然会被视为尚未处理完毕。
+func (sched *myScheduler) scheduleRetry(req *module.Request, wait time.Duration) {
+	atomic.AddUint64(&sched.numRetried, 1)
+	sched.requeue(req, wait)
+}
+
+// requeue 会在等待给定的时间之后把请求原样放回URL边界。
+// 在此期间请求仍然会被视为尚未处理完毕。
+func (sched *myScheduler) requeue(req *module.Request, wait time.Duration) {
+	atomic.AddInt64(&sched.pendingRetries, 1)
+	sched.pendingReqMap.Put(sched.urlKey(req.HTTPReq().URL), req)
+	go func() {
+		defer atomic.AddInt64(&sched.pendingRetries, -1)
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-sched.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if sched.discardIfDraining(req) {
+			return
+		}
+		if err := sched.frontier.Put(req); err != nil {
+			logger.Warnln("The frontier was closed. Ignore request requeueing.")
+		}
+	}()
+}
+
+// addDeadLetter 用于把最终下载失败的请求加入死信列表。
+func (sched *myScheduler) addDeadLetter(req *module.Request, reason string) {
+	sched.deadLetters.add(DeadLetterStruct{
+		URL:      req.HTTPReq().URL.String(),
+		Depth:    req.Depth(),
+		Attempts: req.Attempt() + 1,
+		Reason:   reason,
+	})
+}
@@ -0,0 +1,335 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module/local/downloader"
+)
+
+// timeoutError 代表测试用的超时错误。
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRetryArgs(t *testing.T) {
+	legalArgsList := []RetryArgs{
+		{},
+		{MaxAttempts: 3, BackoffBase: time.Second, BackoffMax: time.Minute, Jitter: 0.5},
+	}
+	for _, args := range legalArgsList {
+		if err := args.Check(); err != nil {
+			t.Fatalf("An error occurs when checking retry arguments: %s (args: %#v)",
+				err, args)
+		}
+	}
+	illegalArgsList := []RetryArgs{
+		{BackoffBase: -1},
+		{BackoffMax: -1},
+		{BackoffBase: time.Minute, BackoffMax: time.Second},
+		{Jitter: -0.1},
+		{Jitter: 1.1},
+	}
+	for _, args := range illegalArgsList {
+		if err := args.Check(); err == nil {
+			t.Fatalf("No error when checking illegal retry arguments! (args: %#v)",
+				args)
+		}
+	}
+	args := RetryArgs{BackoffBase: 100 * time.Millisecond, BackoffMax: time.Second}
+	expectedBackoffs := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for attempt, expected := range expectedBackoffs {
+		if backoff := args.backoff(uint32(attempt)); backoff != expected {
+			t.Fatalf("Inconsistent backoff for attempt %d: expected: %s, actual: %s",
+				attempt, expected, backoff)
+		}
+	}
+	args.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		backoff := args.backoff(1)
+		if backoff < 100*time.Millisecond || backoff > 200*time.Millisecond {
+			t.Fatalf("The backoff with jitter is out of range: %s", backoff)
+		}
+	}
+}
+
+func TestRetryClassify(t *testing.T) {
+	retryableErrors := []error{
+		timeoutError{},
+		&url.Error{Op: "Get", URL: "http://example.com", Err: timeoutError{}},
+		&net.OpError{Op: "read", Net: "tcp",
+			Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET}},
+		&url.Error{Op: "Get", URL: "http://example.com", Err: io.EOF},
+		io.ErrUnexpectedEOF,
+	}
+	for _, err := range retryableErrors {
+		if !isRetryableError(err) {
+			t.Fatalf("The error should be retryable! (error: %#v)", err)
+		}
+	}
+	unretryableErrors := []error{
+		nil,
+		errors.New("unsupported protocol scheme"),
+		&url.Error{Op: "Get", URL: "http://example.com", Err: errors.New("no such host")},
+	}
+	for _, err := range unretryableErrors {
+		if isRetryableError(err) {
+			t.Fatalf("The error should not be retryable! (error: %#v)", err)
+		}
+	}
+	statusMap := map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusNotFound:            false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+	}
+	for statusCode, expected := range statusMap {
+		if retryable := isRetryableStatus(statusCode); retryable != expected {
+			t.Fatalf("Inconsistent retryability for status code %d: expected: %v, actual: %v",
+				statusCode, expected, retryable)
+		}
+	}
+}
+
+func TestRetryDownload(t *testing.T) {
+	var lock sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		hits[r.URL.Path]++
+		hit := hits[r.URL.Path]
+		lock.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<html><body><a href="/flaky">flaky</a><a href="/dead">dead</a></body></html>`)
+		case "/flaky":
+			if hit < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><body></body></html>")
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	requestArgs := genRequestArgs([]string{host}, 1)
+	requestArgs.Politeness = PolitenessArgs{
+		BackoffBase: time.Millisecond,
+		BackoffMax:  time.Millisecond,
+	}
+	requestArgs.Retry = RetryArgs{
+		MaxAttempts: 3,
+		BackoffBase: 10 * time.Millisecond,
+		BackoffMax:  50 * time.Millisecond,
+	}
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1),
+		genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	hitsOf := func(path string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return hits[path]
+	}
+	ok := waitFor(10*time.Second, func() bool {
+		return hitsOf("/flaky") >= 3 && hitsOf("/dead") >= 3 && sched.Idle()
+	})
+	if !ok {
+		t.Fatalf("The failed requests have not been retried! (hits: flaky: %d, dead: %d)",
+			hitsOf("/flaky"), hitsOf("/dead"))
+	}
+	for _, path := range []string{"/flaky", "/dead"} {
+		if hit := hitsOf(path); hit != 3 {
+			t.Fatalf("Inconsistent hits: expected: %d, actual: %d (path: %s)",
+				3, hit, path)
+		}
+	}
+	summary := sched.Summary().Struct()
+	if summary.NumRetried != 4 {
+		t.Fatalf("Inconsistent retried number: expected: %d, actual: %d",
+			4, summary.NumRetried)
+	}
+	if len(summary.DeadLetters) != 1 {
+		t.Fatalf("Inconsistent dead letter number: expected: %d, actual: %d",
+			1, len(summary.DeadLetters))
+	}
+	letter := summary.DeadLetters[0]
+	if letter.URL != server.URL+"/dead" || letter.Depth != 1 || letter.Attempts != 3 {
+		t.Fatalf("Inconsistent dead letter: %#v", letter)
+	}
+	// 被加入死信列表的响应不会再被分析。
+	if called := summary.Analyzers[0].Called; called != 2 {
+		t.Fatalf("Inconsistent analyzer called count: expected: %d, actual: %d",
+			2, called)
+	}
+}
+
+func TestRetryDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<html><body><a href="/dead">dead</a><a href="http://127.0.0.1:1/">refused</a></body></html>`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	requestArgs := genRequestArgs([]string{host, "127.0.0.1:1"}, 1)
+	requestArgs.Politeness = PolitenessArgs{
+		BackoffBase: time.Millisecond,
+		BackoffMax:  time.Millisecond,
+	}
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1),
+		genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	ok := waitFor(10*time.Second, func() bool {
+		return sched.Summary().Struct().Downloaders[0].Called >= 3 && sched.Idle()
+	})
+	if !ok {
+		t.Fatal("The scheduler has not finished crawling!")
+	}
+	summary := sched.Summary().Struct()
+	if summary.NumRetried != 0 {
+		t.Fatalf("Some requests have been retried when retrying is disabled! (retried: %d)",
+			summary.NumRetried)
+	}
+	// 未启用重试时，下载失败的请求会直接被加入死信列表。
+	if len(summary.DeadLetters) != 2 {
+		t.Fatalf("Inconsistent dead letter number: expected: %d, actual: %d (dead letters: %#v)",
+			2, len(summary.DeadLetters), summary.DeadLetters)
+	}
+	for _, letter := range summary.DeadLetters {
+		if letter.Attempts != 1 || letter.Reason == "" {
+			t.Fatalf("Inconsistent dead letter: %#v", letter)
+		}
+	}
+}
+
+func TestRetryNonRetryable(t *testing.T) {
+	var lock sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		hits[r.URL.Path]++
+		lock.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<html><body><a href="/missing">missing</a><a href="/image">image</a></body></html>`)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "PNG")
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	requestArgs := genRequestArgs([]string{host}, 1)
+	requestArgs.Politeness = PolitenessArgs{
+		BackoffBase: time.Millisecond,
+		BackoffMax:  time.Millisecond,
+	}
+	requestArgs.Retry = RetryArgs{
+		MaxAttempts: 3,
+		BackoffBase: 10 * time.Millisecond,
+		BackoffMax:  50 * time.Millisecond,
+	}
+	moduleArgs := genSimpleModuleArgs(1, 1, 1, t)
+	d, err := downloader.New(moduleArgs.Downloaders[0].ID(), &http.Client{}, nil,
+		downloader.WithAllowedContentTypes("text/html"))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", err)
+	}
+	moduleArgs.Downloaders[0] = d
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1), moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	ok := waitFor(10*time.Second, func() bool {
+		return len(sched.Summary().Struct().DeadLetters) >= 2 && sched.Idle()
+	})
+	if !ok {
+		t.Fatal("The failed requests have not been dead-lettered!")
+	}
+	summary := sched.Summary().Struct()
+	if summary.NumRetried != 0 {
+		t.Fatalf("Some non-retryable requests have been retried! (retried: %d)",
+			summary.NumRetried)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	for _, path := range []string{"/missing", "/image"} {
+		if hit := hits[path]; hit != 1 {
+			t.Fatalf("Inconsistent hits: expected: %d, actual: %d (path: %s)",
+				1, hit, path)
+		}
+	}
+	if len(summary.DeadLetters) != 2 {
+		t.Fatalf("Inconsistent dead letter number: expected: %d, actual: %d (dead letters: %#v)",
+			2, len(summary.DeadLetters), summary.DeadLetters)
+	}
+	reasons := map[string]string{}
+	for _, letter := range summary.DeadLetters {
+		if letter.Attempts != 1 {
+			t.Fatalf("Inconsistent dead letter: %#v", letter)
+		}
+		reasons[letter.URL] = letter.Reason
+	}
+	if reason := reasons[server.URL+"/missing"]; !strings.Contains(reason, "404") {
+		t.Fatalf("Inconsistent dead letter reason: %q (URL: %s)",
+			reason, server.URL+"/missing")
+	}
+	if reason := reasons[server.URL+"/image"]; !strings.Contains(reason, "image/png") {
+		t.Fatalf("Inconsistent dead letter reason: %q (URL: %s)",
+			reason, server.URL+"/image")
+	}
+}
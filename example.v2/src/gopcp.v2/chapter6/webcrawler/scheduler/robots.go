@@ -0,0 +1,435 @@
+package scheduler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// maxRobotsSize 代表robots.txt文件的最大读取长度。
+const maxRobotsSize = 512 * 1024
+
+// defaultRobotsRetryInterval 代表默认的robots.txt重新获取的时间间隔。
+const defaultRobotsRetryInterval = time.Minute
+
+// maxRobotsFailures 代表robots.txt连续暂时不可用的最大次数。
+// 达到此次数之后，站点的所有路径都会被禁止访问，且不再重新获取。
+const maxRobotsFailures = 5
+
+// robotsRule 代表robots.txt中的一条Allow或Disallow规则。
+type robotsRule struct {
+	// allow 代表是否为Allow规则。
+	allow bool
+	// pattern 代表路径模式，其中可以包含通配符“*”和结束符“$”。
+	pattern string
+}
+
+// robotsGroup 代表robots.txt中针对一组User-agent的规则组。
+type robotsGroup struct {
+	// agents 代表小写的User-agent列表。
+	agents []string
+	// rules 代表规则列表。
+	rules []robotsRule
+	// crawlDelay 代表Crawl-delay指定的请求间隔。
+	crawlDelay time.Duration
+}
+
+// robotsData 代表解析后的robots.txt。
+type robotsData struct {
+	// groups 代表规则组列表。
+	groups []*robotsGroup
+	// sitemaps 代表通过Sitemap指令声明的站点地图的URL列表。
+	sitemaps []string
+	// disallowAll 代表是否禁止访问所有路径。
+	disallowAll bool
+	// unavailable 代表robots.txt是否暂时不可用。
+	unavailable bool
+}
+
+// robotsAllowAll 代表允许访问所有路径的robots.txt。
+var robotsAllowAll = &robotsData{}
+
+// robotsDisallowAll 代表禁止访问所有路径的robots.txt。
+var robotsDisallowAll = &robotsData{disallowAll: true}
+
+// robotsUnavailable 代表暂时不可用的robots.txt，此时所有路径都暂时被禁止访问。
+var robotsUnavailable = &robotsData{disallowAll: true, unavailable: true}
+
+// parseRobots 用于解析robots.txt的内容。
+func parseRobots(reader io.Reader) (*robotsData, error) {
+	data := &robotsData{}
+	var current *robotsGroup
+	// lastWasAgent 代表上一条有效指令是否为User-agent。
+	var lastWasAgent bool
+	scanner := bufio.NewScanner(io.LimitReader(reader, maxRobotsSize))
+	scanner.Buffer(make([]byte, 0, 4096), maxRobotsSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		switch key {
+		case "user-agent":
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				data.groups = append(data.groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{
+					allow:   key == "allow",
+					pattern: value,
+				})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			if value != "" {
+				data.sitemaps = append(data.sitemaps, value)
+			}
+		}
+		lastWasAgent = false
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// group 用于获取适用于给定用户代理的规则组。
+// 会选择与用户代理匹配得最长的那个规则组，没有匹配时使用“*”规则组。
+func (data *robotsData) group(userAgent string) *robotsGroup {
+	userAgent = strings.ToLower(userAgent)
+	var selected, fallback *robotsGroup
+	var selectedLen int
+	for _, g := range data.groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				if fallback == nil {
+					fallback = g
+				}
+				continue
+			}
+			if agent != "" && strings.Contains(userAgent, agent) && len(agent) > selectedLen {
+				selected = g
+				selectedLen = len(agent)
+			}
+		}
+	}
+	if selected != nil {
+		return selected
+	}
+	return fallback
+}
+
+// allowed 用于判断给定用户代理能否访问给定的路径。
+// 路径应包含查询字符串。匹配长度最长的规则生效，长度相同时Allow优先。
+func (data *robotsData) allowed(userAgent string, path string) bool {
+	if data.disallowAll {
+		return false
+	}
+	g := data.group(userAgent)
+	if g == nil {
+		return true
+	}
+	if path == "" {
+		path = "/"
+	}
+	allow := true
+	matchedLen := -1
+	for _, rule := range g.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		patternLen := len(rule.pattern)
+		if patternLen > matchedLen || (patternLen == matchedLen && rule.allow) {
+			allow = rule.allow
+			matchedLen = patternLen
+		}
+	}
+	return allow
+}
+
+// crawlDelay 用于获取适用于给定用户代理的请求间隔。
+func (data *robotsData) crawlDelay(userAgent string) time.Duration {
+	g := data.group(userAgent)
+	if g == nil {
+		return 0
+	}
+	return g.crawlDelay
+}
+
+// matchRobotsPattern 用于判断路径是否匹配robots.txt中的路径模式。
+// 未以“$”结尾的模式只需匹配路径的前缀。
+func matchRobotsPattern(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	return matchRobotsParts(strings.Split(pattern, "*"), path, anchored)
+}
+
+// matchRobotsParts 用于判断路径是否匹配以通配符分隔的各段模式。
+func matchRobotsParts(parts []string, path string, anchored bool) bool {
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	for i := 0; i <= len(rest); i++ {
+		if matchRobotsParts(parts[1:], rest[i:], anchored) {
+			return true
+		}
+	}
+	return false
+}
+
+// robotsEntry 代表robots.txt缓存中的条目。
+type robotsEntry struct {
+	// done 会在获取完毕后被关闭。
+	done chan struct{}
+	// data 代表解析后的robots.txt。
+	data *robotsData
+	// expires 代表条目的过期时间。若为零值，则永不过期。
+	expires time.Time
+	// failures 代表robots.txt连续暂时不可用的次数。
+	failures uint32
+}
+
+// fetched 用于判断条目是否已获取完毕。
+func (entry *robotsEntry) fetched() bool {
+	select {
+	case <-entry.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// expired 用于判断已获取完毕的条目在给定时间是否已过期。
+func (entry *robotsEntry) expired(now time.Time) bool {
+	return !entry.expires.IsZero() && !now.Before(entry.expires)
+}
+
+// robotsCache 代表按站点缓存robots.txt的类型。
+type robotsCache struct {
+	// entries 代表站点（scheme://host）与缓存条目的映射。
+	entries map[string]*robotsEntry
+	lock    sync.Mutex
+}
+
+// newRobotsCache 用于创建一个robots.txt缓存。
+func newRobotsCache() *robotsCache {
+	return &robotsCache{entries: map[string]*robotsEntry{}}
+}
+
+// robotsFetch 代表获取robots.txt的函数。
+// 参数failures代表此前robots.txt连续暂时不可用的次数。
+// 第二个结果值代表结果的有效期，为0时代表永久有效。
+type robotsFetch func(failures uint32) (*robotsData, time.Duration)
+
+// get 用于获取指定站点的robots.txt及其过期时间。
+// 若缓存中不存在或已过期，则会调用fetch获取，同一站点同时只会获取一次。
+func (rc *robotsCache) get(site string, fetch robotsFetch) (*robotsData, time.Time) {
+	rc.lock.Lock()
+	entry, ok := rc.entries[site]
+	var failures uint32
+	if ok && entry.fetched() && entry.expired(time.Now()) {
+		failures = entry.failures
+		ok = false
+	}
+	if !ok {
+		entry = &robotsEntry{done: make(chan struct{})}
+		rc.entries[site] = entry
+	}
+	rc.lock.Unlock()
+	if ok {
+		<-entry.done
+		return entry.data, entry.expires
+	}
+	data, ttl := fetch(failures)
+	entry.data = data
+	if data.unavailable {
+		entry.failures = failures + 1
+	}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	close(entry.done)
+	return entry.data, entry.expires
+}
+
+// peek 用于在不获取的情况下查看指定站点已缓存且未过期的robots.txt。
+func (rc *robotsCache) peek(site string) (*robotsData, bool) {
+	rc.lock.Lock()
+	entry, ok := rc.entries[site]
+	rc.lock.Unlock()
+	if !ok || !entry.fetched() || entry.expired(time.Now()) {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+// robotsSite 用于获取给定协议和主机所对应的站点。
+func robotsSite(scheme string, host string) string {
+	return strings.ToLower(scheme) + "://" + strings.ToLower(host)
+}
+
+// robotsAllowed 用于根据已缓存的robots.txt判断给定的请求是否被允许。
+// 它不会获取robots.txt。若站点的robots.txt尚未被获取或暂时不可用，
+// 则先放行该请求，由checkRobots在下载之前再做判断。
+func (sched *myScheduler) robotsAllowed(req *module.Request) bool {
+	if !sched.requestArgs.Robots.Obey {
+		return true
+	}
+	reqURL := req.HTTPReq().URL
+	data, ok := sched.robotsCache.peek(robotsSite(reqURL.Scheme, reqURL.Host))
+	if !ok || data.unavailable {
+		return true
+	}
+	return data.allowed(sched.requestArgs.Robots.UserAgent, reqURL.RequestURI())
+}
+
+// checkRobots 用于在下载之前判断给定的请求是否被robots.txt允许。
+// 必要时它会获取站点的robots.txt。
+// 若robots.txt暂时不可用，则第二个结果值代表需要等待多久之后再做判断。
+func (sched *myScheduler) checkRobots(req *module.Request) (bool, time.Duration) {
+	if !sched.requestArgs.Robots.Obey {
+		return true, 0
+	}
+	reqURL := req.HTTPReq().URL
+	data, expires := sched.robots(reqURL.Scheme, reqURL.Host)
+	if data.unavailable {
+		wait := time.Until(expires)
+		if wait < requeueDelay {
+			wait = requeueDelay
+		}
+		return false, wait
+	}
+	return data.allowed(sched.requestArgs.Robots.UserAgent, reqURL.RequestURI()), 0
+}
+
+// robots 用于获取指定站点的robots.txt及其过期时间。
+// 只有在遵守robots.txt时，其中的抓取延迟才会被应用到相应的主机上。
+func (sched *myScheduler) robots(scheme string, host string) (*robotsData, time.Time) {
+	host = strings.ToLower(host)
+	site := robotsSite(scheme, host)
+	return sched.robotsCache.get(site, func(failures uint32) (*robotsData, time.Duration) {
+		data, ttl := sched.fetchRobots(site, failures)
+		if !sched.requestArgs.Robots.Obey {
+			return data, ttl
+		}
+		userAgent := sched.requestArgs.Robots.UserAgent
+		if delay := data.crawlDelay(userAgent); delay > 0 {
+			logger.Infof("-- Crawl delay for %s: %s", host, delay)
+			sched.hostDispatcher.setCrawlDelay(host, delay)
+		}
+		return data, ttl
+	})
+}
+
+// robotsRetryInterval 用于获取robots.txt暂时无法获取时重新获取的时间间隔。
+func (sched *myScheduler) robotsRetryInterval() time.Duration {
+	if interval := sched.requestArgs.Robots.RetryInterval; interval > 0 {
+		return interval
+	}
+	return defaultRobotsRetryInterval
+}
+
+// fetchRobots 用于通过已注册的下载器获取并解析指定站点的robots.txt。
+// 参数failures代表此前robots.txt连续暂时不可用的次数。
+// 第二个结果值代表结果的有效期，为0时代表永久有效。
+// 依照RFC 9309：
+// 若响应状态码为4xx，则视为允许访问所有路径；
+// 若状态码为5xx，则视为暂时禁止访问所有路径，并在一段时间之后重新获取，
+// 连续多次如此则视为禁止访问所有路径；
+// 若因网络错误等原因无法获取，则暂时视为允许访问所有路径，并在一段时间之后重新获取。
+func (sched *myScheduler) fetchRobots(site string, failures uint32) (*robotsData, time.Duration) {
+	robotsURL := site + "/robots.txt"
+	logger.Infof("Fetch robots.txt (URL: %s)...", robotsURL)
+	httpReq, err := http.NewRequest("GET", robotsURL, nil)
+	if err != nil {
+		sendError(err, "", sched.errorBufferPool)
+		return robotsDisallowAll, 0
+	}
+	if userAgent := sched.requestArgs.Robots.UserAgent; userAgent != "" {
+		httpReq.Header.Set("User-Agent", userAgent)
+	}
+	retryInterval := sched.robotsRetryInterval()
+	httpResp, mid, err := sched.downloadDirectly(httpReq)
+	if err != nil {
+		sendError(err, mid, sched.errorBufferPool)
+		logger.Warnf("Allow all paths of %s temporarily! (error: %s)", site, err)
+		return robotsAllowAll, retryInterval
+	}
+	if httpResp.Body != nil {
+		defer httpResp.Body.Close()
+	}
+	switch {
+	case httpResp.StatusCode >= 200 && httpResp.StatusCode < 300:
+		data, err := parseRobots(httpResp.Body)
+		if err != nil {
+			sendError(err, mid, sched.errorBufferPool)
+			return robotsAllowAll, 0
+		}
+		return data, 0
+	case httpResp.StatusCode >= 400 && httpResp.StatusCode < 500:
+		return robotsAllowAll, 0
+	}
+	if failures+1 >= maxRobotsFailures {
+		logger.Warnf("Disallow all paths of %s! (status code: %d, failures: %d)",
+			site, httpResp.StatusCode, failures+1)
+		return robotsDisallowAll, 0
+	}
+	logger.Warnf("Disallow all paths of %s temporarily! (status code: %d)",
+		site, httpResp.StatusCode)
+	return robotsUnavailable, retryInterval
+}
+
+// downloadDirectly 用于不经过URL边界，直接通过已注册的下载器下载给定的请求。
+// 该请求不受下载器的内容类型和响应体大小的限制。
+// 第二个结果值代表所用的下载器的ID。
+func (sched *myScheduler) downloadDirectly(
+	httpReq *http.Request) (*http.Response, module.MID, error) {
+	m, err := sched.acquireModule(module.TYPE_DOWNLOADER)
+	if err != nil || m == nil {
+		errMsg := fmt.Sprintf("couldn't get a downloader: %s", err)
+		return nil, "", genError(errMsg)
+	}
+	defer sched.releaseModule(m)
+	downloader, ok := m.(module.Downloader)
+	if !ok {
+		errMsg := fmt.Sprintf("incorrect downloader type: %T (MID: %s)",
+			m, m.ID())
+		return nil, m.ID(), genError(errMsg)
+	}
+	begin := time.Now()
+	resp, err := downloader.Download(module.NewUnlimitedRequest(httpReq))
+	sched.registrar.Report(m.ID(), err, time.Since(begin))
+	if err != nil {
+		return nil, m.ID(), err
+	}
+	return resp.HTTPResp(), m.ID(), nil
+}
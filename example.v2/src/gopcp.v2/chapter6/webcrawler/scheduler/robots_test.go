@@ -0,0 +1,296 @@
+package scheduler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/module/local/downloader"
+)
+
+var testingRobots = `# comments
+User-agent: *
+Disallow: /private
+Allow: /private/open
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: BadBot
+User-agent: test-crawler
+Disallow: /
+Allow: /public
+
+Sitemap: http://example.com/sitemap.xml
+`
+
+func TestRobotsParse(t *testing.T) {
+	data, err := parseRobots(strings.NewReader(testingRobots))
+	if err != nil {
+		t.Fatalf("An error occurs when parsing robots.txt: %s", err)
+	}
+	if len(data.groups) != 2 {
+		t.Fatalf("Inconsistent group number: expected: %d, actual: %d",
+			2, len(data.groups))
+	}
+	if len(data.sitemaps) != 1 || data.sitemaps[0] != "http://example.com/sitemap.xml" {
+		t.Fatalf("Inconsistent sitemaps: %v", data.sitemaps)
+	}
+	if d := data.crawlDelay("gopcp"); d != 2*time.Second {
+		t.Fatalf("Inconsistent crawl delay: expected: %s, actual: %s",
+			2*time.Second, d)
+	}
+	if d := data.crawlDelay("test-crawler/1.0"); d != 0 {
+		t.Fatalf("Inconsistent crawl delay: expected: 0s, actual: %s", d)
+	}
+	cases := []struct {
+		userAgent string
+		path      string
+		allowed   bool
+	}{
+		{"gopcp", "/", true},
+		{"gopcp", "/index.html", true},
+		{"gopcp", "/private", false},
+		{"gopcp", "/private/secret?a=1", false},
+		{"gopcp", "/private/open/1.html", true},
+		{"gopcp", "/doc/a.pdf", false},
+		{"gopcp", "/doc/a.pdf?download=1", true},
+		{"Test-Crawler/1.0", "/", false},
+		{"Test-Crawler/1.0", "/public/a.html", true},
+		{"badbot", "/private/open", false},
+	}
+	for _, c := range cases {
+		if allowed := data.allowed(c.userAgent, c.path); allowed != c.allowed {
+			t.Fatalf("Inconsistent result: expected: %v, actual: %v (user agent: %s, path: %s)",
+				c.allowed, allowed, c.userAgent, c.path)
+		}
+	}
+	if !robotsAllowAll.allowed("gopcp", "/private") {
+		t.Fatal("The path is disallowed by an empty robots.txt!")
+	}
+	if robotsDisallowAll.allowed("gopcp", "/") {
+		t.Fatal("The path is allowed by a disallowing robots.txt!")
+	}
+}
+
+func TestRobotsPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"/", "/a", true},
+		{"/a", "/abc", true},
+		{"/a", "/b", false},
+		{"/a$", "/a", true},
+		{"/a$", "/ab", false},
+		{"/*.php", "/x/y.php?z", true},
+		{"/*.php$", "/x/y.php?z", false},
+		{"/*.php$", "/x.php/y.php", true},
+		{"/a*b*c", "/a--b--c--", true},
+		{"/a*b*c", "/a--c--b", false},
+		{"*", "/anything", true},
+	}
+	for _, c := range cases {
+		if matched := matchRobotsPattern(c.pattern, c.path); matched != c.matched {
+			t.Fatalf("Inconsistent result: expected: %v, actual: %v (pattern: %s, path: %s)",
+				c.matched, matched, c.pattern, c.path)
+		}
+	}
+}
+
+func TestRobotsEnforce(t *testing.T) {
+	server := newPageServer(map[string][]string{
+		"/":             {"/a", "/private/x"},
+		"/a":            {"/private/open"},
+		"/private/x":    {},
+		"/private/open": {},
+	})
+	server.robots = "User-agent: *\nDisallow: /private\nAllow: /private/open\nCrawl-delay: 0.05\n"
+	defer server.Close()
+	requestArgs := genRequestArgs([]string{server.Host()}, 3)
+	requestArgs.Robots = RobotsArgs{Obey: true, UserAgent: "test-crawler"}
+	sched := NewScheduler()
+	err := sched.Init(
+		requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err = sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	if !waitFor(5*time.Second, func() bool { return server.Hits("/private/open") > 0 }) {
+		t.Fatal("The allowed URL has not been fetched!")
+	}
+	waitFor(5*time.Second, sched.Idle)
+	if hits := server.Hits("/private/x"); hits != 0 {
+		t.Fatalf("The disallowed URL has been fetched! (hits: %d)", hits)
+	}
+	if hits := server.Hits("/robots.txt"); hits != 1 {
+		t.Fatalf("Inconsistent robots.txt hits: expected: %d, actual: %d", 1, hits)
+	}
+	summary := sched.Summary().Struct()
+	if summary.NumRobotsRejected != 1 {
+		t.Fatalf("Inconsistent robots rejected number: expected: %d, actual: %d",
+			1, summary.NumRobotsRejected)
+	}
+	mySched := sched.(*myScheduler)
+	mySched.hostDispatcher.lock.Lock()
+	st, ok := mySched.hostDispatcher.hosts[server.Host()]
+	mySched.hostDispatcher.lock.Unlock()
+	if !ok || st.crawlDelay != 50*time.Millisecond {
+		t.Fatalf("The crawl delay has not been applied! (host: %s)", server.Host())
+	}
+}
+
+func TestRobotsCacheExpiry(t *testing.T) {
+	rc := newRobotsCache()
+	var calls []uint32
+	fetch := func(failures uint32) (*robotsData, time.Duration) {
+		calls = append(calls, failures)
+		return robotsUnavailable, 20 * time.Millisecond
+	}
+	if _, ok := rc.peek("http://a.com"); ok {
+		t.Fatal("The robots.txt has been cached before fetching!")
+	}
+	data, expires := rc.get("http://a.com", fetch)
+	if data != robotsUnavailable || expires.IsZero() {
+		t.Fatalf("Inconsistent robots.txt: %#v (expires: %s)", data, expires)
+	}
+	rc.get("http://a.com", fetch)
+	if len(calls) != 1 {
+		t.Fatalf("The robots.txt has been fetched again before expiry! (calls: %d)", len(calls))
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := rc.peek("http://a.com"); ok {
+		t.Fatal("The expired robots.txt is still valid!")
+	}
+	rc.get("http://a.com", func(failures uint32) (*robotsData, time.Duration) {
+		calls = append(calls, failures)
+		return robotsAllowAll, 0
+	})
+	if len(calls) != 2 || calls[1] != 1 {
+		t.Fatalf("Inconsistent fetch calls: %v", calls)
+	}
+	if data, ok := rc.peek("http://a.com"); !ok || data != robotsAllowAll {
+		t.Fatalf("Inconsistent cached robots.txt: %#v", data)
+	}
+}
+
+// newRobotsTestingServer 用于创建一个测试robots.txt的获取用的服务器。
+// 对robots.txt的前几次请求会由参数fail处理。
+func newRobotsTestingServer(failTimes int, fail http.HandlerFunc) *pageServer {
+	ps := newPageServer(map[string][]string{
+		"/":          {"/private/x"},
+		"/private/x": {},
+	})
+	var robotsHits int
+	ps.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			ps.lock.Lock()
+			robotsHits++
+			failing := robotsHits <= failTimes
+			ps.lock.Unlock()
+			if failing {
+				ps.lock.Lock()
+				ps.hits[r.URL.Path]++
+				ps.lock.Unlock()
+				fail(w, r)
+				return
+			}
+		}
+		ps.serve(w, r)
+	})
+	ps.robots = "User-agent: *\nDisallow: /private\n"
+	return ps
+}
+
+// startRobotsScheduler 用于启动一个遵守robots.txt的调度器。
+func startRobotsScheduler(
+	server *pageServer, moduleArgs ModuleArgs, t *testing.T) Scheduler {
+	requestArgs := genRequestArgs([]string{server.Host()}, 3)
+	requestArgs.Robots = RobotsArgs{
+		Obey:          true,
+		UserAgent:     "test-crawler",
+		RetryInterval: 100 * time.Millisecond,
+	}
+	sched := NewScheduler()
+	err := sched.Init(requestArgs, genDataArgs(10, 2, 1), moduleArgs)
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err = sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	return sched
+}
+
+func TestRobotsUnavailable(t *testing.T) {
+	server := newRobotsTestingServer(2, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer server.Close()
+	sched := startRobotsScheduler(server, genSimpleModuleArgs(1, 1, 1, t), t)
+	defer sched.Stop()
+	if server.Hits("/") != 0 {
+		t.Fatal("The page has been fetched while robots.txt is unavailable!")
+	}
+	if !waitFor(5*time.Second, func() bool { return server.Hits("/") > 0 }) {
+		t.Fatal("The page has not been fetched after robots.txt became available!")
+	}
+	waitFor(5*time.Second, sched.Idle)
+	if hits := server.Hits("/robots.txt"); hits != 3 {
+		t.Fatalf("Inconsistent robots.txt hits: expected: %d, actual: %d", 3, hits)
+	}
+	if hits := server.Hits("/private/x"); hits != 0 {
+		t.Fatalf("The disallowed URL has been fetched! (hits: %d)", hits)
+	}
+}
+
+func TestRobotsFailOpen(t *testing.T) {
+	server := newRobotsTestingServer(1, func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	})
+	defer server.Close()
+	sched := startRobotsScheduler(server, genSimpleModuleArgs(1, 1, 1, t), t)
+	defer sched.Stop()
+	if !waitFor(5*time.Second, func() bool { return server.Hits("/") > 0 }) {
+		t.Fatal("The page has not been fetched while robots.txt is unreachable!")
+	}
+	time.Sleep(150 * time.Millisecond)
+	mySched := sched.(*myScheduler)
+	data, _ := mySched.robots("http", server.Host())
+	if data.allowed("test-crawler", "/private/x") {
+		t.Fatal("The robots.txt has not been fetched again after the retry interval!")
+	}
+	if hits := server.Hits("/robots.txt"); hits != 2 {
+		t.Fatalf("Inconsistent robots.txt hits: expected: %d, actual: %d", 2, hits)
+	}
+}
+
+func TestRobotsWithoutLimits(t *testing.T) {
+	server := newRobotsTestingServer(0, nil)
+	defer server.Close()
+	d, err := downloader.New("D1", &http.Client{}, nil,
+		downloader.WithAllowedContentTypes("text/html"),
+		downloader.WithMaxBodyBytes(10, false))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", err)
+	}
+	moduleArgs := genSimpleModuleArgs(0, 1, 1, t)
+	moduleArgs.Downloaders = []module.Downloader{d}
+	sched := startRobotsScheduler(server, moduleArgs, t)
+	defer sched.Stop()
+	mySched := sched.(*myScheduler)
+	data, _ := mySched.robots("http", server.Host())
+	if data.allowed("test-crawler", "/private/x") {
+		t.Fatal("The text/plain robots.txt has been ignored!")
+	}
+}
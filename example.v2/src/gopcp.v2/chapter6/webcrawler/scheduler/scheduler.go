@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"gopcp.v2/chapter5/cmap"
 	"gopcp.v2/chapter6/webcrawler/module"
@@ -29,9 +33,26 @@ type Scheduler interface {
 	// Start 用于启动调度器并执行爬取流程。
 	// 参数firstHTTPReq即代表首次请求。调度器会以此为起始点开始执行爬取流程。
 	Start(firstHTTPReq *http.Request) (err error)
+	// StartWithSeeds 用于以多个种子请求启动调度器并执行爬取流程。
+	// 参数seedHTTPReqs代表种子请求的列表，其中的请求的深度都为0。
+	// 若启用了站点地图的发现，则种子请求所在站点的robots.txt中
+	// 声明的站点地图里的URL也会被作为深度为0的请求。
+	StartWithSeeds(seedHTTPReqs []*http.Request) (err error)
 	// Stop 用于停止调度器的运行。
 	// 所有处理模块执行的流程都会被中止。
 	Stop() (err error)
+	// StopGracefully 用于优雅地停止调度器的运行。
+	// 调度器会先停止接受新的请求，然后等待已下载的响应被分析、
+	// 已产生的条目被条目处理管道处理，直至全部处理完毕或ctx被取消，
+	// 最后再像Stop方法那样停止调度器。
+	// 结果值report代表排空报告。
+	StopGracefully(ctx context.Context) (report DrainReport, err error)
+	// Pause 用于暂停调度器的运行。
+	// 下载、分析和条目处理的流程都会停止从相应的缓冲池取出数据，
+	// 但已在其中的数据不会丢失。本方法会等待正在处理的数据被处理完毕。
+	Pause() (err error)
+	// Resume 用于恢复已被暂停的调度器的运行。
+	Resume() (err error)
 	// Status 用于获取调度器的状态。
 	Status() Status
 	// ErrorChan 用于获得错误通道。
@@ -42,6 +63,34 @@ type Scheduler interface {
 	Idle() bool
 	// Summary 用于获取摘要实例。
 	Summary() SchedSummary
+	// Checkpoint 用于把调度器的当前状态保存为快照文件。
+	// 快照中包含已处理的URL、尚未处理完毕的请求以及请求和数据相关的参数。
+	// 参数snapshotPath代表快照文件的路径。
+	Checkpoint(snapshotPath string) (err error)
+	// Duplicates 用于获取重复页面索引。
+	// 其中记录了基于内容的重复页面检测所发现的重复页面的簇。
+	Duplicates() DuplicateIndex
+	// OnRequest 用于注册请求钩子。
+	// 请求钩子会在请求被放入URL边界之前被调用，可用于修改或丢弃请求。
+	OnRequest(hook RequestHook)
+	// OnResponse 用于注册响应钩子。
+	// 响应钩子会在响应被下载之后、被分析之前被调用，可用于修改或丢弃响应。
+	OnResponse(hook ResponseHook)
+	// OnItem 用于注册条目钩子。
+	// 条目钩子会在条目被交给条目处理管道之前被调用，可用于修改或丢弃条目。
+	OnItem(hook ItemHook)
+	// OnError 用于注册错误钩子。
+	// 错误钩子会在错误被发送到错误通道之前被调用，可用于修改或丢弃错误。
+	OnError(hook ErrorHook)
+	// AddModule 用于在调度器运行期间添加组件，即增加相应类型的组件的实例。
+	// 只有在调度器已被初始化、已启动或已暂停时才能添加组件。
+	AddModule(m module.Module) (err error)
+	// RemoveModule 用于在调度器运行期间移除组件。
+	// 若参数drain为true，则本方法会等待该组件处理完正在处理的数据，
+	// 然后关闭实现了io.Closer接口的组件。否则，本方法在注销组件之后立即返回，
+	// 而该组件会在不再被使用之后在后台被关闭。
+	// 每种类型的最后一个组件不能被移除。
+	RemoveModule(mid module.MID, drain bool) (err error)
 }
 
 // NewScheduler 会创建一个调度器实例。
@@ -53,20 +102,60 @@ func NewScheduler() Scheduler {
 type myScheduler struct {
 	// maxDepth 代表爬取的最大深度。首次请求的深度为0。
 	maxDepth uint32
-	// acceptedDomainMap 代表可以接受的URL的主域名的字典。
-	acceptedDomainMap cmap.ConcurrentMap
+	// scope 代表爬取范围的判定器，其中包含可以接受的URL的主域名等。
+	scope *scope
 	// registrar 代表组件注册器。
 	registrar module.Registrar
-	// reqBufferPool 代表请求的缓冲池。
-	reqBufferPool buffer.Pool
+	// frontier 代表URL边界，即待下载的请求的队列。
+	frontier Frontier
+	// scoreFunc 代表请求分数的计算函数。
+	scoreFunc ScoreFunc
+	// hostDispatcher 代表按主机调度请求的分发器。
+	hostDispatcher *hostDispatcher
 	// respBufferPool 代表响应的缓冲池。
 	respBufferPool buffer.Pool
 	// itemBufferPool 代表条目的缓冲池。
 	itemBufferPool buffer.Pool
 	// errorBufferPool 代表错误的缓冲池。
 	errorBufferPool buffer.Pool
-	// urlMap 代表已处理的URL的字典。
-	urlMap cmap.ConcurrentMap
+	// urlSet 代表已处理的URL的集合。
+	urlSet SeenSet
+	// pendingReqMap 代表已被接受但尚未处理完毕的请求的字典。
+	pendingReqMap cmap.ConcurrentMap
+	// robotsCache 代表按站点缓存的robots.txt。
+	robotsCache *robotsCache
+	// numRobotsRejected 代表因robots.txt而被忽略的请求的数量。
+	numRobotsRejected uint64
+	// numRetried 代表已安排的重试的次数。
+	numRetried uint64
+	// pendingRetries 代表正在等待重试的请求的数量。
+	pendingRetries int64
+	// deadLetters 代表最终下载失败的请求的列表。
+	deadLetters *deadLetterList
+	// pendingSeeds 代表正在展开的站点地图的数量。
+	pendingSeeds int64
+	// numSitemapURLs 代表从站点地图中得到的URL的数量。
+	numSitemapURLs uint64
+	// duplicates 代表重复页面索引。
+	duplicates *myDuplicateIndex
+	// hooks 代表各个阶段的钩子链。
+	hooks hookChain
+	// draining 代表是否正在排空。1代表是，0代表否。
+	draining uint32
+	// drainCounts 代表排空过程中的计数。
+	drainCounts drainCounts
+	// pauseGate 代表暂停闸门。
+	pauseGate pauseGate
+	// workers 代表各个阶段的工作协程的统计信息。
+	workers stageWorkers
+	// moduleUsage 代表组件的使用计数。
+	moduleUsage moduleUsage
+	// moduleLock 代表专用于组件的添加和移除的互斥锁。
+	moduleLock sync.Mutex
+	// requestArgs 代表请求相关的参数。
+	requestArgs RequestArgs
+	// dataArgs 代表数据相关的参数。
+	dataArgs DataArgs
 	// ctx 代表上下文，用于感知调度器的停止。
 	ctx context.Context
 	// cancelFunc 代表取消函数，用于停止调度器。
@@ -122,19 +211,43 @@ func (sched *myScheduler) Init(
 	} else {
 		sched.registrar.Clear()
 	}
+	sched.requestArgs = requestArgs
+	sched.dataArgs = dataArgs
 	sched.maxDepth = requestArgs.MaxDepth
 	logger.Infof("-- Max depth: %d", sched.maxDepth)
-	sched.acceptedDomainMap, _ =
-		cmap.NewConcurrentMap(1, nil)
-	for _, domain := range requestArgs.AcceptedDomains {
-		sched.acceptedDomainMap.Put(domain, struct{}{})
+	if sched.scope, err = newScope(requestArgs.Scope,
+		requestArgs.AcceptedDomains, requestArgs.Canonical); err != nil {
+		return
 	}
 	logger.Infof("-- Accepted primary domains: %v",
 		requestArgs.AcceptedDomains)
-	sched.urlMap, _ = cmap.NewConcurrentMap(16, nil)
-	logger.Infof("-- URL map: length: %d, concurrency: %d",
-		sched.urlMap.Len(), sched.urlMap.Concurrency())
+	logger.Infof("-- Scope: %+v", requestArgs.Scope)
+	if sched.urlSet, err = NewSeenSet(requestArgs.SeenSet); err != nil {
+		return
+	}
+	logger.Infof("-- URL set: type: %s, length: %d",
+		sched.urlSet.Type(), sched.urlSet.Len())
+	sched.pendingReqMap, _ = cmap.NewConcurrentMap(16, nil)
+	sched.scoreFunc = moduleArgs.ScoreFunc
+	if err = sched.initFrontier(dataArgs); err != nil {
+		return
+	}
 	sched.initBufferPool(dataArgs)
+	sched.hostDispatcher =
+		newHostDispatcher(requestArgs.Politeness, dataArgs.ReqBufferCap,
+			sched.frontier.Type(), sched.scoreFunc)
+	logger.Infof("-- Politeness: %+v", requestArgs.Politeness)
+	sched.robotsCache = newRobotsCache()
+	sched.numRobotsRejected = 0
+	logger.Infof("-- Robots: %+v", requestArgs.Robots)
+	sched.numRetried = 0
+	sched.deadLetters = &deadLetterList{}
+	logger.Infof("-- Retry: %+v", requestArgs.Retry)
+	sched.numSitemapURLs = 0
+	logger.Infof("-- Sitemap: %+v", requestArgs.Sitemap)
+	sched.duplicates = newDuplicateIndex(
+		requestArgs.Dedup.MaxDistance, requestArgs.Dedup.MaxEntries)
+	logger.Infof("-- Dedup: %+v", requestArgs.Dedup)
 	sched.resetContext()
 	sched.summary =
 		newSchedSummary(requestArgs, dataArgs, moduleArgs, sched)
@@ -148,6 +261,10 @@ func (sched *myScheduler) Init(
 }
 
 func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
+	return sched.StartWithSeeds([]*http.Request{firstHTTPReq})
+}
+
+func (sched *myScheduler) StartWithSeeds(seedHTTPReqs []*http.Request) (err error) {
 	defer func() {
 		if p := recover(); p != nil {
 			errMsg := fmt.Sprintf("Fatal scheduler error: %s", p)
@@ -174,22 +291,38 @@ func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
 		return
 	}
 	// 检查参数。
-	logger.Info("Check first HTTP request...")
-	if firstHTTPReq == nil {
-		err = genParameterError("nil first HTTP request")
+	logger.Info("Check seed HTTP requests...")
+	if len(seedHTTPReqs) == 0 {
+		err = genParameterError("empty seed HTTP request list")
 		return
 	}
-	logger.Info("The first HTTP request is valid.")
-	// 获得首次请求的主域名，并将其添加到可接受的主域名的字典。
-	logger.Info("Get the primary domain...")
-	logger.Infof("-- Host: %s", firstHTTPReq.Host)
-	var primaryDomain string
-	primaryDomain, err = getPrimaryDomain(firstHTTPReq.Host)
-	if err != nil {
-		return
+	var scopeKeys []string
+	for i, seedHTTPReq := range seedHTTPReqs {
+		if seedHTTPReq == nil {
+			err = genParameterError(fmt.Sprintf("nil seed HTTP request[%d]", i))
+			return
+		}
+		if seedHTTPReq.URL == nil {
+			err = genParameterError(fmt.Sprintf("nil URL of seed HTTP request[%d]", i))
+			return
+		}
+		// 获得种子请求所在的范围（默认为其主域名）。
+		logger.Infof("-- Host: %s", seedHTTPReq.Host)
+		seedURL := *seedHTTPReq.URL
+		seedURL.Host = seedHTTPReq.Host
+		var scopeKey string
+		scopeKey, err = sched.scope.keyOf(&seedURL)
+		if err != nil {
+			return
+		}
+		logger.Infof("-- Scope key (%s): %s", sched.scope.mode, scopeKey)
+		scopeKeys = append(scopeKeys, scopeKey)
+	}
+	logger.Info("The seed HTTP requests are valid.")
+	// 把种子请求所在的范围添加到爬取范围。
+	for _, scopeKey := range scopeKeys {
+		sched.scope.accept(scopeKey)
 	}
-	logger.Infof("-- Primary domain: %s", primaryDomain)
-	sched.acceptedDomainMap.Put(primaryDomain, struct{}{})
 	// 开始调度数据和组件。
 	if err = sched.checkBufferPoolForStart(); err != nil {
 		return
@@ -197,10 +330,13 @@ func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
 	sched.download()
 	sched.analyze()
 	sched.pick()
+	sched.checkpointPeriodically()
 	logger.Info("Scheduler has been started.")
-	// 放入第一个请求。
-	firstReq := module.NewRequest(firstHTTPReq, 0)
-	sched.sendReq(firstReq)
+	// 放入种子请求。
+	for _, seedHTTPReq := range seedHTTPReqs {
+		sched.sendReq(module.NewRequest(seedHTTPReq, 0))
+	}
+	sched.discoverSitemaps(seedHTTPReqs)
 	return nil
 }
 
@@ -223,13 +359,41 @@ func (sched *myScheduler) Stop() (err error) {
 	if err != nil {
 		return
 	}
+	sched.shutdown()
+	return nil
+}
+
+// shutdown 会保存最终的快照，然后中止所有的流程并关闭URL边界和各个缓冲池。
+func (sched *myScheduler) shutdown() {
+	if path := sched.dataArgs.SnapshotPath; path != "" {
+		if err := saveSnapshot(sched.snapshot(), path); err != nil {
+			logger.Errorf("An error occurs when saving the final snapshot: %s (path: %s)",
+				err, path)
+		}
+	}
 	sched.cancelFunc()
-	sched.reqBufferPool.Close()
+	sched.frontier.Close()
 	sched.respBufferPool.Close()
 	sched.itemBufferPool.Close()
 	sched.errorBufferPool.Close()
+	sched.pauseGate.open()
+	atomic.StoreUint32(&sched.draining, 0)
+	sched.closeModules()
 	logger.Info("Scheduler has been stopped.")
-	return nil
+}
+
+// closeModules 用于关闭所有实现了io.Closer接口的已注册组件，
+// 例如需要写出缓冲中的条目的条目处理管道。
+func (sched *myScheduler) closeModules() {
+	for mid, m := range sched.registrar.GetAll() {
+		closer, ok := m.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			logger.Errorf("An error occurs when closing module %s: %s", mid, err)
+		}
+	}
 }
 
 func (sched *myScheduler) Status() Status {
@@ -261,6 +425,10 @@ func (sched *myScheduler) ErrorChan() <-chan error {
 				sendError(errors.New(errMsg), "", sched.errorBufferPool)
 				continue
 			}
+			err, ok = sched.hookError(err)
+			if !ok {
+				continue
+			}
 			if sched.canceled() {
 				close(errCh)
 				break
@@ -272,13 +440,19 @@ func (sched *myScheduler) ErrorChan() <-chan error {
 }
 
 func (sched *myScheduler) Idle() bool {
+	if sched.workers.busy() {
+		return false
+	}
 	moduleMap := sched.registrar.GetAll()
 	for _, module := range moduleMap {
 		if module.HandlingNumber() > 0 {
 			return false
 		}
 	}
-	if sched.reqBufferPool.Total() > 0 ||
+	if sched.frontier.Len() > 0 ||
+		sched.hostDispatcher.Total() > 0 ||
+		atomic.LoadInt64(&sched.pendingRetries) > 0 ||
+		atomic.LoadInt64(&sched.pendingSeeds) > 0 ||
 		sched.respBufferPool.Total() > 0 ||
 		sched.itemBufferPool.Total() > 0 {
 		return false
@@ -303,8 +477,20 @@ func (sched *myScheduler) checkAndSetStatus(
 	return
 }
 
-// registerModules 会注册所有给定的组件。
+// registerModules 会为各类组件设置选择器和健康策略，并注册所有给定的组件。
 func (sched *myScheduler) registerModules(moduleArgs ModuleArgs) error {
+	if err := sched.registrar.SetHealthPolicy(moduleArgs.Health); err != nil {
+		return genErrorByError(err)
+	}
+	for mtype, selectorType := range moduleArgs.Selectors.typeMap() {
+		selector, err := module.NewSelector(selectorType)
+		if err != nil {
+			return genErrorByError(err)
+		}
+		if err := sched.registrar.SetSelector(mtype, selector); err != nil {
+			return genErrorByError(err)
+		}
+	}
 	for _, d := range moduleArgs.Downloaders {
 		if d == nil {
 			continue
@@ -353,7 +539,8 @@ func (sched *myScheduler) registerModules(moduleArgs ModuleArgs) error {
 	return nil
 }
 
-// download 会从请求缓冲池取出请求并下载，
+// download 会从请求缓冲池取出请求并交给主机分发器，
+// 再从主机分发器取出满足约束的请求并下载，
 // 然后把得到的响应放入响应缓冲池。
 func (sched *myScheduler) download() {
 	go func() {
@@ -361,74 +548,157 @@ func (sched *myScheduler) download() {
 			if sched.canceled() {
 				break
 			}
-			datum, err := sched.reqBufferPool.Get()
+			req, err := sched.frontier.Get()
 			if err != nil {
-				logger.Warnln("The request buffer pool was closed. Break request reception.")
+				logger.Warnln("The frontier was closed. Break request reception.")
 				break
 			}
-			req, ok := datum.(*module.Request)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect request type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
+			if err := sched.hostDispatcher.put(sched.ctx.Done(), req); err != nil {
+				logger.Warnln("The host dispatcher was closed. Break request reception.")
+				break
 			}
-			sched.downloadOne(req)
 		}
 	}()
+	stats := &sched.workers.download
+	for i := 0; i < workerNumber(sched.dataArgs.Concurrency.DownloadWorkers); i++ {
+		go func() {
+			stats.start()
+			defer stats.exit()
+			for {
+				if sched.canceled() {
+					break
+				}
+				if !sched.waitIfPaused() {
+					break
+				}
+				req, err := sched.hostDispatcher.get(sched.ctx.Done())
+				if err != nil {
+					logger.Warnln("The host dispatcher was closed. Break request download.")
+					break
+				}
+				if !sched.waitIfPaused() {
+					sched.hostDispatcher.finish(req, nil)
+					break
+				}
+				stats.begin()
+				if sched.discardIfDraining(req) {
+					sched.hostDispatcher.finish(req, nil)
+					stats.end()
+					continue
+				}
+				resp := sched.downloadOne(req)
+				var httpResp *http.Response
+				if resp != nil {
+					httpResp = resp.HTTPResp()
+				}
+				sched.hostDispatcher.finish(req, httpResp)
+				stats.end()
+			}
+		}()
+	}
 }
 
 // downloadOne 会根据给定的请求执行下载并把响应放入响应缓冲池。
-func (sched *myScheduler) downloadOne(req *module.Request) {
+// 结果值代表下载得到的响应，下载失败时为nil。
+func (sched *myScheduler) downloadOne(req *module.Request) *module.Response {
 	if req == nil {
-		return
+		return nil
 	}
 	if sched.canceled() {
-		return
+		return nil
+	}
+	// 若请求将被重试或被放回URL边界，则它仍然是未处理完毕的请求。
+	var retrying bool
+	defer func() {
+		if !retrying {
+			sched.pendingReqMap.Delete(sched.urlKey(req.HTTPReq().URL))
+		}
+	}()
+	// 请求的URL已被记录，因此只能把请求原样放回URL边界，而不能再次发送。
+	allowed, wait := sched.checkRobots(req)
+	if wait > 0 {
+		logger.Warnf("Delay the request for %s! The robots.txt is unavailable. (URL: %s)\n",
+			wait, req.HTTPReq().URL)
+		retrying = true
+		sched.requeue(req, wait)
+		return nil
+	}
+	if !allowed {
+		sched.rejectByRobots(req)
+		return nil
 	}
-	m, err := sched.registrar.Get(module.TYPE_DOWNLOADER)
+	m, err := sched.acquireModule(module.TYPE_DOWNLOADER)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get a downloader: %s", err)
 		sendError(errors.New(errMsg), "", sched.errorBufferPool)
-		sched.sendReq(req)
-		return
+		retrying = true
+		sched.requeue(req, requeueDelay)
+		return nil
 	}
+	defer sched.releaseModule(m)
 	downloader, ok := m.(module.Downloader)
 	if !ok {
 		errMsg := fmt.Sprintf("incorrect downloader type: %T (MID: %s)",
 			m, m.ID())
 		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
-		sched.sendReq(req)
-		return
+		retrying = true
+		sched.requeue(req, requeueDelay)
+		return nil
 	}
+	begin := time.Now()
 	resp, err := downloader.Download(req)
-	if resp != nil {
-		sendResp(resp, sched.respBufferPool)
+	sched.registrar.Report(m.ID(), err, time.Since(begin))
+	var exhausted bool
+	retrying, exhausted = sched.retryIfNeeded(req, resp, err)
+	if retrying {
+		return resp
+	}
+	// 用尽重试次数的响应已被加入死信列表，不会再被分析。
+	if resp != nil && !exhausted {
+		if hookedResp, ok := sched.hookResponse(resp); ok {
+			sendResp(hookedResp, sched.respBufferPool)
+		}
 	}
 	if err != nil {
 		sendError(err, m.ID(), sched.errorBufferPool)
 	}
+	return resp
 }
 
 // analyze 会从响应缓冲池取出响应并解析，
 // 然后把得到的条目或请求放入相应的缓冲池。
 func (sched *myScheduler) analyze() {
-	go func() {
-		for {
-			if sched.canceled() {
-				break
-			}
-			datum, err := sched.respBufferPool.Get()
-			if err != nil {
-				logger.Warnln("The response buffer pool was closed. Break response reception.")
-				break
+	stats := &sched.workers.analyze
+	for i := 0; i < workerNumber(sched.dataArgs.Concurrency.AnalyzeWorkers); i++ {
+		go func() {
+			stats.start()
+			defer stats.exit()
+			for {
+				if sched.canceled() {
+					break
+				}
+				if !sched.waitIfPaused() {
+					break
+				}
+				datum, err := sched.respBufferPool.Get()
+				if err != nil {
+					logger.Warnln("The response buffer pool was closed. Break response reception.")
+					break
+				}
+				if !sched.waitIfPaused() {
+					break
+				}
+				stats.begin()
+				resp, ok := datum.(*module.Response)
+				if !ok {
+					errMsg := fmt.Sprintf("incorrect response type: %T", datum)
+					sendError(errors.New(errMsg), "", sched.errorBufferPool)
+				}
+				sched.analyzeOne(resp)
+				stats.end()
 			}
-			resp, ok := datum.(*module.Response)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect response type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
-			}
-			sched.analyzeOne(resp)
-		}
-	}()
+		}()
+	}
 }
 
 // analyzeOne 会根据给定的响应执行解析并把结果放入相应的缓冲池。
@@ -439,13 +709,19 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 	if sched.canceled() {
 		return
 	}
-	m, err := sched.registrar.Get(module.TYPE_ANALYZER)
+	// 重复页面中的条目仍会被处理，但其中的链接不会被跟进。
+	var dup bool
+	if check := sched.duplicateCheck(&dup); check != nil {
+		resp = resp.WithDuplicateCheck(check)
+	}
+	m, err := sched.acquireModule(module.TYPE_ANALYZER)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get an analyzer: %s", err)
 		sendError(errors.New(errMsg), "", sched.errorBufferPool)
 		sendResp(resp, sched.respBufferPool)
 		return
 	}
+	defer sched.releaseModule(m)
 	analyzer, ok := m.(module.Analyzer)
 	if !ok {
 		errMsg := fmt.Sprintf("incorrect analyzer type: %T (MID: %s)",
@@ -454,7 +730,14 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 		sendResp(resp, sched.respBufferPool)
 		return
 	}
+	begin := time.Now()
 	dataList, errs := analyzer.Analyze(resp)
+	// 只有在没有得到任何数据时，分析错误才会被视为分析器的失败。
+	var analyzeErr error
+	if len(dataList) == 0 && len(errs) > 0 {
+		analyzeErr = errs[0]
+	}
+	sched.registrar.Report(m.ID(), analyzeErr, time.Since(begin))
 	if dataList != nil {
 		for _, data := range dataList {
 			if data == nil {
@@ -462,7 +745,9 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 			}
 			switch d := data.(type) {
 			case *module.Request:
-				sched.sendReq(d)
+				if !dup {
+					sched.sendReq(d)
+				}
 			case module.Item:
 				sendItem(d, sched.itemBufferPool)
 			default:
@@ -476,28 +761,44 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 			sendError(err, m.ID(), sched.errorBufferPool)
 		}
 	}
+	if sched.isDraining() {
+		atomic.AddUint64(&sched.drainCounts.flushedResponses, 1)
+	}
 }
 
 // pick 会从条目缓冲池取出条目并处理。
 func (sched *myScheduler) pick() {
-	go func() {
-		for {
-			if sched.canceled() {
-				break
-			}
-			datum, err := sched.itemBufferPool.Get()
-			if err != nil {
-				logger.Warnln("The item buffer pool was closed. Break item reception.")
-				break
+	stats := &sched.workers.pick
+	for i := 0; i < workerNumber(sched.dataArgs.Concurrency.PickWorkers); i++ {
+		go func() {
+			stats.start()
+			defer stats.exit()
+			for {
+				if sched.canceled() {
+					break
+				}
+				if !sched.waitIfPaused() {
+					break
+				}
+				datum, err := sched.itemBufferPool.Get()
+				if err != nil {
+					logger.Warnln("The item buffer pool was closed. Break item reception.")
+					break
+				}
+				if !sched.waitIfPaused() {
+					break
+				}
+				stats.begin()
+				item, ok := datum.(module.Item)
+				if !ok {
+					errMsg := fmt.Sprintf("incorrect item type: %T", datum)
+					sendError(errors.New(errMsg), "", sched.errorBufferPool)
+				}
+				sched.pickOne(item)
+				stats.end()
 			}
-			item, ok := datum.(module.Item)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect item type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
-			}
-			sched.pickOne(item)
-		}
-	}()
+		}()
+	}
 }
 
 // pickOne 会处理给定的条目。
@@ -505,13 +806,21 @@ func (sched *myScheduler) pickOne(item module.Item) {
 	if sched.canceled() {
 		return
 	}
-	m, err := sched.registrar.Get(module.TYPE_PIPELINE)
+	item, ok := sched.hookItem(item)
+	if !ok {
+		if sched.isDraining() {
+			atomic.AddUint64(&sched.drainCounts.flushedItems, 1)
+		}
+		return
+	}
+	m, err := sched.acquireModule(module.TYPE_PIPELINE)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get a pipeline: %s", err)
 		sendError(errors.New(errMsg), "", sched.errorBufferPool)
 		sendItem(item, sched.itemBufferPool)
 		return
 	}
+	defer sched.releaseModule(m)
 	pipeline, ok := m.(module.Pipeline)
 	if !ok {
 		errMsg := fmt.Sprintf("incorrect pipeline type: %T (MID: %s)",
@@ -520,12 +829,21 @@ func (sched *myScheduler) pickOne(item module.Item) {
 		sendItem(item, sched.itemBufferPool)
 		return
 	}
+	begin := time.Now()
 	errs := pipeline.Send(item)
+	var sendErr error
+	if len(errs) > 0 {
+		sendErr = errs[0]
+	}
+	sched.registrar.Report(m.ID(), sendErr, time.Since(begin))
 	if errs != nil {
 		for _, err := range errs {
 			sendError(err, m.ID(), sched.errorBufferPool)
 		}
 	}
+	if sched.isDraining() {
+		atomic.AddUint64(&sched.drainCounts.flushedItems, 1)
+	}
 }
 
 // sendReq 会向请求缓冲池发送请求。
@@ -537,6 +855,10 @@ func (sched *myScheduler) sendReq(req *module.Request) bool {
 	if sched.canceled() {
 		return false
 	}
+	req, ok := sched.hookRequest(req)
+	if !ok {
+		return false
+	}
 	httpReq := req.HTTPReq()
 	if httpReq == nil {
 		logger.Warnln("Ignore the request! Its HTTP request is invalid!")
@@ -553,17 +875,17 @@ func (sched *myScheduler) sendReq(req *module.Request) bool {
 			scheme, "http", "https", reqURL)
 		return false
 	}
-	if v := sched.urlMap.Get(reqURL.String()); v != nil {
+	if sched.discardIfDraining(req) {
+		return false
+	}
+	urlKey := sched.urlKey(reqURL)
+	if sched.urlSet.Contains(urlKey) {
 		logger.Warnf("Ignore the request! Its URL is repeated. (URL: %s)\n", reqURL)
 		return false
 	}
-	pd, _ := getPrimaryDomain(httpReq.Host)
-	if sched.acceptedDomainMap.Get(pd) == nil {
-		if pd == "bing.net" {
-			panic(httpReq.URL)
-		}
-		logger.Warnf("Ignore the request! Its host %q is not in accepted primary domain map. (URL: %s)\n",
-			httpReq.Host, reqURL)
+	if !sched.scope.contains(urlKey) {
+		logger.Warnf("Ignore the request! It is out of the crawl scope (mode: %s). (URL: %s)\n",
+			sched.scope.mode, reqURL)
 		return false
 	}
 	if req.Depth() > sched.maxDepth {
@@ -571,15 +893,32 @@ func (sched *myScheduler) sendReq(req *module.Request) bool {
 			req.Depth(), sched.maxDepth, reqURL)
 		return false
 	}
+	if !sched.robotsAllowed(req) {
+		sched.rejectByRobots(req)
+		return false
+	}
+	sched.urlSet.Put(urlKey)
+	sched.pendingReqMap.Put(urlKey, req)
 	go func(req *module.Request) {
-		if err := sched.reqBufferPool.Put(req); err != nil {
-			logger.Warnln("The request buffer pool was closed. Ignore request sending.")
+		if err := sched.frontier.Put(req); err != nil {
+			logger.Warnln("The frontier was closed. Ignore request sending.")
 		}
 	}(req)
-	sched.urlMap.Put(reqURL.String(), struct{}{})
 	return true
 }
 
+// rejectByRobots 用于忽略被robots.txt禁止访问的请求。
+func (sched *myScheduler) rejectByRobots(req *module.Request) {
+	atomic.AddUint64(&sched.numRobotsRejected, 1)
+	logger.Warnf("Ignore the request! It is disallowed by robots.txt. (URL: %s)\n",
+		req.HTTPReq().URL)
+}
+
+// urlKey 用于获取给定URL用于去重的键，即其规范形式。
+func (sched *myScheduler) urlKey(u *url.URL) string {
+	return sched.requestArgs.Canonical.Canonicalize(u)
+}
+
 // sendResp 会向响应缓冲池发送响应。
 func sendResp(resp *module.Response, respBufferPool buffer.Pool) bool {
 	if resp == nil || respBufferPool == nil || respBufferPool.Closed() {
@@ -606,17 +945,26 @@ func sendItem(item module.Item, itemBufferPool buffer.Pool) bool {
 	return true
 }
 
+// initFrontier 用于按照给定的参数初始化URL边界。
+// 如果URL边界可用且未关闭，就先关闭它。
+func (sched *myScheduler) initFrontier(dataArgs DataArgs) error {
+	if sched.frontier != nil && !sched.frontier.Closed() {
+		sched.frontier.Close()
+	}
+	capacity := uint64(dataArgs.ReqBufferCap) * uint64(dataArgs.ReqMaxBufferNumber)
+	frontier, err := NewFrontier(dataArgs.Frontier, capacity, sched.scoreFunc)
+	if err != nil {
+		return err
+	}
+	sched.frontier = frontier
+	logger.Infof("-- Frontier: type: %s, capacity: %d",
+		sched.frontier.Type(), sched.frontier.Cap())
+	return nil
+}
+
 // initBufferPool 用于按照给定的参数初始化缓冲池。
 // 如果某个缓冲池可用且未关闭，就先关闭该缓冲池。
 func (sched *myScheduler) initBufferPool(dataArgs DataArgs) {
-	// 初始化请求缓冲池。
-	if sched.reqBufferPool != nil && !sched.reqBufferPool.Closed() {
-		sched.reqBufferPool.Close()
-	}
-	sched.reqBufferPool, _ = buffer.NewPool(
-		dataArgs.ReqBufferCap, dataArgs.ReqMaxBufferNumber)
-	logger.Infof("-- Request buffer pool: bufferCap: %d, maxBufferNumber: %d",
-		sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber())
 	// 初始化响应缓冲池。
 	if sched.respBufferPool != nil && !sched.respBufferPool.Closed() {
 		sched.respBufferPool.Close()
@@ -643,17 +991,21 @@ func (sched *myScheduler) initBufferPool(dataArgs DataArgs) {
 		sched.errorBufferPool.BufferCap(), sched.errorBufferPool.MaxBufferNumber())
 }
 
-// checkBufferPoolForStart 会检查缓冲池是否已为调度器的启动准备就绪。
-// 如果某个缓冲池不可用，就直接返回错误值报告此情况。
-// 如果某个缓冲池已关闭，就按照原先的参数重新初始化它。
+// checkBufferPoolForStart 会检查URL边界和缓冲池是否已为调度器的启动准备就绪。
+// 如果URL边界或某个缓冲池不可用，就直接返回错误值报告此情况。
+// 如果URL边界或某个缓冲池已关闭，就按照原先的参数重新初始化它。
 func (sched *myScheduler) checkBufferPoolForStart() error {
-	// 检查请求缓冲池。
-	if sched.reqBufferPool == nil {
-		return genError("nil request buffer pool")
+	// 检查URL边界。
+	if sched.frontier == nil {
+		return genError("nil frontier")
 	}
-	if sched.reqBufferPool != nil && sched.reqBufferPool.Closed() {
-		sched.reqBufferPool, _ = buffer.NewPool(
-			sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber())
+	if sched.frontier.Closed() {
+		frontier, err := NewFrontier(
+			sched.frontier.Type(), sched.frontier.Cap(), sched.scoreFunc)
+		if err != nil {
+			return err
+		}
+		sched.frontier = frontier
 	}
 	// 检查响应缓冲池。
 	if sched.respBufferPool == nil {
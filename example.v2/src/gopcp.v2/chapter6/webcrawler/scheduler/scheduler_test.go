@@ -2,12 +2,14 @@ package scheduler
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"gopcp.v2/chapter5/cmap"
 	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/module/local/pipeline"
 	"gopcp.v2/chapter6/webcrawler/toolkit/buffer"
 )
 
@@ -91,7 +93,7 @@ func TestSchedInit(t *testing.T) {
 			dataArgs,
 			invalidModuleArgs)
 		if err == nil {
-			t.Fatalf("No error when initialize scheduler with illegal module arguments %s!",
+			t.Fatalf("No error when initialize scheduler with illegal module arguments %v!",
 				invalidModuleArgs)
 		}
 	}
@@ -178,6 +180,48 @@ func TestSchedStop(t *testing.T) {
 	}
 }
 
+// testingCloser 代表测试专用的可关闭对象。
+type testingCloser struct {
+	closed int32
+}
+
+func (c *testingCloser) Close() error {
+	atomic.AddInt32(&c.closed, 1)
+	return nil
+}
+
+func TestSchedStopCloseModules(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	sched := NewScheduler()
+	requestArgs := genRequestArgs([]string{}, 0)
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(1, 1, 0, t)
+	closer := &testingCloser{}
+	p, err := pipeline.New("P1", []module.ProcessItem{processItem}, nil,
+		pipeline.WithClosers(closer))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a pipeline: %s", err)
+	}
+	moduleArgs.Pipelines = []module.Pipeline{p}
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL, nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	if closed := atomic.LoadInt32(&closer.closed); closed != 0 {
+		t.Fatalf("The pipeline has been closed before stopping! (closed: %d)", closed)
+	}
+	if err := sched.Stop(); err != nil {
+		t.Fatalf("An error occurs when stopping scheduler: %s", err)
+	}
+	if closed := atomic.LoadInt32(&closer.closed); closed != 1 {
+		t.Fatalf("Inconsistent close count: expected: %d, actual: %d", 1, closed)
+	}
+}
+
 func TestSchedStatus(t *testing.T) {
 	// 准备初始化参数。
 	requestArgs := genRequestArgs([]string{"bing.com"}, 0)
@@ -340,10 +384,10 @@ func TestSchedSendReq(t *testing.T) {
 			err)
 	}
 	mySched := sched.(*myScheduler)
-	urlMapLen := mySched.urlMap.Len()
-	if urlMapLen != 1 {
-		t.Fatalf("Inconsistent URL map length: expected: %d, actual: %d",
-			1, urlMapLen)
+	urlSetLen := mySched.urlSet.Len()
+	if urlSetLen != 1 {
+		t.Fatalf("Inconsistent URL set length: expected: %d, actual: %d",
+			1, urlSetLen)
 	}
 	// 测试参数无效的情况。
 	if mySched.sendReq(nil) {
@@ -364,7 +408,7 @@ func TestSchedSendReq(t *testing.T) {
 	if mySched.sendReq(req) {
 		t.Fatalf("It still can send repeated request!")
 	}
-	mySched.urlMap, _ = cmap.NewConcurrentMap(16, nil)
+	mySched.urlSet, _ = NewSeenSet(requestArgs.SeenSet)
 	// 测试scheme不匹配的情况。
 	httpReq.URL.Scheme = "tcp"
 	if mySched.sendReq(req) {
@@ -383,6 +427,46 @@ func TestSchedSendReq(t *testing.T) {
 	}
 }
 
+func TestSchedSendReqCanonical(t *testing.T) {
+	requestArgs := genRequestArgs([]string{"bing.com"}, 0)
+	sched := NewScheduler()
+	err := sched.Init(
+		requestArgs,
+		genDataArgs(10, 2, 1),
+		genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+	urls := []string{
+		"http://cn.bing.com/search?q=golang&first=1",
+		"http://CN.bing.com:80/search?first=1&q=golang",
+		"http://cn.bing.com/x/../search?q=golang&first=1&utm_source=test#results",
+	}
+	for i, u := range urls {
+		httpReq, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)",
+				err, u)
+		}
+		sent := mySched.sendReq(module.NewRequest(httpReq, 0))
+		if i == 0 && !sent {
+			t.Fatalf("Couldn't send request! (URL: %s)", u)
+		}
+		if i > 0 && sent {
+			t.Fatalf("It still can send request with equivalent URL! (URL: %s)", u)
+		}
+	}
+	expectedKey := "http://cn.bing.com/search?first=1&q=golang"
+	if !mySched.urlSet.Contains(expectedKey) {
+		t.Fatalf("Couldn't find the canonical URL %q in URL set!", expectedKey)
+	}
+	if urlSetLen := mySched.urlSet.Len(); urlSetLen != 1 {
+		t.Fatalf("Inconsistent URL set length: expected: %d, actual: %d",
+			1, urlSetLen)
+	}
+}
+
 func TestSendResp(t *testing.T) {
 	// 测试响应无效的情况。
 	buffer, _ := buffer.NewPool(10, 2)
@@ -420,3 +504,34 @@ func TestSendItem(t *testing.T) {
 		t.Fatalf("It still can send item with closed buffer!")
 	}
 }
+
+func TestSchedSelectors(t *testing.T) {
+	moduleArgs := genSimpleModuleArgs(3, 2, 1, t)
+	moduleArgs.Selectors = SelectorArgs{
+		Downloader: module.SELECTOR_TYPE_ROUND_ROBIN,
+		Analyzer:   module.SELECTOR_TYPE_LEAST_HANDLING,
+	}
+	sched := NewScheduler()
+	if err := sched.Init(genRequestArgs([]string{"bing.com"}, 0),
+		genDataArgs(10, 2, 1), moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	registrar := sched.(*myScheduler).registrar
+	selected := map[module.MID]int{}
+	for i := 0; i < 6; i++ {
+		m, err := registrar.Get(module.TYPE_DOWNLOADER)
+		if err != nil {
+			t.Fatalf("An error occurs when getting a downloader: %s", err)
+		}
+		selected[m.ID()]++
+	}
+	for _, d := range moduleArgs.Downloaders {
+		if selected[d.ID()] != 2 {
+			t.Fatalf("Inconsistent round robin selection: %v", selected)
+		}
+	}
+	summary := sched.Summary().Struct()
+	if summary.ModuleArgs.Selectors != moduleArgs.Selectors {
+		t.Fatalf("Inconsistent selectors in summary: %+v", summary.ModuleArgs.Selectors)
+	}
+}
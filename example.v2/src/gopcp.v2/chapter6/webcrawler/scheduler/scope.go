@@ -0,0 +1,247 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopcp.v2/chapter5/cmap"
+	"gopcp.v2/chapter6/webcrawler/toolkit/canonical"
+)
+
+// ScopeMode 代表爬取范围的判定模式。
+type ScopeMode string
+
+// 当前认可的爬取范围判定模式的常量。
+const (
+	// SCOPE_MODE_REGISTRABLE 代表按可注册域名（即主域名）判定。
+	// 主域名在可接受列表中的URL都在范围内。
+	SCOPE_MODE_REGISTRABLE ScopeMode = "registrable"
+	// SCOPE_MODE_HOST 代表按主机判定。
+	// 主机（包含端口）在可接受列表中的URL才在范围内。
+	SCOPE_MODE_HOST ScopeMode = "host"
+	// SCOPE_MODE_SUBDOMAIN 代表按子域名判定。
+	// 主机名等于可接受列表中的某个域名或为其子域名的URL都在范围内。
+	SCOPE_MODE_SUBDOMAIN ScopeMode = "subdomain"
+	// SCOPE_MODE_PREFIX 代表按URL前缀判定。
+	// 以可接受列表中的某个前缀开头的URL才在范围内。
+	SCOPE_MODE_PREFIX ScopeMode = "prefix"
+)
+
+// legalScopeModeMap 代表合法的爬取范围判定模式的字典。
+var legalScopeModeMap = map[ScopeMode]bool{
+	SCOPE_MODE_REGISTRABLE: true,
+	SCOPE_MODE_HOST:        true,
+	SCOPE_MODE_SUBDOMAIN:   true,
+	SCOPE_MODE_PREFIX:      true,
+}
+
+// ScopeArgs 代表爬取范围相关的参数容器的类型。
+type ScopeArgs struct {
+	// Mode 代表判定模式。若为空，则按可注册域名判定。
+	// 除了按URL前缀判定之外，可接受的列表都由请求相关参数中的
+	// AcceptedDomains和首次请求的URL共同决定。
+	Mode ScopeMode `json:"mode"`
+	// Prefixes 代表可接受的URL前缀的列表，仅在按URL前缀判定时有效。
+	// 首次请求的URL所在的目录也会被加入此列表。
+	// 前缀与URL一样，都会先按照请求相关参数中的Canonical规则被规范化。
+	Prefixes []string `json:"prefixes"`
+	// Allow 代表允许的URL的正则表达式列表。
+	// 若不为空，则URL必须至少匹配其中一个才在范围内。
+	Allow []string `json:"allow"`
+	// Deny 代表禁止的URL的正则表达式列表。
+	// 匹配其中任何一个的URL都不在范围内。
+	Deny []string `json:"deny"`
+}
+
+func (args *ScopeArgs) Check() error {
+	if args.Mode != "" && !legalScopeModeMap[args.Mode] {
+		return genError(fmt.Sprintf("illegal scope mode: %q", args.Mode))
+	}
+	for _, prefix := range args.Prefixes {
+		u, err := url.Parse(prefix)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return genError(fmt.Sprintf("illegal URL prefix: %q", prefix))
+		}
+	}
+	if _, err := compileRegexps(args.Allow); err != nil {
+		return err
+	}
+	if _, err := compileRegexps(args.Deny); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Same 用于判断两个爬取范围相关的参数容器是否相同。
+func (args *ScopeArgs) Same(another *ScopeArgs) bool {
+	if another == nil {
+		return false
+	}
+	return another.Mode == args.Mode &&
+		sameStrings(another.Prefixes, args.Prefixes) &&
+		sameStrings(another.Allow, args.Allow) &&
+		sameStrings(another.Deny, args.Deny)
+}
+
+// sameStrings 用于判断两个字符串列表是否相同。
+func sameStrings(one, another []string) bool {
+	if len(one) != len(another) {
+		return false
+	}
+	for i, s := range one {
+		if s != another[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// compileRegexps 用于编译正则表达式列表。
+func compileRegexps(exprs []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, genError(fmt.Sprintf("illegal regular expression %q: %s",
+				expr, err))
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+// scope 代表爬取范围的判定器。
+type scope struct {
+	// mode 代表判定模式。
+	mode ScopeMode
+	// acceptedMap 代表可接受的键的字典。
+	// 键的含义取决于判定模式，可能是主域名、主机、域名或URL前缀。
+	acceptedMap cmap.ConcurrentMap
+	// allow 代表允许的URL的正则表达式列表。
+	allow []*regexp.Regexp
+	// deny 代表禁止的URL的正则表达式列表。
+	deny []*regexp.Regexp
+	// canonical 代表URL规范化的规则。所有的键都由URL的规范形式生成。
+	canonical canonical.Rules
+}
+
+// newScope 用于根据参数创建爬取范围的判定器。
+// 参数acceptedDomains代表可接受的主域名、主机或域名的列表。
+// 参数rules代表URL规范化的规则，应与生成URL的键时所用的规则一致。
+func newScope(args ScopeArgs, acceptedDomains []string, rules canonical.Rules) (*scope, error) {
+	if err := args.Check(); err != nil {
+		return nil, err
+	}
+	mode := args.Mode
+	if mode == "" {
+		mode = SCOPE_MODE_REGISTRABLE
+	}
+	s := &scope{mode: mode, canonical: rules}
+	s.allow, _ = compileRegexps(args.Allow)
+	s.deny, _ = compileRegexps(args.Deny)
+	s.acceptedMap, _ = cmap.NewConcurrentMap(1, nil)
+	switch mode {
+	case SCOPE_MODE_PREFIX:
+		for _, prefix := range args.Prefixes {
+			u, _ := url.Parse(prefix)
+			s.accept(rules.Canonicalize(u))
+		}
+	default:
+		for _, domain := range acceptedDomains {
+			s.accept(strings.ToLower(domain))
+		}
+	}
+	return s, nil
+}
+
+// accept 用于添加可接受的键。
+func (s *scope) accept(key string) {
+	s.acceptedMap.Put(key, struct{}{})
+}
+
+// keyOf 用于根据URL生成在当前模式下可接受的键，用于接受首次请求所在的范围。
+func (s *scope) keyOf(u *url.URL) (string, error) {
+	if strings.TrimSpace(u.Host) == "" {
+		return "", genError("empty host")
+	}
+	u, err := url.Parse(s.canonical.Canonicalize(u))
+	if err != nil {
+		return "", genError(fmt.Sprintf("illegal URL: %s", err))
+	}
+	switch s.mode {
+	case SCOPE_MODE_HOST:
+		return strings.ToLower(u.Host), nil
+	case SCOPE_MODE_SUBDOMAIN:
+		return hostnameOf(u.Host), nil
+	case SCOPE_MODE_PREFIX:
+		dir := u.EscapedPath()
+		if !strings.HasSuffix(dir, "/") {
+			dir = path.Dir(dir)
+			if !strings.HasSuffix(dir, "/") {
+				dir += "/"
+			}
+		}
+		return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + dir, nil
+	default:
+		return getPrimaryDomain(u.Host)
+	}
+}
+
+// contains 用于判断URL是否在爬取范围内。
+// 参数urlKey代表按照判定器的规则生成的URL的规范形式，
+// 正则表达式、URL前缀以及主机等都会依据它来判定。
+func (s *scope) contains(urlKey string) bool {
+	u, err := url.Parse(urlKey)
+	if err != nil {
+		return false
+	}
+	for _, re := range s.deny {
+		if re.MatchString(urlKey) {
+			return false
+		}
+	}
+	if len(s.allow) > 0 {
+		var allowed bool
+		for _, re := range s.allow {
+			if re.MatchString(urlKey) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	switch s.mode {
+	case SCOPE_MODE_HOST:
+		return s.acceptedMap.Get(strings.ToLower(u.Host)) != nil
+	case SCOPE_MODE_SUBDOMAIN:
+		hostname := hostnameOf(u.Host)
+		for {
+			if s.acceptedMap.Get(hostname) != nil {
+				return true
+			}
+			i := strings.IndexByte(hostname, '.')
+			if i < 0 {
+				return false
+			}
+			hostname = hostname[i+1:]
+		}
+	case SCOPE_MODE_PREFIX:
+		var matched bool
+		s.acceptedMap.Range(func(key string, element interface{}) bool {
+			matched = strings.HasPrefix(urlKey, key)
+			return !matched
+		})
+		return matched
+	default:
+		pd, err := getPrimaryDomain(u.Host)
+		if err != nil {
+			return false
+		}
+		return s.acceptedMap.Get(pd) != nil
+	}
+}
@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"net/url"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/toolkit/canonical"
+)
+
+func TestScopeArgs(t *testing.T) {
+	validArgsList := []ScopeArgs{
+		{},
+		{Mode: SCOPE_MODE_HOST},
+		{Mode: SCOPE_MODE_PREFIX, Prefixes: []string{"http://a.com/docs/"}},
+		{Allow: []string{`/docs/`}, Deny: []string{`\.pdf$`}},
+	}
+	for _, args := range validArgsList {
+		if err := args.Check(); err != nil {
+			t.Fatalf("An error occurs when checking scope arguments: %s (args: %#v)",
+				err, args)
+		}
+	}
+	invalidArgsList := []ScopeArgs{
+		{Mode: "random"},
+		{Mode: SCOPE_MODE_PREFIX, Prefixes: []string{"/docs/"}},
+		{Allow: []string{`(`}},
+		{Deny: []string{`[`}},
+	}
+	for _, args := range invalidArgsList {
+		if err := args.Check(); err == nil {
+			t.Fatalf("No error when check scope arguments! (args: %#v)", args)
+		}
+		requestArgs := genRequestArgs([]string{}, 0)
+		requestArgs.Scope = args
+		if err := requestArgs.Check(); err == nil {
+			t.Fatalf("No error when check request arguments with illegal scope! (args: %#v)",
+				args)
+		}
+	}
+	one := ScopeArgs{Mode: SCOPE_MODE_HOST, Deny: []string{"a"}}
+	another := ScopeArgs{Mode: SCOPE_MODE_HOST, Deny: []string{"a"}}
+	if !one.Same(&another) {
+		t.Fatalf("Inconsistent scope arguments: expected: %#v, actual: %#v",
+			one, another)
+	}
+	another.Deny = []string{"b"}
+	if one.Same(&another) {
+		t.Fatal("Same scope arguments with different deny list!")
+	}
+}
+
+func TestScope(t *testing.T) {
+	cases := []struct {
+		args     ScopeArgs
+		accepted []string
+		firstURL string
+		inScope  []string
+		outScope []string
+	}{
+		{
+			args:     ScopeArgs{},
+			firstURL: "http://www.example.co.uk/",
+			inScope:  []string{"http://news.example.co.uk/a", "https://example.co.uk:8080/"},
+			outScope: []string{"http://other.co.uk/", "http://example.com/"},
+		},
+		{
+			args:     ScopeArgs{Mode: SCOPE_MODE_REGISTRABLE},
+			accepted: []string{"Example.COM"},
+			firstURL: "http://www.example.org/",
+			inScope:  []string{"http://www.EXAMPLE.com/a", "http://news.example.com/", "http://example.org/"},
+			outScope: []string{"http://example.net/"},
+		},
+		{
+			args:     ScopeArgs{Mode: SCOPE_MODE_HOST},
+			accepted: []string{"Static.Example.com"},
+			firstURL: "http://www.example.com/",
+			inScope:  []string{"http://www.example.com/a", "http://static.example.com/b"},
+			outScope: []string{"http://news.example.com/", "http://www.example.com:8080/"},
+		},
+		{
+			args:     ScopeArgs{Mode: SCOPE_MODE_HOST},
+			firstURL: "http://WWW.example.com:80/",
+			inScope:  []string{"http://www.example.com/a", "http://www.example.com.:80/b"},
+			outScope: []string{"https://www.example.com:80/", "http://www.example.com:8080/"},
+		},
+		{
+			args:     ScopeArgs{Mode: SCOPE_MODE_SUBDOMAIN},
+			firstURL: "http://blog.example.com/",
+			inScope:  []string{"http://blog.example.com/a", "http://a.b.blog.example.com:8080/"},
+			outScope: []string{"http://example.com/", "http://xblog.example.com/"},
+		},
+		{
+			args:     ScopeArgs{Mode: SCOPE_MODE_PREFIX, Prefixes: []string{"http://EXAMPLE.com/api/"}},
+			firstURL: "http://example.com/docs/index.html",
+			inScope:  []string{"http://example.com/docs/a/b.html", "http://example.com/api/v1"},
+			outScope: []string{"http://example.com/", "http://example.com/docsx", "https://example.com/docs/"},
+		},
+		{
+			args:     ScopeArgs{Mode: SCOPE_MODE_PREFIX, Prefixes: []string{"http://example.com:80/api/../v2/"}},
+			firstURL: "HTTP://Example.com:80/docs/./index.html",
+			inScope:  []string{"http://example.com/docs/a.html", "http://example.com:80/v2/a"},
+			outScope: []string{"http://example.com/api/", "http://example.com:8080/docs/"},
+		},
+		{
+			args: ScopeArgs{
+				Allow: []string{`/docs/`, `/api/`},
+				Deny:  []string{`\.pdf$`},
+			},
+			firstURL: "http://example.com/docs/",
+			inScope:  []string{"http://example.com/docs/a.html", "http://www.example.com/api/1"},
+			outScope: []string{"http://example.com/blog/", "http://example.com/docs/a.pdf"},
+		},
+	}
+	for _, c := range cases {
+		s, err := newScope(c.args, c.accepted, canonical.Rules{})
+		if err != nil {
+			t.Fatalf("An error occurs when creating scope: %s (args: %#v)",
+				err, c.args)
+		}
+		firstURL, _ := url.Parse(c.firstURL)
+		key, err := s.keyOf(firstURL)
+		if err != nil {
+			t.Fatalf("An error occurs when getting scope key: %s (URL: %s)",
+				err, c.firstURL)
+		}
+		s.accept(key)
+		for _, rawURL := range c.inScope {
+			u, _ := url.Parse(rawURL)
+			if !s.contains(s.canonical.Canonicalize(u)) {
+				t.Fatalf("The URL %s is out of scope! (args: %#v)", rawURL, c.args)
+			}
+		}
+		for _, rawURL := range c.outScope {
+			u, _ := url.Parse(rawURL)
+			if s.contains(s.canonical.Canonicalize(u)) {
+				t.Fatalf("The URL %s is in scope! (args: %#v)", rawURL, c.args)
+			}
+		}
+	}
+	s, _ := newScope(ScopeArgs{}, nil, canonical.Rules{})
+	if _, err := s.keyOf(&url.URL{Scheme: "http"}); err == nil {
+		t.Fatal("No error when getting scope key for an empty host!")
+	}
+}
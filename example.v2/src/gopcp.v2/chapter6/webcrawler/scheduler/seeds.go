@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/sitemap"
+)
+
+// SitemapArgs 代表站点地图相关的参数容器的类型。
+type SitemapArgs struct {
+	// Discover 代表是否发现并展开种子请求所在站点的站点地图。
+	// 若为true，则调度器会在启动时获取这些站点的robots.txt，
+	// 并把其中通过Sitemap指令声明的站点地图（包括经过gzip压缩的站点地图
+	// 和站点地图索引）中的URL作为深度为0的请求。
+	Discover bool `json:"discover"`
+	// ModifiedSince 代表增量爬取的起始时间。
+	// 若不为零值，则lastmod不晚于此时间的URL和站点地图都会被忽略，
+	// 而没有lastmod的URL和站点地图总会被保留。
+	ModifiedSince time.Time `json:"modified_since"`
+	// MaxURLs 代表从站点地图中最多得到的URL的数量。若为0，则不做限制。
+	MaxURLs uint32 `json:"max_urls"`
+}
+
+// Same 用于判断两个站点地图相关的参数容器是否相同。
+func (args *SitemapArgs) Same(another *SitemapArgs) bool {
+	if another == nil {
+		return false
+	}
+	return another.Discover == args.Discover &&
+		another.ModifiedSince.Equal(args.ModifiedSince) &&
+		another.MaxURLs == args.MaxURLs
+}
+
+// discoverSitemaps 会在后台展开种子请求所在站点的站点地图，
+// 并把其中的URL作为深度为0的请求放入URL边界。
+// 在展开完毕之前，调度器不会被视为空闲的。
+func (sched *myScheduler) discoverSitemaps(seedHTTPReqs []*http.Request) {
+	if !sched.requestArgs.Sitemap.Discover {
+		return
+	}
+	var sites [][2]string
+	siteSet := map[string]bool{}
+	for _, seedHTTPReq := range seedHTTPReqs {
+		scheme := strings.ToLower(seedHTTPReq.URL.Scheme)
+		host := strings.ToLower(seedHTTPReq.URL.Host)
+		if siteSet[scheme+"://"+host] {
+			continue
+		}
+		siteSet[scheme+"://"+host] = true
+		sites = append(sites, [2]string{scheme, host})
+	}
+	atomic.AddInt64(&sched.pendingSeeds, 1)
+	go func() {
+		defer atomic.AddInt64(&sched.pendingSeeds, -1)
+		var locs []string
+		for _, site := range sites {
+			data, _ := sched.robots(site[0], site[1])
+			locs = append(locs, data.sitemaps...)
+		}
+		if len(locs) == 0 {
+			logger.Info("No sitemap has been declared in robots.txt.")
+			return
+		}
+		logger.Infof("Expand sitemaps %v...", locs)
+		args := sched.requestArgs.Sitemap
+		entries, errs := sitemap.Expand(
+			locs, sched.fetchSitemap, args.ModifiedSince, int(args.MaxURLs))
+		for _, err := range errs {
+			sendError(err, "", sched.errorBufferPool)
+		}
+		for _, entry := range entries {
+			if sched.canceled() {
+				return
+			}
+			httpReq, err := http.NewRequest("GET", entry.Loc, nil)
+			if err != nil {
+				sendError(err, "", sched.errorBufferPool)
+				continue
+			}
+			if sched.sendReq(module.NewRequest(httpReq, 0)) {
+				atomic.AddUint64(&sched.numSitemapURLs, 1)
+			}
+		}
+		logger.Infof("Sitemaps have been expanded. (URL number: %d)",
+			len(entries))
+	}()
+}
+
+// fetchSitemap 用于通过已注册的下载器获取站点地图的内容。
+func (sched *myScheduler) fetchSitemap(loc string) (io.ReadCloser, error) {
+	httpReq, err := http.NewRequest("GET", loc, nil)
+	if err != nil {
+		return nil, err
+	}
+	if userAgent := sched.requestArgs.Robots.UserAgent; userAgent != "" {
+		httpReq.Header.Set("User-Agent", userAgent)
+	}
+	httpResp, _, err := sched.downloadDirectly(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		if httpResp.Body != nil {
+			httpResp.Body.Close()
+		}
+		return nil, fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
+	}
+	return httpResp.Body, nil
+}
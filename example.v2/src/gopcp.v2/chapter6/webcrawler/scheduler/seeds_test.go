@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSeedsWithSitemap(t *testing.T) {
+	var lock sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		hits[r.URL.Path]++
+		lock.Unlock()
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nCrawl-delay: 5\nSitemap: %s/sitemap.xml\n", base)
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s/pages.xml.gz</loc></sitemap></sitemapindex>`, base)
+		case "/pages.xml.gz":
+			writer := gzip.NewWriter(w)
+			fmt.Fprintf(writer, `<urlset>
+<url><loc>%[1]s/a</loc><lastmod>2024-05-01</lastmod></url>
+<url><loc>%[1]s/b</loc><lastmod>2019-05-01</lastmod></url>
+<url><loc>%[1]s/c</loc></url>
+<url><loc>http://out.of.scope/d</loc></url>
+</urlset>`, base)
+			writer.Close()
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><body></body></html>")
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	requestArgs := genRequestArgs([]string{host}, 0)
+	requestArgs.Sitemap = SitemapArgs{
+		Discover:      true,
+		ModifiedSince: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, genDataArgs(10, 2, 1),
+		genSimpleModuleArgs(1, 1, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if err := sched.StartWithSeeds(nil); err == nil {
+		t.Fatal("No error when starting scheduler with empty seed list!")
+	}
+	if err := sched.StartWithSeeds([]*http.Request{nil}); err == nil {
+		t.Fatal("No error when starting scheduler with nil seed!")
+	}
+	var seeds []*http.Request
+	for _, path := range []string{"/", "/c"} {
+		httpReq, _ := http.NewRequest("GET", server.URL+path, nil)
+		seeds = append(seeds, httpReq)
+	}
+	if err := sched.StartWithSeeds(seeds); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	hitsOf := func(path string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return hits[path]
+	}
+	ok := waitFor(10*time.Second, func() bool {
+		return hitsOf("/a") > 0 && sched.Idle()
+	})
+	if !ok {
+		t.Fatal("The URLs in sitemap have not been crawled!")
+	}
+	expectedHits := map[string]int{"/": 1, "/a": 1, "/b": 0, "/c": 1}
+	for path, expected := range expectedHits {
+		if hit := hitsOf(path); hit != expected {
+			t.Fatalf("Inconsistent hits: expected: %d, actual: %d (path: %s)",
+				expected, hit, path)
+		}
+	}
+	if num := sched.Summary().Struct().NumSitemapURLs; num != 1 {
+		t.Fatalf("Inconsistent sitemap URL number: expected: %d, actual: %d",
+			1, num)
+	}
+	// 未遵守robots.txt时，其中的抓取延迟不会被应用。
+	hd := sched.(*myScheduler).hostDispatcher
+	hd.lock.Lock()
+	defer hd.lock.Unlock()
+	if delay := hd.stateLocked(host).crawlDelay; delay != 0 {
+		t.Fatalf("The crawl delay has been applied without obeying robots.txt! (delay: %s)",
+			delay)
+	}
+}
@@ -0,0 +1,178 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"gopcp.v2/chapter5/cmap"
+	"gopcp.v2/chapter6/webcrawler/toolkit/bloom"
+)
+
+// SeenSetType 代表已处理URL集合的类型。
+type SeenSetType string
+
+// 当前认可的已处理URL集合类型的常量。
+const (
+	// SEEN_SET_TYPE_EXACT 代表精确的集合，它会保存所有的URL。
+	SEEN_SET_TYPE_EXACT SeenSetType = "exact"
+	// SEEN_SET_TYPE_BLOOM 代表基于可扩展布隆过滤器的集合。
+	// 它占用的内存远少于精确的集合，但会以一定的概率把未处理的URL误判为已处理。
+	SEEN_SET_TYPE_BLOOM SeenSetType = "bloom"
+)
+
+// legalSeenSetTypeMap 代表合法的已处理URL集合类型的字典。
+var legalSeenSetTypeMap = map[SeenSetType]bool{
+	SEEN_SET_TYPE_EXACT: true,
+	SEEN_SET_TYPE_BLOOM: true,
+}
+
+// 已处理URL集合的默认参数。
+const (
+	// defaultBloomCapacity 代表布隆过滤器默认的初始容量。
+	defaultBloomCapacity = 1 << 16
+	// defaultBloomFPRate 代表布隆过滤器默认的误判率。
+	defaultBloomFPRate = 0.001
+	// exactEntryOverhead 代表精确的集合中每个URL额外占用的内存的估计字节数。
+	exactEntryOverhead = 64
+)
+
+// SeenSetArgs 代表已处理URL集合相关的参数容器的类型。
+type SeenSetArgs struct {
+	// Type 代表集合的类型。若为空，则使用精确的集合。
+	Type SeenSetType `json:"type"`
+	// InitialCapacity 代表布隆过滤器的初始容量。
+	// 仅对基于布隆过滤器的集合有效，若为0，则使用默认值。
+	InitialCapacity uint64 `json:"initial_capacity"`
+	// FalsePositiveRate 代表布隆过滤器的误判率。
+	// 仅对基于布隆过滤器的集合有效，若为0，则使用默认值。
+	FalsePositiveRate float64 `json:"false_positive_rate"`
+}
+
+func (args *SeenSetArgs) Check() error {
+	if args.Type != "" && !legalSeenSetTypeMap[args.Type] {
+		return genError(fmt.Sprintf("illegal seen set type: %q", args.Type))
+	}
+	if args.FalsePositiveRate < 0 || args.FalsePositiveRate >= 1 {
+		return genError(fmt.Sprintf("illegal false positive rate: %f",
+			args.FalsePositiveRate))
+	}
+	return nil
+}
+
+// SeenSet 代表已处理URL集合的接口类型。
+// 该接口的实现类型必须是并发安全的。
+type SeenSet interface {
+	// Type 用于获取集合的类型。
+	Type() SeenSetType
+	// Put 用于添加URL。
+	Put(url string)
+	// Contains 用于判断URL是否已被添加过。
+	Contains(url string) bool
+	// Len 用于获取已添加的URL的数量。
+	Len() uint64
+	// MemoryUsage 用于获取集合占用的内存的估计字节数。
+	MemoryUsage() uint64
+}
+
+// NewSeenSet 用于根据参数创建一个已处理URL集合。
+func NewSeenSet(args SeenSetArgs) (SeenSet, error) {
+	if err := args.Check(); err != nil {
+		return nil, err
+	}
+	switch args.Type {
+	case SEEN_SET_TYPE_BLOOM:
+		capacity := args.InitialCapacity
+		if capacity == 0 {
+			capacity = defaultBloomCapacity
+		}
+		fpRate := args.FalsePositiveRate
+		if fpRate == 0 {
+			fpRate = defaultBloomFPRate
+		}
+		filter, err := bloom.New(capacity, fpRate)
+		if err != nil {
+			return nil, genError(fmt.Sprintf("couldn't create bloom filter: %s", err))
+		}
+		return &bloomSeenSet{filter: filter}, nil
+	default:
+		urls, _ := cmap.NewConcurrentMap(16, nil)
+		return &exactSeenSet{urls: urls}, nil
+	}
+}
+
+// SeenSetSummaryStruct 代表已处理URL集合的摘要类型。
+type SeenSetSummaryStruct struct {
+	Type        SeenSetType `json:"type"`
+	MemoryUsage uint64      `json:"memory_usage"`
+}
+
+// getSeenSetSummary 用于生成和返回已处理URL集合的摘要信息。
+func getSeenSetSummary(seenSet SeenSet) SeenSetSummaryStruct {
+	return SeenSetSummaryStruct{
+		Type:        seenSet.Type(),
+		MemoryUsage: seenSet.MemoryUsage(),
+	}
+}
+
+// exactSeenSet 代表精确的已处理URL集合的实现类型。
+type exactSeenSet struct {
+	// urls 代表已处理的URL的字典。
+	urls cmap.ConcurrentMap
+	// memoryUsage 代表占用的内存的估计字节数。
+	memoryUsage uint64
+}
+
+func (set *exactSeenSet) Type() SeenSetType {
+	return SEEN_SET_TYPE_EXACT
+}
+
+func (set *exactSeenSet) Put(url string) {
+	if ok, _ := set.urls.Put(url, struct{}{}); ok {
+		atomic.AddUint64(&set.memoryUsage, uint64(len(url))+exactEntryOverhead)
+	}
+}
+
+func (set *exactSeenSet) Contains(url string) bool {
+	return set.urls.Get(url) != nil
+}
+
+func (set *exactSeenSet) Len() uint64 {
+	return set.urls.Len()
+}
+
+func (set *exactSeenSet) MemoryUsage() uint64 {
+	return atomic.LoadUint64(&set.memoryUsage)
+}
+
+// Range 用于遍历所有的URL。若fn返回false，则遍历会终止。
+func (set *exactSeenSet) Range(fn func(url string) bool) {
+	set.urls.Range(func(key string, element interface{}) bool {
+		return fn(key)
+	})
+}
+
+// bloomSeenSet 代表基于布隆过滤器的已处理URL集合的实现类型。
+type bloomSeenSet struct {
+	// filter 代表可扩展布隆过滤器。
+	filter *bloom.Filter
+}
+
+func (set *bloomSeenSet) Type() SeenSetType {
+	return SEEN_SET_TYPE_BLOOM
+}
+
+func (set *bloomSeenSet) Put(url string) {
+	set.filter.Add(url)
+}
+
+func (set *bloomSeenSet) Contains(url string) bool {
+	return set.filter.Test(url)
+}
+
+func (set *bloomSeenSet) Len() uint64 {
+	return set.filter.Len()
+}
+
+func (set *bloomSeenSet) MemoryUsage() uint64 {
+	return set.filter.MemoryUsage()
+}
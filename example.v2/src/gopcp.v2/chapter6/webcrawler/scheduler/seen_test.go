@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestSeenSetNew(t *testing.T) {
+	validArgsList := []SeenSetArgs{
+		{},
+		{Type: SEEN_SET_TYPE_EXACT},
+		{Type: SEEN_SET_TYPE_BLOOM},
+		{Type: SEEN_SET_TYPE_BLOOM, InitialCapacity: 100, FalsePositiveRate: 0.01},
+	}
+	for _, args := range validArgsList {
+		seenSet, err := NewSeenSet(args)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a seen set: %s (args: %#v)",
+				err, args)
+		}
+		expectedType := args.Type
+		if expectedType == "" {
+			expectedType = SEEN_SET_TYPE_EXACT
+		}
+		if seenSet.Type() != expectedType {
+			t.Fatalf("Inconsistent seen set type: expected: %s, actual: %s",
+				expectedType, seenSet.Type())
+		}
+	}
+	invalidArgsList := []SeenSetArgs{
+		{Type: "random"},
+		{Type: SEEN_SET_TYPE_BLOOM, FalsePositiveRate: -0.1},
+		{Type: SEEN_SET_TYPE_BLOOM, FalsePositiveRate: 1},
+	}
+	for _, args := range invalidArgsList {
+		if _, err := NewSeenSet(args); err == nil {
+			t.Fatalf("No error when creating a seen set with illegal arguments! (args: %#v)",
+				args)
+		}
+		requestArgs := genRequestArgs([]string{}, 0)
+		requestArgs.SeenSet = args
+		if err := requestArgs.Check(); err == nil {
+			t.Fatalf("No error when check request arguments with illegal seen set arguments! (args: %#v)",
+				args)
+		}
+	}
+}
+
+func TestSeenSet(t *testing.T) {
+	number := 10000
+	memoryUsages := map[SeenSetType]uint64{}
+	for _, seenSetType := range []SeenSetType{SEEN_SET_TYPE_EXACT, SEEN_SET_TYPE_BLOOM} {
+		seenSet, _ := NewSeenSet(SeenSetArgs{Type: seenSetType})
+		for i := 0; i < number; i++ {
+			url := fmt.Sprintf("http://a.com/very/long/path/to/page/%d.html", i)
+			if seenSet.Contains(url) && seenSetType == SEEN_SET_TYPE_EXACT {
+				t.Fatalf("The seen set contains a URL not put! (type: %s, URL: %s)",
+					seenSetType, url)
+			}
+			seenSet.Put(url)
+			seenSet.Put(url)
+			if !seenSet.Contains(url) {
+				t.Fatalf("The seen set doesn't contain the URL put! (type: %s, URL: %s)",
+					seenSetType, url)
+			}
+		}
+		if l := seenSet.Len(); l > uint64(number) || l < uint64(number)*99/100 {
+			t.Fatalf("Inconsistent seen set length: expected: about %d, actual: %d (type: %s)",
+				number, l, seenSetType)
+		}
+		memoryUsages[seenSetType] = seenSet.MemoryUsage()
+	}
+	if memoryUsages[SEEN_SET_TYPE_BLOOM] == 0 ||
+		memoryUsages[SEEN_SET_TYPE_BLOOM] >= memoryUsages[SEEN_SET_TYPE_EXACT] {
+		t.Fatalf("The bloom seen set doesn't save memory! (memory usages: %v)",
+			memoryUsages)
+	}
+}
+
+func TestSeenSetCheckpoint(t *testing.T) {
+	server := newPageServer(map[string][]string{"/": {}})
+	defer server.Close()
+	requestArgs := genRequestArgs([]string{server.Host()}, 1)
+	requestArgs.SeenSet = SeenSetArgs{Type: SEEN_SET_TYPE_BLOOM}
+	sched := NewScheduler()
+	err := sched.Init(
+		requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+	urls := []string{server.URL + "/", server.URL + "/a"}
+	for _, u := range urls {
+		httpReq, _ := http.NewRequest("GET", u, nil)
+		if !mySched.sendReq(module.NewRequest(httpReq, 0)) {
+			t.Fatalf("Couldn't send request! (URL: %s)", u)
+		}
+	}
+	summary := sched.Summary().Struct()
+	if summary.URLSet.Type != SEEN_SET_TYPE_BLOOM || summary.URLSet.MemoryUsage == 0 {
+		t.Fatalf("Inconsistent URL set summary: %#v", summary.URLSet)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := sched.Checkpoint(path); err != nil {
+		t.Fatalf("An error occurs when saving snapshot: %s", err)
+	}
+	snapshot, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("An error occurs when loading snapshot: %s", err)
+	}
+	if len(snapshot.SeenURLs) != 0 || len(snapshot.SeenFilter) == 0 {
+		t.Fatalf("Inconsistent seen URLs in snapshot: %d URL(s), %d filter byte(s)",
+			len(snapshot.SeenURLs), len(snapshot.SeenFilter))
+	}
+	resumed, err := Resume(path, genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+	defer resumed.Stop()
+	urlSet := resumed.(*myScheduler).urlSet
+	if urlSet.Type() != SEEN_SET_TYPE_BLOOM {
+		t.Fatalf("Inconsistent URL set type: expected: %s, actual: %s",
+			SEEN_SET_TYPE_BLOOM, urlSet.Type())
+	}
+	for _, u := range urls {
+		if !urlSet.Contains(u) {
+			t.Fatalf("The resumed URL set doesn't contain %s!", u)
+		}
+	}
+}
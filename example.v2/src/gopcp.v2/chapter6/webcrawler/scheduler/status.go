@@ -23,17 +23,22 @@ const (
 	SCHED_STATUS_STOPPING Status = 5
 	// SCHED_STATUS_STOPPED 代表已停止的状态。
 	SCHED_STATUS_STOPPED Status = 6
+	// SCHED_STATUS_PAUSING 代表正在暂停的状态。
+	SCHED_STATUS_PAUSING Status = 7
+	// SCHED_STATUS_PAUSED 代表已暂停的状态。
+	SCHED_STATUS_PAUSED Status = 8
 )
 
 // checkStatus 用于状态的检查。
 // 参数currentStatus代表当前的状态。
 // 参数wantedStatus代表想要的状态。
 // 检查规则：
-//     1. 处于正在初始化、正在启动或正在停止状态时，不能从外部改变状态。
-//     2. 想要的状态只能是正在初始化、正在启动或正在停止状态中的一个。
-//     3. 处于未初始化状态时，不能变为正在启动或正在停止状态。
-//     4. 处于已启动状态时，不能变为正在初始化或正在启动状态。
-//     5. 只要未处于已启动状态就不能变为正在停止状态。
+//     1. 处于正在初始化、正在启动、正在停止或正在暂停状态时，不能从外部改变状态。
+//     2. 想要的状态只能是正在初始化、正在启动、正在停止或正在暂停状态中的一个。
+//     3. 处于未初始化状态时，不能变为正在启动、正在停止或正在暂停状态。
+//     4. 处于已启动或已暂停状态时，不能变为正在初始化或正在启动状态。
+//     5. 只要未处于已启动或已暂停状态就不能变为正在停止状态。
+//     6. 只要未处于已启动状态就不能变为正在暂停状态。
 func checkStatus(
 	currentStatus Status,
 	wantedStatus Status,
@@ -49,13 +54,16 @@ func checkStatus(
 		err = genError("the scheduler is being started!")
 	case SCHED_STATUS_STOPPING:
 		err = genError("the scheduler is being stopped!")
+	case SCHED_STATUS_PAUSING:
+		err = genError("the scheduler is being paused!")
 	}
 	if err != nil {
 		return
 	}
 	if currentStatus == SCHED_STATUS_UNINITIALIZED &&
 		(wantedStatus == SCHED_STATUS_STARTING ||
-			wantedStatus == SCHED_STATUS_STOPPING) {
+			wantedStatus == SCHED_STATUS_STOPPING ||
+			wantedStatus == SCHED_STATUS_PAUSING) {
 		err = genError("the scheduler has not yet been initialized!")
 		return
 	}
@@ -64,6 +72,8 @@ func checkStatus(
 		switch currentStatus {
 		case SCHED_STATUS_STARTED:
 			err = genError("the scheduler has been started!")
+		case SCHED_STATUS_PAUSED:
+			err = genError("the scheduler has been paused!")
 		}
 	case SCHED_STATUS_STARTING:
 		switch currentStatus {
@@ -71,9 +81,20 @@ func checkStatus(
 			err = genError("the scheduler has not been initialized!")
 		case SCHED_STATUS_STARTED:
 			err = genError("the scheduler has been started!")
+		case SCHED_STATUS_PAUSED:
+			err = genError("the scheduler has been paused!")
 		}
 	case SCHED_STATUS_STOPPING:
-		if currentStatus != SCHED_STATUS_STARTED {
+		if currentStatus != SCHED_STATUS_STARTED &&
+			currentStatus != SCHED_STATUS_PAUSED {
+			err = genError("the scheduler has not been started!")
+		}
+	case SCHED_STATUS_PAUSING:
+		switch currentStatus {
+		case SCHED_STATUS_STARTED:
+		case SCHED_STATUS_PAUSED:
+			err = genError("the scheduler has been paused!")
+		default:
 			err = genError("the scheduler has not been started!")
 		}
 	default:
@@ -102,6 +123,10 @@ func GetStatusDescription(status Status) string {
 		return "stopping"
 	case SCHED_STATUS_STOPPED:
 		return "stopped"
+	case SCHED_STATUS_PAUSING:
+		return "pausing"
+	case SCHED_STATUS_PAUSED:
+		return "paused"
 	default:
 		return "unknown"
 	}
@@ -85,11 +85,49 @@ func TestCheckStatus(t *testing.T) {
 				GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
 		}
 	}
+	for _, currentStatus := range []Status{
+		SCHED_STATUS_STARTED,
+		SCHED_STATUS_PAUSED,
+	} {
+		if err := checkStatus(currentStatus, wantedStatus, nil); err != nil {
+			t.Fatalf("An error occurs when checking status: %s (currentStatus: %q, wantedStatus: %q)!",
+				err, GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+		}
+	}
+	// 6. 只要未处于已启动状态就不能变为正在暂停状态。
+	currentStatusList = []Status{
+		SCHED_STATUS_UNINITIALIZED,
+		SCHED_STATUS_INITIALIZING,
+		SCHED_STATUS_INITIALIZED,
+		SCHED_STATUS_STARTING,
+		SCHED_STATUS_STOPPING,
+		SCHED_STATUS_STOPPED,
+		SCHED_STATUS_PAUSING,
+		SCHED_STATUS_PAUSED,
+	}
+	wantedStatus = SCHED_STATUS_PAUSING
+	for _, currentStatus := range currentStatusList {
+		if err := checkStatus(currentStatus, wantedStatus, nil); err == nil {
+			t.Fatalf("It still can check status with current status %q wanted status %q!",
+				GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+		}
+	}
 	currentStatus = SCHED_STATUS_STARTED
 	if err := checkStatus(currentStatus, wantedStatus, nil); err != nil {
 		t.Fatalf("An error occurs when checking status: %s (currentStatus: %q, wantedStatus: %q)!",
 			err, GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
 	}
+	// 处于已暂停状态时，不能变为正在初始化和正在启动状态。
+	currentStatus = SCHED_STATUS_PAUSED
+	for _, wantedStatus := range []Status{
+		SCHED_STATUS_INITIALIZING,
+		SCHED_STATUS_STARTING,
+	} {
+		if err := checkStatus(currentStatus, wantedStatus, nil); err == nil {
+			t.Fatalf("It still can check status with current status %q wanted status %q!",
+				GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+		}
+	}
 }
 
 func TestCheckStatusInParallel(t *testing.T) {
@@ -156,7 +194,9 @@ func TestGetStatusDescription(t *testing.T) {
 		SCHED_STATUS_STARTED:       "started",
 		SCHED_STATUS_STOPPING:      "stopping",
 		SCHED_STATUS_STOPPED:       "stopped",
-		Status(7):                  "unknown",
+		SCHED_STATUS_PAUSING:       "pausing",
+		SCHED_STATUS_PAUSED:        "paused",
+		Status(9):                  "unknown",
 	}
 	for status, expectedDesc := range statusMap {
 		desc := GetStatusDescription(status)
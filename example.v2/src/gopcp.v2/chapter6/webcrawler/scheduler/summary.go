@@ -3,6 +3,7 @@ package scheduler
 import (
 	"encoding/json"
 	"sort"
+	"sync/atomic"
 
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/toolkit/buffer"
@@ -49,18 +50,31 @@ type mySchedSummary struct {
 
 // SummaryStruct 代表调度器摘要的结构。
 type SummaryStruct struct {
-	RequestArgs     RequestArgs             `json:"request_args"`
-	DataArgs        DataArgs                `json:"data_args"`
-	ModuleArgs      ModuleArgsSummary       `json:"module_args"`
-	Status          string                  `json:"status"`
-	Downloaders     []module.SummaryStruct  `json:"downloaders"`
-	Analyzers       []module.SummaryStruct  `json:"analyzers"`
-	Pipelines       []module.SummaryStruct  `json:"pipelines"`
-	ReqBufferPool   BufferPoolSummaryStruct `json:"request_buffer_pool"`
-	RespBufferPool  BufferPoolSummaryStruct `json:"response_buffer_pool"`
-	ItemBufferPool  BufferPoolSummaryStruct `json:"item_buffer_pool"`
-	ErrorBufferPool BufferPoolSummaryStruct `json:"error_buffer_pool"`
-	NumURL          uint64                  `json:"url_number"`
+	RequestArgs     RequestArgs              `json:"request_args"`
+	DataArgs        DataArgs                 `json:"data_args"`
+	ModuleArgs      ModuleArgsSummary        `json:"module_args"`
+	Status          string                   `json:"status"`
+	Downloaders     []module.SummaryStruct   `json:"downloaders"`
+	Analyzers       []module.SummaryStruct   `json:"analyzers"`
+	Pipelines       []module.SummaryStruct   `json:"pipelines"`
+	Frontier        FrontierSummaryStruct    `json:"frontier"`
+	RespBufferPool  BufferPoolSummaryStruct  `json:"response_buffer_pool"`
+	ItemBufferPool  BufferPoolSummaryStruct  `json:"item_buffer_pool"`
+	ErrorBufferPool BufferPoolSummaryStruct  `json:"error_buffer_pool"`
+	HostQueues      []HostQueueSummaryStruct `json:"host_queues"`
+	Workers         WorkersSummaryStruct     `json:"workers"`
+	URLSet          SeenSetSummaryStruct     `json:"url_set"`
+	NumURL          uint64                   `json:"url_number"`
+	// NumRobotsRejected 代表因robots.txt而被忽略的请求的数量。
+	NumRobotsRejected uint64 `json:"robots_rejected_number"`
+	// NumRetried 代表已安排的下载重试的次数。
+	NumRetried uint64 `json:"retried_number"`
+	// NumSitemapURLs 代表从站点地图中得到的URL的数量。
+	NumSitemapURLs uint64 `json:"sitemap_url_number"`
+	// NumDuplicates 代表基于内容的重复页面检测所发现的重复页面的数量。
+	NumDuplicates uint64 `json:"duplicate_number"`
+	// DeadLetters 代表最终下载失败的请求的列表。
+	DeadLetters []DeadLetterStruct `json:"dead_letters"`
 }
 
 // Same 用于判断当前的调度器摘要与另一份是否相同。
@@ -101,7 +115,7 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 			return false
 		}
 	}
-	if another.ReqBufferPool != one.ReqBufferPool {
+	if another.Frontier != one.Frontier {
 		return false
 	}
 	if another.RespBufferPool != one.RespBufferPool {
@@ -113,27 +127,69 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 	if another.ErrorBufferPool != one.ErrorBufferPool {
 		return false
 	}
+	if len(another.HostQueues) != len(one.HostQueues) {
+		return false
+	}
+	for i, hq := range another.HostQueues {
+		if hq != one.HostQueues[i] {
+			return false
+		}
+	}
+	if another.Workers != one.Workers {
+		return false
+	}
+	if another.URLSet != one.URLSet {
+		return false
+	}
 	if another.NumURL != one.NumURL {
 		return false
 	}
+	if another.NumRobotsRejected != one.NumRobotsRejected {
+		return false
+	}
+	if another.NumRetried != one.NumRetried {
+		return false
+	}
+	if another.NumSitemapURLs != one.NumSitemapURLs {
+		return false
+	}
+	if another.NumDuplicates != one.NumDuplicates {
+		return false
+	}
+	if len(another.DeadLetters) != len(one.DeadLetters) {
+		return false
+	}
+	for i, dl := range another.DeadLetters {
+		if dl != one.DeadLetters[i] {
+			return false
+		}
+	}
 	return true
 }
 
 func (ss *mySchedSummary) Struct() SummaryStruct {
 	registrar := ss.sched.registrar
 	return SummaryStruct{
-		RequestArgs:     ss.requestArgs,
-		DataArgs:        ss.dataArgs,
-		ModuleArgs:      ss.moduleArgs.Summary(),
-		Status:          GetStatusDescription(ss.sched.Status()),
-		Downloaders:     getModuleSummaries(registrar, module.TYPE_DOWNLOADER),
-		Analyzers:       getModuleSummaries(registrar, module.TYPE_ANALYZER),
-		Pipelines:       getModuleSummaries(registrar, module.TYPE_PIPELINE),
-		ReqBufferPool:   getBufferPoolSummary(ss.sched.reqBufferPool),
-		RespBufferPool:  getBufferPoolSummary(ss.sched.respBufferPool),
-		ItemBufferPool:  getBufferPoolSummary(ss.sched.itemBufferPool),
-		ErrorBufferPool: getBufferPoolSummary(ss.sched.errorBufferPool),
-		NumURL:          ss.sched.urlMap.Len(),
+		RequestArgs:       ss.requestArgs,
+		DataArgs:          ss.dataArgs,
+		ModuleArgs:        ss.moduleArgs.Summary(),
+		Status:            GetStatusDescription(ss.sched.Status()),
+		Downloaders:       getModuleSummaries(registrar, module.TYPE_DOWNLOADER),
+		Analyzers:         getModuleSummaries(registrar, module.TYPE_ANALYZER),
+		Pipelines:         getModuleSummaries(registrar, module.TYPE_PIPELINE),
+		Frontier:          getFrontierSummary(ss.sched.frontier),
+		RespBufferPool:    getBufferPoolSummary(ss.sched.respBufferPool),
+		ItemBufferPool:    getBufferPoolSummary(ss.sched.itemBufferPool),
+		ErrorBufferPool:   getBufferPoolSummary(ss.sched.errorBufferPool),
+		HostQueues:        ss.sched.hostDispatcher.summary(),
+		Workers:           ss.sched.workers.summary(),
+		URLSet:            getSeenSetSummary(ss.sched.urlSet),
+		NumURL:            ss.sched.urlSet.Len(),
+		NumRobotsRejected: atomic.LoadUint64(&ss.sched.numRobotsRejected),
+		NumRetried:        atomic.LoadUint64(&ss.sched.numRetried),
+		NumSitemapURLs:    atomic.LoadUint64(&ss.sched.numSitemapURLs),
+		NumDuplicates:     ss.sched.Duplicates().Len(),
+		DeadLetters:       ss.sched.deadLetters.summary(),
 	}
 }
 
@@ -154,6 +210,22 @@ type BufferPoolSummaryStruct struct {
 	Total           uint64 `json:"total"`
 }
 
+// FrontierSummaryStruct 代表URL边界的摘要类型。
+type FrontierSummaryStruct struct {
+	Type     FrontierType `json:"type"`
+	Capacity uint64       `json:"capacity"`
+	Total    uint64       `json:"total"`
+}
+
+// getFrontierSummary 用于生成和返回URL边界的摘要信息。
+func getFrontierSummary(frontier Frontier) FrontierSummaryStruct {
+	return FrontierSummaryStruct{
+		Type:     frontier.Type(),
+		Capacity: frontier.Cap(),
+		Total:    frontier.Len(),
+	}
+}
+
 // getBufferPoolSummary 用于生成和返回某个数据缓冲池的摘要信息。
 func getBufferPoolSummary(bufferPool buffer.Pool) BufferPoolSummaryStruct {
 	return BufferPoolSummaryStruct{
@@ -169,8 +241,10 @@ func getModuleSummaries(registrar module.Registrar, mType module.Type) []module.
 	moduleMap, _ := registrar.GetAllByType(mType)
 	summaries := []module.SummaryStruct{}
 	if len(moduleMap) > 0 {
-		for _, module := range moduleMap {
-			summaries = append(summaries, module.Summary())
+		for mid, module := range moduleMap {
+			summary := module.Summary()
+			summary.Health, _ = registrar.Health(mid)
+			summaries = append(summaries, summary)
 		}
 	}
 	if len(summaries) > 1 {
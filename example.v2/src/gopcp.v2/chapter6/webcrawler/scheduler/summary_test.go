@@ -132,12 +132,12 @@ func TestSummaryStruct(t *testing.T) {
 	}
 	another.Pipelines = make([]module.SummaryStruct, len(one.Pipelines))
 	copy(another.Pipelines, one.Pipelines)
-	// 不同的请求缓冲池摘要。
-	another.ReqBufferPool.Total = 10
+	// 不同的URL边界摘要。
+	another.Frontier.Total = 10
 	if one.Same(another) {
-		t.Fatalf("Same scheduler summaries with different request buffer summary!")
+		t.Fatalf("Same scheduler summaries with different frontier summary!")
 	}
-	another.ReqBufferPool = one.ReqBufferPool
+	another.Frontier = one.Frontier
 	// 不同的响应缓冲池摘要。
 	another.RespBufferPool.Total = 11
 	if one.Same(another) {
@@ -185,22 +185,90 @@ func TestSummaryString(t *testing.T) {
 	expectedSummaryStr := `{
     "request_args": {
         "accepted_primary_domains": [],
-        "max_depth": 0
+        "max_depth": 0,
+        "politeness": {
+            "min_delay": 0,
+            "max_conns_per_host": 0,
+            "backoff_base": 0,
+            "backoff_max": 0
+        },
+        "robots": {
+            "obey": false,
+            "user_agent": "",
+            "retry_interval": 0
+        },
+        "canonical": {
+            "disabled": false,
+            "keep_fragment": false,
+            "keep_default_port": false,
+            "keep_query_order": false,
+            "tracking_params": null
+        },
+        "seen_set": {
+            "type": "",
+            "initial_capacity": 0,
+            "false_positive_rate": 0
+        },
+        "scope": {
+            "mode": "",
+            "prefixes": null,
+            "allow": null,
+            "deny": null
+        },
+        "retry": {
+            "max_attempts": 0,
+            "backoff_base": 0,
+            "backoff_max": 0,
+            "jitter": 0
+        },
+        "sitemap": {
+            "discover": false,
+            "modified_since": "0001-01-01T00:00:00Z",
+            "max_urls": 0
+        },
+        "dedup": {
+            "enabled": false,
+            "max_distance": 0,
+            "max_body_bytes": 0,
+            "max_entries": 0
+        }
     },
     "data_args": {
         "req_buffer_cap": 10,
         "req_max_buffer_number": 2,
+        "frontier": "",
         "resp_buffer_cap": 10,
         "resp_max_buffer_number": 2,
         "item_buffer_cap": 10,
         "item_max_buffer_number": 2,
         "error_buffer_cap": 10,
-        "error_max_buffer_number": 2
+        "error_max_buffer_number": 2,
+        "concurrency": {
+            "download_workers": 0,
+            "analyze_workers": 0,
+            "pick_workers": 0
+        },
+        "snapshot_path": "",
+        "snapshot_interval": 0
     },
     "module_args": {
         "downloader_list_size": 2,
         "analyzer_list_size": 2,
-        "pipeline_list_size": 1
+        "pipeline_list_size": 1,
+        "selectors": {
+            "downloader": "",
+            "analyzer": "",
+            "pipeline": ""
+        },
+        "health": {
+            "enabled": false,
+            "window_size": 0,
+            "min_calls": 0,
+            "failure_rate": 0,
+            "slow_threshold": 0,
+            "open_timeout": 0,
+            "half_open_probes": 0
+        }
     },
     "status": "initialized",
     "downloaders": [
@@ -209,14 +277,52 @@ func TestSummaryString(t *testing.T) {
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "latency": {
+                "p50": 0,
+                "p90": 0,
+                "p99": 0,
+                "max": 0
+            },
+            "bytes": 0,
+            "errors": {
+                "downloader": 0,
+                "analyzer": 0,
+                "pipeline": 0,
+                "scheduler": 0
+            },
+            "health": {
+                "state": "closed",
+                "calls": 0,
+                "failures": 0,
+                "avg_latency": 0
+            }
         },
         {
             "id": "D2",
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "latency": {
+                "p50": 0,
+                "p90": 0,
+                "p99": 0,
+                "max": 0
+            },
+            "bytes": 0,
+            "errors": {
+                "downloader": 0,
+                "analyzer": 0,
+                "pipeline": 0,
+                "scheduler": 0
+            },
+            "health": {
+                "state": "closed",
+                "calls": 0,
+                "failures": 0,
+                "avg_latency": 0
+            }
         }
     ],
     "analyzers": [
@@ -225,14 +331,52 @@ func TestSummaryString(t *testing.T) {
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "latency": {
+                "p50": 0,
+                "p90": 0,
+                "p99": 0,
+                "max": 0
+            },
+            "bytes": 0,
+            "errors": {
+                "downloader": 0,
+                "analyzer": 0,
+                "pipeline": 0,
+                "scheduler": 0
+            },
+            "health": {
+                "state": "closed",
+                "calls": 0,
+                "failures": 0,
+                "avg_latency": 0
+            }
         },
         {
             "id": "A4",
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "latency": {
+                "p50": 0,
+                "p90": 0,
+                "p99": 0,
+                "max": 0
+            },
+            "bytes": 0,
+            "errors": {
+                "downloader": 0,
+                "analyzer": 0,
+                "pipeline": 0,
+                "scheduler": 0
+            },
+            "health": {
+                "state": "closed",
+                "calls": 0,
+                "failures": 0,
+                "avg_latency": 0
+            }
         }
     ],
     "pipelines": [
@@ -242,16 +386,34 @@ func TestSummaryString(t *testing.T) {
             "accepted": 0,
             "completed": 0,
             "handling": 0,
+            "latency": {
+                "p50": 0,
+                "p90": 0,
+                "p99": 0,
+                "max": 0
+            },
+            "bytes": 0,
+            "errors": {
+                "downloader": 0,
+                "analyzer": 0,
+                "pipeline": 0,
+                "scheduler": 0
+            },
+            "health": {
+                "state": "closed",
+                "calls": 0,
+                "failures": 0,
+                "avg_latency": 0
+            },
             "extra": {
                 "fail_fast": false,
                 "processor_number": 1
             }
         }
     ],
-    "request_buffer_pool": {
-        "buffer_cap": 10,
-        "max_buffer_number": 2,
-        "buffer_number": 1,
+    "frontier": {
+        "type": "bfs",
+        "capacity": 20,
         "total": 0
     },
     "response_buffer_pool": {
@@ -272,7 +434,34 @@ func TestSummaryString(t *testing.T) {
         "buffer_number": 1,
         "total": 0
     },
-    "url_number": 0
+    "host_queues": [],
+    "workers": {
+        "download": {
+            "total": 0,
+            "busy": 0,
+            "idle": 0
+        },
+        "analyze": {
+            "total": 0,
+            "busy": 0,
+            "idle": 0
+        },
+        "pick": {
+            "total": 0,
+            "busy": 0,
+            "idle": 0
+        }
+    },
+    "url_set": {
+        "type": "exact",
+        "memory_usage": 0
+    },
+    "url_number": 0,
+    "robots_rejected_number": 0,
+    "retried_number": 0,
+    "sitemap_url_number": 0,
+    "duplicate_number": 0,
+    "dead_letters": []
 }`
 	summaryStr := summary.String()
 	if summaryStr != expectedSummaryStr {
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// maxWorkerNumber 代表每个阶段的工作协程的最大数量。
+const maxWorkerNumber = 1024
+
+// ConcurrencyArgs 代表并发相关的参数容器的类型。
+type ConcurrencyArgs struct {
+	// DownloadWorkers 代表下载请求的工作协程的数量。若为0，则使用1。
+	DownloadWorkers uint32 `json:"download_workers"`
+	// AnalyzeWorkers 代表分析响应的工作协程的数量。若为0，则使用1。
+	AnalyzeWorkers uint32 `json:"analyze_workers"`
+	// PickWorkers 代表处理条目的工作协程的数量。若为0，则使用1。
+	PickWorkers uint32 `json:"pick_workers"`
+}
+
+func (args *ConcurrencyArgs) Check() error {
+	for _, stage := range []struct {
+		name   string
+		number uint32
+	}{
+		{"download", args.DownloadWorkers},
+		{"analyze", args.AnalyzeWorkers},
+		{"pick", args.PickWorkers},
+	} {
+		if stage.number > maxWorkerNumber {
+			return genError(fmt.Sprintf("too many %s workers: %d (max: %d)",
+				stage.name, stage.number, maxWorkerNumber))
+		}
+	}
+	return nil
+}
+
+// workerNumber 用于获取实际使用的工作协程的数量。
+func workerNumber(number uint32) int {
+	if number == 0 {
+		return 1
+	}
+	return int(number)
+}
+
+// workerStats 代表某个阶段的工作协程的统计信息。
+type workerStats struct {
+	// total 代表正在运行的工作协程的数量。
+	total uint32
+	// busy 代表正在处理数据的工作协程的数量。
+	busy uint32
+}
+
+// start 用于记录一个工作协程的启动。
+func (stats *workerStats) start() {
+	atomic.AddUint32(&stats.total, 1)
+}
+
+// exit 用于记录一个工作协程的退出。
+func (stats *workerStats) exit() {
+	atomic.AddUint32(&stats.total, ^uint32(0))
+}
+
+// begin 用于记录一个工作协程开始处理数据。
+func (stats *workerStats) begin() {
+	atomic.AddUint32(&stats.busy, 1)
+}
+
+// end 用于记录一个工作协程处理数据完毕。
+func (stats *workerStats) end() {
+	atomic.AddUint32(&stats.busy, ^uint32(0))
+}
+
+// Busy 用于获取正在处理数据的工作协程的数量。
+func (stats *workerStats) Busy() uint32 {
+	return atomic.LoadUint32(&stats.busy)
+}
+
+// summary 用于生成和返回工作协程的摘要信息。
+func (stats *workerStats) summary() WorkerSummaryStruct {
+	total := atomic.LoadUint32(&stats.total)
+	busy := stats.Busy()
+	var idle uint32
+	if total > busy {
+		idle = total - busy
+	}
+	return WorkerSummaryStruct{
+		Total: total,
+		Busy:  busy,
+		Idle:  idle,
+	}
+}
+
+// stageWorkers 代表各个阶段的工作协程的统计信息。
+type stageWorkers struct {
+	// download 代表下载阶段的统计信息。
+	download workerStats
+	// analyze 代表分析阶段的统计信息。
+	analyze workerStats
+	// pick 代表条目处理阶段的统计信息。
+	pick workerStats
+}
+
+// busy 用于判断是否有工作协程正在处理数据。
+func (workers *stageWorkers) busy() bool {
+	return workers.download.Busy() > 0 ||
+		workers.analyze.Busy() > 0 ||
+		workers.pick.Busy() > 0
+}
+
+// summary 用于生成和返回各个阶段的工作协程的摘要信息。
+func (workers *stageWorkers) summary() WorkersSummaryStruct {
+	return WorkersSummaryStruct{
+		Download: workers.download.summary(),
+		Analyze:  workers.analyze.summary(),
+		Pick:     workers.pick.summary(),
+	}
+}
+
+// WorkerSummaryStruct 代表某个阶段的工作协程的摘要类型。
+type WorkerSummaryStruct struct {
+	Total uint32 `json:"total"`
+	Busy  uint32 `json:"busy"`
+	Idle  uint32 `json:"idle"`
+}
+
+// WorkersSummaryStruct 代表各个阶段的工作协程的摘要类型。
+type WorkersSummaryStruct struct {
+	Download WorkerSummaryStruct `json:"download"`
+	Analyze  WorkerSummaryStruct `json:"analyze"`
+	Pick     WorkerSummaryStruct `json:"pick"`
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWorkerArgsCheck(t *testing.T) {
+	args := ConcurrencyArgs{}
+	if err := args.Check(); err != nil {
+		t.Fatalf("An error occurs when checking concurrency arguments: %s (args: %#v)",
+			err, args)
+	}
+	args = ConcurrencyArgs{DownloadWorkers: 8, AnalyzeWorkers: 4, PickWorkers: 2}
+	if err := args.Check(); err != nil {
+		t.Fatalf("An error occurs when checking concurrency arguments: %s (args: %#v)",
+			err, args)
+	}
+	args.PickWorkers = maxWorkerNumber + 1
+	if err := args.Check(); err == nil {
+		t.Fatalf("No error when checking illegal concurrency arguments! (args: %#v)",
+			args)
+	}
+}
+
+func TestWorkerNumber(t *testing.T) {
+	server := newPageServer(map[string][]string{
+		"/": {"/a", "/b"},
+	})
+	defer server.Close()
+	requestArgs := genRequestArgs([]string{server.Host()}, 1)
+	dataArgs := genDataArgs(10, 2, 1)
+	dataArgs.Concurrency = ConcurrencyArgs{
+		DownloadWorkers: 3,
+		AnalyzeWorkers:  2,
+	}
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, genSimpleModuleArgs(3, 2, 1, t)); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if err := sched.Start(httpReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	var workers WorkersSummaryStruct
+	ok := waitFor(5*time.Second, func() bool {
+		workers = sched.Summary().Struct().Workers
+		return workers.Download.Total == 3 &&
+			workers.Analyze.Total == 2 &&
+			workers.Pick.Total == 1
+	})
+	if !ok {
+		t.Fatalf("Inconsistent worker numbers: expected: %d/%d/%d, actual: %d/%d/%d",
+			3, 2, 1, workers.Download.Total, workers.Analyze.Total, workers.Pick.Total)
+	}
+	for _, stage := range []WorkerSummaryStruct{
+		workers.Download, workers.Analyze, workers.Pick} {
+		if stage.Busy+stage.Idle != stage.Total {
+			t.Fatalf("Inconsistent worker summary: %#v", stage)
+		}
+	}
+	if err := sched.Stop(); err != nil {
+		t.Fatalf("An error occurs when stopping scheduler: %s", err)
+	}
+	ok = waitFor(5*time.Second, func() bool {
+		workers = sched.Summary().Struct().Workers
+		return workers == WorkersSummaryStruct{}
+	})
+	if !ok {
+		t.Fatalf("Some workers are still running after stopped! (workers: %#v)",
+			workers)
+	}
+}
@@ -0,0 +1,257 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// 可扩展布隆过滤器的增长参数。
+const (
+	// growthFactor 代表每个新的子过滤器相对于上一个子过滤器的容量倍数。
+	growthFactor = 2
+	// tighteningRatio 代表每个新的子过滤器相对于上一个子过滤器的误判率比例。
+	tighteningRatio = 0.5
+)
+
+// ErrIllegalParams 代表参数不合法的错误。
+var ErrIllegalParams = errors.New("illegal bloom filter params")
+
+// Filter 代表可扩展布隆过滤器的类型。
+// 它由一组容量递增且误判率递减的子过滤器组成，
+// 当前子过滤器装满后会自动追加新的子过滤器，
+// 因此总的误判率可以维持在给定值之下。
+// 该类型是并发安全的。
+type Filter struct {
+	// initialCapacity 代表第一个子过滤器的容量。
+	initialCapacity uint64
+	// fpRate 代表期望的总误判率。
+	fpRate float64
+	// filters 代表子过滤器列表。
+	filters []*partition
+	// count 代表已添加的元素的数量。
+	count uint64
+	// lock 代表保护以上字段的互斥锁。
+	lock sync.RWMutex
+}
+
+// partition 代表子过滤器的类型。
+type partition struct {
+	// bits 代表位数组。
+	bits []uint64
+	// m 代表位数组的长度。
+	m uint64
+	// k 代表哈希函数的数量。
+	k uint64
+	// capacity 代表容量。
+	capacity uint64
+	// count 代表已添加的元素的数量。
+	count uint64
+}
+
+// New 用于创建一个可扩展布隆过滤器。
+// 参数initialCapacity代表初始的容量，不能为0。
+// 参数fpRate代表期望的误判率，必须在(0, 1)之间。
+func New(initialCapacity uint64, fpRate float64) (*Filter, error) {
+	if initialCapacity == 0 || fpRate <= 0 || fpRate >= 1 {
+		return nil, ErrIllegalParams
+	}
+	f := &Filter{
+		initialCapacity: initialCapacity,
+		fpRate:          fpRate,
+	}
+	f.grow()
+	return f, nil
+}
+
+// newPartition 用于按照容量和误判率创建一个子过滤器。
+func newPartition(capacity uint64, fpRate float64) *partition {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Ceil(-math.Log2(fpRate)))
+	if k == 0 {
+		k = 1
+	}
+	return &partition{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+// grow 用于追加一个新的子过滤器。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (f *Filter) grow() {
+	n := len(f.filters)
+	capacity := f.initialCapacity * uint64(math.Pow(growthFactor, float64(n)))
+	fpRate := f.fpRate * (1 - tighteningRatio) * math.Pow(tighteningRatio, float64(n))
+	f.filters = append(f.filters, newPartition(capacity, fpRate))
+}
+
+// hashes 用于计算给定键的两个基础哈希值。
+func hashes(key string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write([]byte(key))
+	h2 := fnv.New64()
+	h2.Write([]byte(key))
+	return h1.Sum64(), h2.Sum64() | 1
+}
+
+// test 用于判断子过滤器中是否可能包含给定的键。
+func (p *partition) test(h1, h2 uint64) bool {
+	for i := uint64(0); i < p.k; i++ {
+		pos := (h1 + i*h2) % p.m
+		if p.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// add 用于向子过滤器中添加给定的键。
+func (p *partition) add(h1, h2 uint64) {
+	for i := uint64(0); i < p.k; i++ {
+		pos := (h1 + i*h2) % p.m
+		p.bits[pos/64] |= 1 << (pos % 64)
+	}
+	p.count++
+}
+
+// Add 用于添加给定的键。
+// 若该键之前可能已被添加过，则返回false，否则返回true。
+func (f *Filter) Add(key string) bool {
+	h1, h2 := hashes(key)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.testLocked(h1, h2) {
+		return false
+	}
+	last := f.filters[len(f.filters)-1]
+	if last.count >= last.capacity {
+		f.grow()
+		last = f.filters[len(f.filters)-1]
+	}
+	last.add(h1, h2)
+	f.count++
+	return true
+}
+
+// Test 用于判断给定的键是否可能已被添加过。
+// 若结果为false，则该键一定没有被添加过。
+func (f *Filter) Test(key string) bool {
+	h1, h2 := hashes(key)
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.testLocked(h1, h2)
+}
+
+// testLocked 用于在所有子过滤器中检查给定的哈希值。
+// 注意！必须在互斥锁的保护下调用本方法！
+func (f *Filter) testLocked(h1, h2 uint64) bool {
+	for _, p := range f.filters {
+		if p.test(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len 用于获取已添加的键的数量。
+func (f *Filter) Len() uint64 {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.count
+}
+
+// MemoryUsage 用于获取位数组所占用的内存的字节数。
+func (f *Filter) MemoryUsage() uint64 {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	var usage uint64
+	for _, p := range f.filters {
+		usage += uint64(len(p.bits)) * 8
+	}
+	return usage
+}
+
+// filterData 代表用于编码的过滤器数据。
+type filterData struct {
+	InitialCapacity uint64
+	FPRate          float64
+	Count           uint64
+	Partitions      []partitionData
+}
+
+// partitionData 代表用于编码的子过滤器数据。
+type partitionData struct {
+	Bits     []uint64
+	M        uint64
+	K        uint64
+	Capacity uint64
+	Count    uint64
+}
+
+// MarshalBinary 用于把过滤器编码为字节序列。
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	f.lock.RLock()
+	data := filterData{
+		InitialCapacity: f.initialCapacity,
+		FPRate:          f.fpRate,
+		Count:           f.count,
+	}
+	for _, p := range f.filters {
+		data.Partitions = append(data.Partitions, partitionData{
+			Bits:     p.bits,
+			M:        p.m,
+			K:        p.k,
+			Capacity: p.capacity,
+			Count:    p.count,
+		})
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(data)
+	f.lock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary 用于根据字节序列还原过滤器。
+// 过滤器原有的内容会被替换。
+func (f *Filter) UnmarshalBinary(b []byte) error {
+	var data filterData
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&data); err != nil {
+		return err
+	}
+	if data.InitialCapacity == 0 || data.FPRate <= 0 || data.FPRate >= 1 ||
+		len(data.Partitions) == 0 {
+		return ErrIllegalParams
+	}
+	filters := make([]*partition, len(data.Partitions))
+	for i, pd := range data.Partitions {
+		if pd.M == 0 || pd.K == 0 || uint64(len(pd.Bits)) != (pd.M+63)/64 {
+			return ErrIllegalParams
+		}
+		filters[i] = &partition{
+			bits:     pd.Bits,
+			m:        pd.M,
+			k:        pd.K,
+			capacity: pd.Capacity,
+			count:    pd.Count,
+		}
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.initialCapacity = data.InitialCapacity
+	f.fpRate = data.FPRate
+	f.count = data.Count
+	f.filters = filters
+	return nil
+}
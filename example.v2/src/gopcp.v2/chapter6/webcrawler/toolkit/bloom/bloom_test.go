@@ -0,0 +1,97 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New(100, 0.01); err != nil {
+		t.Fatalf("An error occurs when creating a bloom filter: %s", err)
+	}
+	invalidParams := []struct {
+		capacity uint64
+		fpRate   float64
+	}{
+		{0, 0.01},
+		{100, 0},
+		{100, 1},
+		{100, -0.1},
+	}
+	for _, p := range invalidParams {
+		if _, err := New(p.capacity, p.fpRate); err == nil {
+			t.Fatalf("No error when creating a bloom filter with illegal params! (capacity: %d, fpRate: %f)",
+				p.capacity, p.fpRate)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	fpRate := 0.01
+	f, _ := New(1000, fpRate)
+	number := 20000
+	for i := 0; i < number; i++ {
+		key := fmt.Sprintf("http://a.com/%d", i)
+		f.Add(key)
+		if !f.Test(key) {
+			t.Fatalf("Couldn't find the added key %q!", key)
+		}
+	}
+	if len(f.filters) < 2 {
+		t.Fatalf("The filter has not grown! (partition number: %d)", len(f.filters))
+	}
+	for i := 0; i < number; i++ {
+		key := fmt.Sprintf("http://a.com/%d", i)
+		if !f.Test(key) {
+			t.Fatalf("Couldn't find the added key %q after growing!", key)
+		}
+		if f.Add(key) {
+			t.Fatalf("The added key %q was added again!", key)
+		}
+	}
+	var falsePositives int
+	for i := 0; i < number; i++ {
+		if f.Test(fmt.Sprintf("http://b.com/%d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / float64(number); rate > fpRate*2 {
+		t.Fatalf("Too high false positive rate: expected: <= %f, actual: %f",
+			fpRate*2, rate)
+	}
+	if count := f.Len(); count+uint64(falsePositives) < uint64(number)*99/100 {
+		t.Fatalf("Inconsistent count: expected: about %d, actual: %d",
+			number, count)
+	}
+	if f.MemoryUsage() == 0 {
+		t.Fatal("Zero memory usage!")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	f, _ := New(10, 0.001)
+	for i := 0; i < 100; i++ {
+		f.Add(fmt.Sprintf("key-%d", i))
+	}
+	b, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("An error occurs when marshaling: %s", err)
+	}
+	another, _ := New(1, 0.5)
+	if err := another.UnmarshalBinary(b); err != nil {
+		t.Fatalf("An error occurs when unmarshaling: %s", err)
+	}
+	if another.Len() != f.Len() || another.MemoryUsage() != f.MemoryUsage() {
+		t.Fatalf("Inconsistent filter: expected: %d keys, %d bytes, actual: %d keys, %d bytes",
+			f.Len(), f.MemoryUsage(), another.Len(), another.MemoryUsage())
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if !another.Test(key) {
+			t.Fatalf("Couldn't find the key %q in the unmarshaled filter!", key)
+		}
+	}
+	if err := another.UnmarshalBinary([]byte("invalid")); err == nil {
+		t.Fatal("No error when unmarshaling invalid data!")
+	}
+}
@@ -0,0 +1,200 @@
+package canonical
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultTrackingParams 代表默认会被移除的跟踪参数的列表。
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"gclid",
+	"fbclid",
+}
+
+// Rules 代表URL规范化规则的类型。
+// 其零值代表启用所有的规范化步骤并移除默认的跟踪参数。
+type Rules struct {
+	// Disabled 代表是否禁用规范化。
+	// 若为true，则URL会原样使用。
+	Disabled bool `json:"disabled"`
+	// KeepFragment 代表是否保留片段（即“#”之后的部分）。
+	KeepFragment bool `json:"keep_fragment"`
+	// KeepDefaultPort 代表是否保留与协议对应的默认端口。
+	KeepDefaultPort bool `json:"keep_default_port"`
+	// KeepQueryOrder 代表是否保留查询参数的原有顺序。
+	KeepQueryOrder bool `json:"keep_query_order"`
+	// TrackingParams 代表需要被移除的查询参数名称的列表。
+	// 以“*”结尾的名称代表前缀匹配，名称的比较不区分大小写。
+	// 若为nil，则使用DefaultTrackingParams；若为空列表，则不移除任何参数。
+	TrackingParams []string `json:"tracking_params"`
+}
+
+// Check 用于自检规则的有效性。
+func (rules *Rules) Check() error {
+	for _, param := range rules.TrackingParams {
+		if strings.TrimSpace(param) == "" || param == "*" {
+			return fmt.Errorf("illegal tracking param %q", param)
+		}
+	}
+	return nil
+}
+
+// Same 用于判断两份规则是否相同。
+func (rules *Rules) Same(another *Rules) bool {
+	if another == nil {
+		return false
+	}
+	if another.Disabled != rules.Disabled ||
+		another.KeepFragment != rules.KeepFragment ||
+		another.KeepDefaultPort != rules.KeepDefaultPort ||
+		another.KeepQueryOrder != rules.KeepQueryOrder {
+		return false
+	}
+	if (another.TrackingParams == nil) != (rules.TrackingParams == nil) ||
+		len(another.TrackingParams) != len(rules.TrackingParams) {
+		return false
+	}
+	for i, param := range another.TrackingParams {
+		if param != rules.TrackingParams[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Canonicalize 用于按照规则生成给定URL的规范形式。
+// 给定的URL不会被修改。
+func (rules *Rules) Canonicalize(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	if rules.Disabled {
+		return u.String()
+	}
+	cu := *u
+	cu.Scheme = strings.ToLower(cu.Scheme)
+	cu.Host = rules.canonicalHost(cu.Scheme, cu.Host)
+	if cu.Opaque == "" {
+		escapedPath := removeDotSegments(u.EscapedPath())
+		if escapedPath == "" && cu.Host != "" {
+			escapedPath = "/"
+		}
+		// 借助RawPath保留路径原有的转义形式。
+		cu.Path, cu.RawPath = escapedPath, escapedPath
+		if unescaped, err := url.PathUnescape(escapedPath); err == nil {
+			cu.Path = unescaped
+		}
+	}
+	cu.RawQuery = rules.canonicalQuery(cu.RawQuery)
+	cu.ForceQuery = false
+	if !rules.KeepFragment {
+		cu.Fragment = ""
+		cu.RawFragment = ""
+	}
+	return cu.String()
+}
+
+// canonicalHost 用于生成主机的规范形式。
+func (rules *Rules) canonicalHost(scheme string, host string) string {
+	host = strings.ToLower(host)
+	if !rules.KeepDefaultPort &&
+		((scheme == "http" && strings.HasSuffix(host, ":80")) ||
+			(scheme == "https" && strings.HasSuffix(host, ":443"))) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+	// 主机名末尾的“.”不影响其含义。
+	return strings.TrimSuffix(host, ".")
+}
+
+// canonicalQuery 用于生成查询字符串的规范形式。
+// 参数的原有编码会被保留。
+func (rules *Rules) canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	trackingParams := rules.TrackingParams
+	if trackingParams == nil {
+		trackingParams = DefaultTrackingParams
+	}
+	var pairs []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		if isTrackingParam(queryKey(pair), trackingParams) {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	if !rules.KeepQueryOrder {
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return queryKey(pairs[i]) < queryKey(pairs[j])
+		})
+	}
+	return strings.Join(pairs, "&")
+}
+
+// queryKey 用于获取查询参数的名称（已解码）。
+func queryKey(pair string) string {
+	key := pair
+	if i := strings.IndexByte(pair, '='); i >= 0 {
+		key = pair[:i]
+	}
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		key = unescaped
+	}
+	return key
+}
+
+// isTrackingParam 用于判断给定名称的查询参数是否为跟踪参数。
+func isTrackingParam(key string, trackingParams []string) bool {
+	key = strings.ToLower(key)
+	for _, param := range trackingParams {
+		param = strings.ToLower(param)
+		if strings.HasSuffix(param, "*") {
+			if strings.HasPrefix(key, param[:len(param)-1]) {
+				return true
+			}
+			continue
+		}
+		if key == param {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDotSegments 用于按照RFC 3986第5.2.4节移除路径中的“.”和“..”片段。
+func removeDotSegments(path string) string {
+	if path == "" {
+		return ""
+	}
+	segments := strings.Split(path, "/")
+	var output []string
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				output = append(output, "")
+			}
+		case "..":
+			if len(output) > 1 {
+				output = output[:len(output)-1]
+			}
+			if last {
+				output = append(output, "")
+			}
+		default:
+			output = append(output, segment)
+		}
+	}
+	result := strings.Join(output, "/")
+	if strings.HasPrefix(path, "/") && !strings.HasPrefix(result, "/") {
+		result = "/" + result
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+package canonical
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	cases := []struct {
+		rules    Rules
+		rawURL   string
+		expected string
+	}{
+		{Rules{}, "http://a.com/x?b=1&a=2", "http://a.com/x?a=2&b=1"},
+		{Rules{}, "http://A.com:80/x?a=2&b=1", "http://a.com/x?a=2&b=1"},
+		{Rules{}, "http://a.com/x#frag", "http://a.com/x"},
+		{Rules{}, "HTTPS://www.A.com:443", "https://www.a.com/"},
+		{Rules{}, "http://a.com:8080/", "http://a.com:8080/"},
+		{Rules{}, "http://a.com./", "http://a.com/"},
+		{Rules{}, "http://a.com/a/b/../c/./d", "http://a.com/a/c/d"},
+		{Rules{}, "http://a.com/a/..", "http://a.com/"},
+		{Rules{}, "http://a.com/../../x", "http://a.com/x"},
+		{Rules{}, "http://a.com/a/.", "http://a.com/a/"},
+		{Rules{}, "http://a.com/%E4%B8%AD%2F?q=%E4%B8%AD", "http://a.com/%E4%B8%AD%2F?q=%E4%B8%AD"},
+		{Rules{}, "http://a.com/x?utm_source=x&id=1&UTM_Medium=y&gclid=z", "http://a.com/x?id=1"},
+		{Rules{}, "http://a.com/x?utm_source=x", "http://a.com/x"},
+		{Rules{}, "http://a.com/x?b=2&a=1&b=1", "http://a.com/x?a=1&b=2&b=1"},
+		{Rules{}, "http://a.com/x?&&a=1", "http://a.com/x?a=1"},
+		{Rules{KeepFragment: true}, "http://a.com/x#frag", "http://a.com/x#frag"},
+		{Rules{KeepDefaultPort: true}, "http://a.com:80/x", "http://a.com:80/x"},
+		{Rules{KeepDefaultPort: true}, "https://a.com:443/x", "https://a.com:443/x"},
+		{Rules{KeepQueryOrder: true}, "http://a.com/x?b=1&a=2", "http://a.com/x?b=1&a=2"},
+		{Rules{TrackingParams: []string{}}, "http://a.com/x?utm_source=x", "http://a.com/x?utm_source=x"},
+		{Rules{TrackingParams: []string{"sid", "ref_*"}}, "http://a.com/x?SID=1&ref_a=2&utm_source=3", "http://a.com/x?utm_source=3"},
+		{Rules{Disabled: true}, "http://A.com:80/x?b=1&a=2#f", "http://A.com:80/x?b=1&a=2#f"},
+	}
+	for _, c := range cases {
+		u, err := url.Parse(c.rawURL)
+		if err != nil {
+			t.Fatalf("An error occurs when parsing URL %q: %s", c.rawURL, err)
+		}
+		original := u.String()
+		actual := c.rules.Canonicalize(u)
+		if actual != c.expected {
+			t.Fatalf("Inconsistent canonical URL for %q: expected: %s, actual: %s (rules: %#v)",
+				c.rawURL, c.expected, actual, c.rules)
+		}
+		if u.String() != original {
+			t.Fatalf("The given URL has been modified: expected: %s, actual: %s",
+				original, u.String())
+		}
+	}
+	rules := Rules{}
+	if actual := rules.Canonicalize(nil); actual != "" {
+		t.Fatalf("Inconsistent canonical URL for nil: expected: %q, actual: %q",
+			"", actual)
+	}
+}
+
+func TestRulesCheck(t *testing.T) {
+	validRulesList := []Rules{
+		{},
+		{TrackingParams: []string{}},
+		{TrackingParams: []string{"sid", "utm_*"}},
+	}
+	for _, rules := range validRulesList {
+		if err := rules.Check(); err != nil {
+			t.Fatalf("An error occurs when checking rules: %s (rules: %#v)",
+				err, rules)
+		}
+	}
+	invalidRulesList := []Rules{
+		{TrackingParams: []string{""}},
+		{TrackingParams: []string{"sid", " "}},
+		{TrackingParams: []string{"*"}},
+	}
+	for _, rules := range invalidRulesList {
+		if err := rules.Check(); err == nil {
+			t.Fatalf("No error when checking rules! (rules: %#v)", rules)
+		}
+	}
+}
+
+func TestRulesSame(t *testing.T) {
+	one := Rules{TrackingParams: []string{"sid"}}
+	another := Rules{TrackingParams: []string{"sid"}}
+	if !one.Same(&another) {
+		t.Fatalf("Inconsistent rules: expected: %#v, actual: %#v", one, another)
+	}
+	differentList := []Rules{
+		{},
+		{TrackingParams: []string{}},
+		{TrackingParams: []string{"ref"}},
+		{TrackingParams: []string{"sid"}, KeepFragment: true},
+		{TrackingParams: []string{"sid"}, Disabled: true},
+	}
+	for _, different := range differentList {
+		if one.Same(&different) {
+			t.Fatalf("Same rules: %#v, %#v", one, different)
+		}
+	}
+	if one.Same(nil) {
+		t.Fatal("Same rules with nil!")
+	}
+}
@@ -0,0 +1,27 @@
+package reader
+
+import "io"
+
+// countingReadCloser 代表会统计已读取的字节数的可关闭读取器。
+type countingReadCloser struct {
+	io.ReadCloser
+	// onRead 代表每次读取到数据之后被调用的函数，参数为本次读取的字节数。
+	onRead func(n uint64)
+}
+
+// NewCountingReadCloser 用于新建一个会统计已读取的字节数的可关闭读取器。
+// 每次读取到数据之后，参数onRead代表的函数都会以本次读取的字节数为参数被调用。
+func NewCountingReadCloser(rc io.ReadCloser, onRead func(n uint64)) io.ReadCloser {
+	return &countingReadCloser{
+		ReadCloser: rc,
+		onRead:     onRead,
+	}
+}
+
+func (rc *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := rc.ReadCloser.Read(p)
+	if n > 0 && rc.onRead != nil {
+		rc.onRead(uint64(n))
+	}
+	return n, err
+}
@@ -11,7 +11,11 @@ import (
 type MultipleReader interface {
 	// Reader 用于获取一个可关闭读取器的实例。
 	// 后者会持有本多重读取器中的数据。
-	Reader() io.ReadCloser
+	// 每次获取的读取器都是相互独立的，且都可以被定位。
+	Reader() io.ReadSeekCloser
+	// Close 用于释放本多重读取器持有的资源。
+	// 已获取的读取器在被关闭之前仍然可用。
+	Close() error
 }
 
 // myMultipleReader 代表多重读取器的实现类型。
@@ -36,6 +40,19 @@ func NewMultipleReader(reader io.Reader) (MultipleReader, error) {
 	}, nil
 }
 
-func (rr *myMultipleReader) Reader() io.ReadCloser {
-	return ioutil.NopCloser(bytes.NewReader(rr.data))
+func (rr *myMultipleReader) Reader() io.ReadSeekCloser {
+	return nopSeekCloser{bytes.NewReader(rr.data)}
+}
+
+func (rr *myMultipleReader) Close() error {
+	return nil
+}
+
+// nopSeekCloser 代表关闭操作为空的可定位读取器。
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
 }
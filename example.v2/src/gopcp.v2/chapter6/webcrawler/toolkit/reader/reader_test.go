@@ -3,6 +3,7 @@ package reader
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"strings"
 	"testing"
 )
@@ -29,3 +30,89 @@ func TestReaderNew(t *testing.T) {
 			expectedData, content2)
 	}
 }
+
+func TestReaderCounting(t *testing.T) {
+	expectedData := "0987dcba"
+	var total uint64
+	rc := NewCountingReadCloser(
+		ioutil.NopCloser(strings.NewReader(expectedData)),
+		func(n uint64) { total += n })
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("An error occurs when reading data: %s", err)
+	}
+	if string(data) != expectedData {
+		t.Fatalf("Inconsistent data: expected: %s, actual: %s",
+			expectedData, data)
+	}
+	if total != uint64(len(expectedData)) {
+		t.Fatalf("Inconsistent byte count: expected: %d, actual: %d",
+			len(expectedData), total)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("An error occurs when closing reader: %s", err)
+	}
+}
+
+func TestReaderSpill(t *testing.T) {
+	expectedData := "0987dcba"
+	dir := t.TempDir()
+	// 数据长度不超过阈值时，数据会被保存在内存中。
+	rr, err := NewSpillMultipleReader(strings.NewReader(expectedData), 8, dir)
+	if err != nil {
+		t.Fatalf("An error occurs when new multiple reader: %s", err)
+	}
+	if _, ok := rr.(*myMultipleReader); !ok {
+		t.Fatalf("Inconsistent multiple reader type: expected: %T, actual: %T",
+			&myMultipleReader{}, rr)
+	}
+	// 数据长度超过阈值时，数据会被写入临时文件。
+	rr, err = NewSpillMultipleReader(strings.NewReader(expectedData), 4, dir)
+	if err != nil {
+		t.Fatalf("An error occurs when new multiple reader: %s", err)
+	}
+	countFiles := func() int {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("An error occurs when reading directory: %s", err)
+		}
+		return len(infos)
+	}
+	if n := countFiles(); n != 1 {
+		t.Fatalf("Inconsistent temp file number: expected: %d, actual: %d", 1, n)
+	}
+	reader1 := rr.Reader()
+	reader2 := rr.Reader()
+	for i, reader := range []io.ReadSeekCloser{reader1, reader2, reader1} {
+		if _, err := reader.Seek(0, io.SeekStart); err != nil {
+			t.Fatalf("An error occurs when seeking: %s", err)
+		}
+		content, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("An error occurs when reading data: %s", err)
+		}
+		if string(content) != expectedData {
+			t.Fatalf("Inconsistent data: expected: %s, actual: %s (index: %d)",
+				expectedData, content, i)
+		}
+	}
+	reader1.Close()
+	if err := rr.Close(); err != nil {
+		t.Fatalf("An error occurs when closing multiple reader: %s", err)
+	}
+	if n := countFiles(); n != 1 {
+		t.Fatal("The temp file has been removed before the last reader is closed!")
+	}
+	if err := reader2.Close(); err != nil {
+		t.Fatalf("An error occurs when closing reader: %s", err)
+	}
+	if n := countFiles(); n != 0 {
+		t.Fatal("The temp file has not been removed after the last reader is closed!")
+	}
+	if _, err := ioutil.ReadAll(rr.Reader()); err == nil {
+		t.Fatal("No error when reading from a released multiple reader!")
+	}
+	if _, err := NewSpillMultipleReader(strings.NewReader(expectedData), -1, dir); err == nil {
+		t.Fatal("No error when new multiple reader with illegal threshold!")
+	}
+}
@@ -0,0 +1,132 @@
+package reader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// NewSpillMultipleReader 用于新建并返回一个可以把数据溢出到临时文件的多重读取器。
+// 若数据的长度不超过参数threshold，则数据会被保存在内存中，
+// 否则会被写入参数dir代表的目录下的临时文件中。
+// 若参数dir为空，则使用系统默认的临时目录。
+// 临时文件会在多重读取器和所有从它获取的读取器都被关闭之后被删除。
+func NewSpillMultipleReader(
+	reader io.Reader, threshold int64, dir string) (MultipleReader, error) {
+	if threshold < 0 {
+		return nil, fmt.Errorf("multiple reader: illegal threshold: %d", threshold)
+	}
+	if reader == nil {
+		return NewMultipleReader(nil)
+	}
+	head, err := ioutil.ReadAll(io.LimitReader(reader, threshold+1))
+	if err != nil {
+		return nil, fmt.Errorf("multiple reader: couldn't create a new one: %s", err)
+	}
+	if int64(len(head)) <= threshold {
+		return &myMultipleReader{data: head}, nil
+	}
+	file, err := ioutil.TempFile(dir, "multiple-reader-")
+	if err != nil {
+		return nil, fmt.Errorf("multiple reader: couldn't create temp file: %s", err)
+	}
+	size, err := io.Copy(file, io.MultiReader(bytes.NewReader(head), reader))
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("multiple reader: couldn't write temp file: %s", err)
+	}
+	return &fileMultipleReader{file: file, size: size, refs: 1}, nil
+}
+
+// fileMultipleReader 代表基于临时文件的多重读取器的实现类型。
+type fileMultipleReader struct {
+	// file 代表保存数据的临时文件。
+	file *os.File
+	// size 代表数据的长度。
+	size int64
+	// refs 代表对临时文件的引用计数，其中包括多重读取器自身持有的引用。
+	refs int
+	// closed 代表多重读取器自身是否已被关闭。
+	closed bool
+	// err 代表释放临时文件时发生的错误。
+	err  error
+	lock sync.Mutex
+}
+
+func (rr *fileMultipleReader) Reader() io.ReadSeekCloser {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+	if rr.refs == 0 {
+		return &fileReader{
+			SectionReader: io.NewSectionReader(closedReaderAt{}, 0, rr.size),
+		}
+	}
+	rr.refs++
+	return &fileReader{
+		SectionReader: io.NewSectionReader(rr.file, 0, rr.size),
+		parent:        rr,
+	}
+}
+
+func (rr *fileMultipleReader) Close() error {
+	rr.lock.Lock()
+	if rr.closed {
+		rr.lock.Unlock()
+		return nil
+	}
+	rr.closed = true
+	rr.lock.Unlock()
+	return rr.release()
+}
+
+// release 用于释放一个对临时文件的引用。
+// 在最后一个引用被释放时，临时文件会被关闭并删除。
+func (rr *fileMultipleReader) release() error {
+	rr.lock.Lock()
+	defer rr.lock.Unlock()
+	if rr.refs == 0 {
+		return rr.err
+	}
+	rr.refs--
+	if rr.refs > 0 {
+		return nil
+	}
+	name := rr.file.Name()
+	if err := rr.file.Close(); err != nil {
+		rr.err = err
+	}
+	if err := os.Remove(name); err != nil && rr.err == nil {
+		rr.err = err
+	}
+	return rr.err
+}
+
+// fileReader 代表从基于临时文件的多重读取器获取的读取器。
+type fileReader struct {
+	*io.SectionReader
+	// parent 代表所属的多重读取器。
+	parent *fileMultipleReader
+	// once 用于保证只释放一次引用。
+	once sync.Once
+}
+
+func (reader *fileReader) Close() error {
+	var err error
+	reader.once.Do(func() {
+		if reader.parent != nil {
+			err = reader.parent.release()
+		}
+	})
+	return err
+}
+
+// closedReaderAt 代表临时文件已被删除时使用的读取器。
+type closedReaderAt struct{}
+
+func (closedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return 0, os.ErrClosed
+}
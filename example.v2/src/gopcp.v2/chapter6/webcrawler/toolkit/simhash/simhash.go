@@ -0,0 +1,118 @@
+package simhash
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"hash/fnv"
+	"io"
+	"math/bits"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// ShingleSize 代表计算SimHash指纹时每个特征包含的词的数量。
+const ShingleSize = 2
+
+// NormalizeHTML 用于从HTML文档中提取规范化的文本。
+// 其中的标签、注释以及script和style元素的内容都会被忽略。
+func NormalizeHTML(reader io.Reader) (string, error) {
+	tokenizer := html.NewTokenizer(reader)
+	var words []string
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			err := tokenizer.Err()
+			if err == io.EOF {
+				return strings.Join(words, " "), nil
+			}
+			return "", err
+		case html.StartTagToken:
+			if isSkippedTag(tokenizer) {
+				skip++
+			}
+		case html.EndTagToken:
+			if skip > 0 && isSkippedTag(tokenizer) {
+				skip--
+			}
+		case html.TextToken:
+			if skip == 0 {
+				words = append(words, Words(string(tokenizer.Text()))...)
+			}
+		}
+	}
+}
+
+// isSkippedTag 用于判断当前标签的内容是否应该被忽略。
+func isSkippedTag(tokenizer *html.Tokenizer) bool {
+	name, _ := tokenizer.TagName()
+	switch string(name) {
+	case "script", "style", "noscript", "template":
+		return true
+	}
+	return false
+}
+
+// NormalizeText 用于规范化纯文本。
+func NormalizeText(text string) string {
+	return strings.Join(Words(text), " ")
+}
+
+// Words 用于把文本拆分为小写的词的列表。
+// 除了字母和数字以外的字符都会被视为分隔符。
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// ExactHash 用于计算文本的精确哈希值，结果值为十六进制的字符串。
+func ExactHash(text string) string {
+	sum := sha1.Sum([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Fingerprint 用于计算规范化文本的64位SimHash指纹。
+// 其中的特征为每ShingleSize个相邻的词，内容相似的文本的指纹之间的海明距离较小。
+// 若文本为空，则结果值为0。
+func Fingerprint(text string) uint64 {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return 0
+	}
+	var weights [64]int
+	if len(words) < ShingleSize {
+		addFeature(&weights, strings.Join(words, " "))
+	}
+	for i := 0; i+ShingleSize <= len(words); i++ {
+		addFeature(&weights, strings.Join(words[i:i+ShingleSize], " "))
+	}
+	var fingerprint uint64
+	for i, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
+
+// addFeature 用于把一个特征的哈希值累加到各个位的权重上。
+func addFeature(weights *[64]int, feature string) {
+	hash := fnv.New64a()
+	hash.Write([]byte(feature))
+	sum := hash.Sum64()
+	for i := range weights {
+		if sum&(1<<uint(i)) != 0 {
+			weights[i]++
+		} else {
+			weights[i]--
+		}
+	}
+}
+
+// Distance 用于计算两个指纹之间的海明距离。
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,91 @@
+package simhash
+
+import (
+	"strings"
+	"testing"
+)
+
+// testingArticle 代表测试用的文章。
+const testingArticle = `Go is an open source programming language that makes it
+simple to build secure, scalable systems. It was designed at Google to improve
+programming productivity in an era of multicore, networked machines and large
+codebases. The designers wanted to address criticism of other languages in use
+while keeping their useful characteristics: static typing and run-time
+efficiency, readability and usability, and high-performance networking and
+multiprocessing.`
+
+func TestNormalizeHTML(t *testing.T) {
+	doc := `<html><head><title>Hello</title>
+<style>body { color: red; }</style>
+<script>var a = "<p>ignored</p>";</script></head>
+<body><!-- comment --><h1>Hello,   World!</h1>
+<p>It's <b>Go</b>&nbsp;1.10 — 你好</p></body></html>`
+	text, err := NormalizeHTML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("An error occurs when normalizing HTML: %s", err)
+	}
+	expected := "hello hello world it s go 1 10 你好"
+	if text != expected {
+		t.Fatalf("Inconsistent normalized text: expected: %q, actual: %q",
+			expected, text)
+	}
+	if text := NormalizeText(" Hello,\tWORLD! "); text != "hello world" {
+		t.Fatalf("Inconsistent normalized text: %q", text)
+	}
+}
+
+func TestExactHash(t *testing.T) {
+	text := NormalizeText(testingArticle)
+	if ExactHash(text) != ExactHash(NormalizeText(strings.ToUpper(testingArticle))) {
+		t.Fatal("Inconsistent exact hashes of the same normalized text!")
+	}
+	if ExactHash(text) == ExactHash(text+" x") {
+		t.Fatal("The exact hashes of different texts are the same!")
+	}
+	if len(ExactHash(text)) != 40 {
+		t.Fatalf("Inconsistent exact hash length: %d", len(ExactHash(text)))
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	if fp := Fingerprint(""); fp != 0 {
+		t.Fatalf("Inconsistent fingerprint of empty text: %x", fp)
+	}
+	if fp := Fingerprint("go"); fp == 0 {
+		t.Fatal("Zero fingerprint of one-word text!")
+	}
+	text := NormalizeText(testingArticle)
+	fp := Fingerprint(text)
+	if Fingerprint(text) != fp {
+		t.Fatal("Inconsistent fingerprints of the same text!")
+	}
+	similar := NormalizeText(strings.Replace(testingArticle,
+		"large\ncodebases", "huge codebases", 1) + " Copyright 2024.")
+	if d := Distance(fp, Fingerprint(similar)); d > 8 {
+		t.Fatalf("Too large distance between similar texts: %d", d)
+	}
+	different := NormalizeText(`The quick brown fox jumps over the lazy dog.
+Pack my box with five dozen liquor jugs. How vexingly quick daft zebras jump!
+Sphinx of black quartz, judge my vow. The five boxing wizards jump quickly.`)
+	if d := Distance(fp, Fingerprint(different)); d <= 8 {
+		t.Fatalf("Too small distance between different texts: %d", d)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b     uint64
+		expected int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0xff, 0x0f, 4},
+		{0, ^uint64(0), 64},
+	}
+	for _, c := range cases {
+		if d := Distance(c.a, c.b); d != c.expected {
+			t.Fatalf("Inconsistent distance between %x and %x: expected: %d, actual: %d",
+				c.a, c.b, c.expected, d)
+		}
+	}
+}
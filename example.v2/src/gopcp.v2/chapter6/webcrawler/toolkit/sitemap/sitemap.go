@@ -0,0 +1,186 @@
+package sitemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// 站点地图的限制。
+const (
+	// MaxSize 代表单个站点地图（解压之后）的最大读取长度。
+	MaxSize = 50 << 20
+	// maxNestingDepth 代表站点地图索引的最大嵌套深度。
+	maxNestingDepth = 3
+)
+
+// Entry 代表站点地图中的条目。
+// 它既可以是一个页面，也可以是站点地图索引中的一个站点地图。
+type Entry struct {
+	// Loc 代表条目的URL。
+	Loc string
+	// LastMod 代表条目的最后修改时间。若为零值，则代表未知。
+	LastMod time.Time
+}
+
+// ModifiedSince 用于判断条目是否在给定的时间之后被修改过。
+// 最后修改时间未知的条目总会被视为已被修改过。
+func (entry Entry) ModifiedSince(since time.Time) bool {
+	if since.IsZero() || entry.LastMod.IsZero() {
+		return true
+	}
+	return entry.LastMod.After(since)
+}
+
+// Document 代表解析后的站点地图。
+type Document struct {
+	// URLs 代表站点地图中的页面的列表。
+	URLs []Entry
+	// Sitemaps 代表站点地图索引中的站点地图的列表。
+	Sitemaps []Entry
+}
+
+// xmlEntry 代表站点地图中url元素或sitemap元素的XML形式。
+type xmlEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// xmlDocument 代表站点地图的XML形式。
+// 根元素为urlset时是普通的站点地图，为sitemapindex时是站点地图索引。
+type xmlDocument struct {
+	XMLName  xml.Name
+	URLs     []xmlEntry `xml:"url"`
+	Sitemaps []xmlEntry `xml:"sitemap"`
+}
+
+// Parse 用于解析站点地图或站点地图索引。
+// 经过gzip压缩的内容会被自动解压。
+func Parse(reader io.Reader) (*Document, error) {
+	bufReader := bufio.NewReader(reader)
+	var content io.Reader = bufReader
+	if magic, err := bufReader.Peek(2); err == nil &&
+		magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(bufReader)
+		if err != nil {
+			return nil, fmt.Errorf("sitemap: couldn't decompress: %s", err)
+		}
+		defer gzipReader.Close()
+		content = gzipReader
+	}
+	var xmlDoc xmlDocument
+	decoder := xml.NewDecoder(io.LimitReader(content, MaxSize))
+	if err := decoder.Decode(&xmlDoc); err != nil {
+		return nil, fmt.Errorf("sitemap: couldn't decode: %s", err)
+	}
+	switch xmlDoc.XMLName.Local {
+	case "urlset", "sitemapindex":
+	default:
+		return nil, fmt.Errorf("sitemap: unexpected root element %q",
+			xmlDoc.XMLName.Local)
+	}
+	return &Document{
+		URLs:     convertEntries(xmlDoc.URLs),
+		Sitemaps: convertEntries(xmlDoc.Sitemaps),
+	}, nil
+}
+
+// convertEntries 用于把XML形式的条目转换为条目，URL为空的条目会被忽略。
+func convertEntries(xmlEntries []xmlEntry) []Entry {
+	var entries []Entry
+	for _, xmlEntry := range xmlEntries {
+		loc := strings.TrimSpace(xmlEntry.Loc)
+		if loc == "" {
+			continue
+		}
+		entries = append(entries, Entry{
+			Loc:     loc,
+			LastMod: parseLastMod(xmlEntry.LastMod),
+		})
+	}
+	return entries
+}
+
+// lastModLayouts 代表lastmod可以使用的W3C日期时间格式的列表。
+var lastModLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseLastMod 用于解析lastmod。若无法解析，则返回零值。
+func parseLastMod(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// FetchFunc 代表获取站点地图内容的函数的类型。
+type FetchFunc func(loc string) (io.ReadCloser, error)
+
+// Expand 用于从给定的站点地图出发，展开所有嵌套的站点地图索引，
+// 并返回其中在since之后被修改过的页面的列表。
+// 参数max代表返回的页面的最大数量，若为0，则不做限制。
+// 无法获取或解析的站点地图会被跳过，其错误会与结果一同返回。
+func Expand(locs []string, fetch FetchFunc,
+	since time.Time, max int) (entries []Entry, errs []error) {
+	visited := map[string]bool{}
+	var expand func(loc string, depth int) bool
+	expand = func(loc string, depth int) bool {
+		if visited[loc] {
+			return true
+		}
+		visited[loc] = true
+		body, err := fetch(loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sitemap: couldn't fetch %s: %s", loc, err))
+			return true
+		}
+		doc, err := Parse(body)
+		body.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s (URL: %s)", err, loc))
+			return true
+		}
+		for _, entry := range doc.URLs {
+			if !entry.ModifiedSince(since) {
+				continue
+			}
+			if max > 0 && len(entries) >= max {
+				return false
+			}
+			entries = append(entries, entry)
+		}
+		if depth >= maxNestingDepth {
+			return true
+		}
+		for _, entry := range doc.Sitemaps {
+			if !entry.ModifiedSince(since) {
+				continue
+			}
+			if !expand(entry.Loc, depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, loc := range locs {
+		if !expand(loc, 0) {
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testingSitemaps 代表测试用的站点地图，键为站点地图的URL。
+var testingSitemaps = map[string]string{
+	"http://example.com/sitemap.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>http://example.com/new.xml.gz</loc><lastmod>2024-05-01</lastmod></sitemap>
+  <sitemap><loc>http://example.com/old.xml</loc><lastmod>2020-01-01</lastmod></sitemap>
+  <sitemap><loc>http://example.com/sitemap.xml</loc></sitemap>
+  <sitemap><loc>http://example.com/missing.xml</loc></sitemap>
+</sitemapindex>`,
+	"http://example.com/new.xml.gz": `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> http://example.com/a </loc><lastmod>2024-05-01T10:00:00+08:00</lastmod></url>
+  <url><loc>http://example.com/b</loc><lastmod>2019-12-31</lastmod></url>
+  <url><loc>http://example.com/c</loc></url>
+  <url><loc></loc></url>
+</urlset>`,
+	"http://example.com/old.xml": `<urlset><url><loc>http://example.com/d</loc></url></urlset>`,
+}
+
+// testingFetch 用于获取测试用的站点地图，以“.gz”结尾的站点地图会被压缩。
+func testingFetch(loc string) (io.ReadCloser, error) {
+	content, ok := testingSitemaps[loc]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	if !strings.HasSuffix(loc, ".gz") {
+		return ioutil.NopCloser(strings.NewReader(content)), nil
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(content))
+	writer.Close()
+	return ioutil.NopCloser(&buf), nil
+}
+
+func TestParse(t *testing.T) {
+	body, _ := testingFetch("http://example.com/new.xml.gz")
+	doc, err := Parse(body)
+	if err != nil {
+		t.Fatalf("An error occurs when parsing sitemap: %s", err)
+	}
+	if len(doc.URLs) != 3 || len(doc.Sitemaps) != 0 {
+		t.Fatalf("Inconsistent entry number: urls: %d, sitemaps: %d",
+			len(doc.URLs), len(doc.Sitemaps))
+	}
+	expectedLastMod := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	if entry := doc.URLs[0]; entry.Loc != "http://example.com/a" ||
+		!entry.LastMod.Equal(expectedLastMod) {
+		t.Fatalf("Inconsistent entry: %#v", entry)
+	}
+	if !doc.URLs[2].LastMod.IsZero() {
+		t.Fatalf("Non-zero last modified time: %s", doc.URLs[2].LastMod)
+	}
+	body, _ = testingFetch("http://example.com/sitemap.xml")
+	doc, err = Parse(body)
+	if err != nil {
+		t.Fatalf("An error occurs when parsing sitemap index: %s", err)
+	}
+	if len(doc.URLs) != 0 || len(doc.Sitemaps) != 4 {
+		t.Fatalf("Inconsistent entry number: urls: %d, sitemaps: %d",
+			len(doc.URLs), len(doc.Sitemaps))
+	}
+	for _, content := range []string{"", "<html></html>", "not xml"} {
+		if _, err := Parse(strings.NewReader(content)); err == nil {
+			t.Fatalf("No error when parsing illegal sitemap %q!", content)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	locs := []string{"http://example.com/sitemap.xml"}
+	entries, errs := Expand(locs, testingFetch, time.Time{}, 0)
+	if len(errs) != 1 {
+		t.Fatalf("Inconsistent error number: expected: %d, actual: %d", 1, len(errs))
+	}
+	expectedLocs := []string{
+		"http://example.com/a",
+		"http://example.com/b",
+		"http://example.com/c",
+		"http://example.com/d",
+	}
+	checkLocs := func(entries []Entry, expectedLocs []string) {
+		if len(entries) != len(expectedLocs) {
+			t.Fatalf("Inconsistent entry number: expected: %d, actual: %d",
+				len(expectedLocs), len(entries))
+		}
+		for i, entry := range entries {
+			if entry.Loc != expectedLocs[i] {
+				t.Fatalf("Inconsistent URL: expected: %s, actual: %s",
+					expectedLocs[i], entry.Loc)
+			}
+		}
+	}
+	checkLocs(entries, expectedLocs)
+	since := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries, _ = Expand(locs, testingFetch, since, 0)
+	checkLocs(entries, []string{"http://example.com/a", "http://example.com/c"})
+	entries, _ = Expand(locs, testingFetch, time.Time{}, 2)
+	checkLocs(entries, expectedLocs[:2])
+}